@@ -0,0 +1,213 @@
+package canon
+
+import "strings"
+
+// Book is one entry of the 66-book Protestant canon. IDs match the book_id
+// values used by the verses table.
+type Book struct {
+	ID            int
+	Name          string
+	OSIS          string
+	USFM          string
+	Chapters      int
+	Abbreviations []string
+}
+
+// Books lists the canon in canonical order, so Books[id-1] has ID id
+var Books = []Book{
+	{ID: 1, Name: "Genesis", OSIS: "Gen", USFM: "GEN", Chapters: 50, Abbreviations: []string{"Gn", "Ge"}},
+	{ID: 2, Name: "Exodus", OSIS: "Exod", USFM: "EXO", Chapters: 40, Abbreviations: []string{"Ex", "Exo"}},
+	{ID: 3, Name: "Leviticus", OSIS: "Lev", USFM: "LEV", Chapters: 27, Abbreviations: []string{"Lv", "Le"}},
+	{ID: 4, Name: "Numbers", OSIS: "Num", USFM: "NUM", Chapters: 36, Abbreviations: []string{"Nm", "Nu", "Nb"}},
+	{ID: 5, Name: "Deuteronomy", OSIS: "Deut", USFM: "DEU", Chapters: 34, Abbreviations: []string{"Dt", "De"}},
+	{ID: 6, Name: "Joshua", OSIS: "Josh", USFM: "JOS", Chapters: 24, Abbreviations: []string{"Jos", "Jsh"}},
+	{ID: 7, Name: "Judges", OSIS: "Judg", USFM: "JDG", Chapters: 21, Abbreviations: []string{"Jdg", "Jg", "Jdgs"}},
+	{ID: 8, Name: "Ruth", OSIS: "Ruth", USFM: "RUT", Chapters: 4, Abbreviations: []string{"Rth", "Ru"}},
+	{ID: 9, Name: "1 Samuel", OSIS: "1Sam", USFM: "1SA", Chapters: 31, Abbreviations: []string{"1Sa", "1Sm", "1S"}},
+	{ID: 10, Name: "2 Samuel", OSIS: "2Sam", USFM: "2SA", Chapters: 24, Abbreviations: []string{"2Sa", "2Sm", "2S"}},
+	{ID: 11, Name: "1 Kings", OSIS: "1Kgs", USFM: "1KI", Chapters: 22, Abbreviations: []string{"1Ki", "1Kg", "1K"}},
+	{ID: 12, Name: "2 Kings", OSIS: "2Kgs", USFM: "2KI", Chapters: 25, Abbreviations: []string{"2Ki", "2Kg", "2K"}},
+	{ID: 13, Name: "1 Chronicles", OSIS: "1Chr", USFM: "1CH", Chapters: 29, Abbreviations: []string{"1Ch", "1Chron"}},
+	{ID: 14, Name: "2 Chronicles", OSIS: "2Chr", USFM: "2CH", Chapters: 36, Abbreviations: []string{"2Ch", "2Chron"}},
+	{ID: 15, Name: "Ezra", OSIS: "Ezra", USFM: "EZR", Chapters: 10, Abbreviations: []string{"Ezr"}},
+	{ID: 16, Name: "Nehemiah", OSIS: "Neh", USFM: "NEH", Chapters: 13, Abbreviations: []string{"Ne"}},
+	{ID: 17, Name: "Esther", OSIS: "Esth", USFM: "EST", Chapters: 10, Abbreviations: []string{"Es", "Est"}},
+	{ID: 18, Name: "Job", OSIS: "Job", USFM: "JOB", Chapters: 42, Abbreviations: []string{"Jb"}},
+	{ID: 19, Name: "Psalms", OSIS: "Ps", USFM: "PSA", Chapters: 150, Abbreviations: []string{"Psalm", "Pss", "Psa", "Psm", "Pslm"}},
+	{ID: 20, Name: "Proverbs", OSIS: "Prov", USFM: "PRO", Chapters: 31, Abbreviations: []string{"Pr", "Prv", "Pro"}},
+	{ID: 21, Name: "Ecclesiastes", OSIS: "Eccl", USFM: "ECC", Chapters: 12, Abbreviations: []string{"Ec", "Ecc", "Qoh", "Qoheleth"}},
+	{ID: 22, Name: "Song of Songs", OSIS: "Song", USFM: "SNG", Chapters: 8, Abbreviations: []string{"Song of Solomon", "SOS", "So", "Canticles", "Cant", "SS"}},
+	{ID: 23, Name: "Isaiah", OSIS: "Isa", USFM: "ISA", Chapters: 66, Abbreviations: []string{"Is"}},
+	{ID: 24, Name: "Jeremiah", OSIS: "Jer", USFM: "JER", Chapters: 52, Abbreviations: []string{"Je", "Jr"}},
+	{ID: 25, Name: "Lamentations", OSIS: "Lam", USFM: "LAM", Chapters: 5, Abbreviations: []string{"La"}},
+	{ID: 26, Name: "Ezekiel", OSIS: "Ezek", USFM: "EZK", Chapters: 48, Abbreviations: []string{"Eze", "Ezk"}},
+	{ID: 27, Name: "Daniel", OSIS: "Dan", USFM: "DAN", Chapters: 12, Abbreviations: []string{"Da", "Dn"}},
+	{ID: 28, Name: "Hosea", OSIS: "Hos", USFM: "HOS", Chapters: 14, Abbreviations: []string{"Ho"}},
+	{ID: 29, Name: "Joel", OSIS: "Joel", USFM: "JOL", Chapters: 3, Abbreviations: []string{"Jl"}},
+	{ID: 30, Name: "Amos", OSIS: "Amos", USFM: "AMO", Chapters: 9, Abbreviations: []string{"Am"}},
+	{ID: 31, Name: "Obadiah", OSIS: "Obad", USFM: "OBA", Chapters: 1, Abbreviations: []string{"Ob"}},
+	{ID: 32, Name: "Jonah", OSIS: "Jonah", USFM: "JON", Chapters: 4, Abbreviations: []string{"Jnh", "Jon"}},
+	{ID: 33, Name: "Micah", OSIS: "Mic", USFM: "MIC", Chapters: 7, Abbreviations: []string{"Mc"}},
+	{ID: 34, Name: "Nahum", OSIS: "Nah", USFM: "NAM", Chapters: 3, Abbreviations: []string{"Na"}},
+	{ID: 35, Name: "Habakkuk", OSIS: "Hab", USFM: "HAB", Chapters: 3, Abbreviations: []string{"Hb"}},
+	{ID: 36, Name: "Zephaniah", OSIS: "Zeph", USFM: "ZEP", Chapters: 3, Abbreviations: []string{"Zep", "Zp"}},
+	{ID: 37, Name: "Haggai", OSIS: "Hag", USFM: "HAG", Chapters: 2, Abbreviations: []string{"Hg"}},
+	{ID: 38, Name: "Zechariah", OSIS: "Zech", USFM: "ZEC", Chapters: 14, Abbreviations: []string{"Zec", "Zc"}},
+	{ID: 39, Name: "Malachi", OSIS: "Mal", USFM: "MAL", Chapters: 4, Abbreviations: []string{"Ml"}},
+	{ID: 40, Name: "Matthew", OSIS: "Matt", USFM: "MAT", Chapters: 28, Abbreviations: []string{"Mt"}},
+	{ID: 41, Name: "Mark", OSIS: "Mark", USFM: "MRK", Chapters: 16, Abbreviations: []string{"Mk", "Mr", "Mrk"}},
+	{ID: 42, Name: "Luke", OSIS: "Luke", USFM: "LUK", Chapters: 24, Abbreviations: []string{"Lk", "Luk"}},
+	{ID: 43, Name: "John", OSIS: "John", USFM: "JHN", Chapters: 21, Abbreviations: []string{"Jn", "Jhn"}},
+	{ID: 44, Name: "Acts", OSIS: "Acts", USFM: "ACT", Chapters: 28, Abbreviations: []string{"Ac"}},
+	{ID: 45, Name: "Romans", OSIS: "Rom", USFM: "ROM", Chapters: 16, Abbreviations: []string{"Ro", "Rm"}},
+	{ID: 46, Name: "1 Corinthians", OSIS: "1Cor", USFM: "1CO", Chapters: 16, Abbreviations: []string{"1Co"}},
+	{ID: 47, Name: "2 Corinthians", OSIS: "2Cor", USFM: "2CO", Chapters: 13, Abbreviations: []string{"2Co"}},
+	{ID: 48, Name: "Galatians", OSIS: "Gal", USFM: "GAL", Chapters: 6, Abbreviations: []string{"Ga"}},
+	{ID: 49, Name: "Ephesians", OSIS: "Eph", USFM: "EPH", Chapters: 6, Abbreviations: []string{"Ephes"}},
+	{ID: 50, Name: "Philippians", OSIS: "Phil", USFM: "PHP", Chapters: 4, Abbreviations: []string{"Php", "Pp"}},
+	{ID: 51, Name: "Colossians", OSIS: "Col", USFM: "COL", Chapters: 4, Abbreviations: []string{"Colos"}},
+	{ID: 52, Name: "1 Thessalonians", OSIS: "1Thess", USFM: "1TH", Chapters: 5, Abbreviations: []string{"1Th", "1Thes"}},
+	{ID: 53, Name: "2 Thessalonians", OSIS: "2Thess", USFM: "2TH", Chapters: 3, Abbreviations: []string{"2Th", "2Thes"}},
+	{ID: 54, Name: "1 Timothy", OSIS: "1Tim", USFM: "1TI", Chapters: 6, Abbreviations: []string{"1Ti", "1Tm"}},
+	{ID: 55, Name: "2 Timothy", OSIS: "2Tim", USFM: "2TI", Chapters: 4, Abbreviations: []string{"2Ti", "2Tm"}},
+	{ID: 56, Name: "Titus", OSIS: "Titus", USFM: "TIT", Chapters: 3, Abbreviations: []string{"Ti", "Tit"}},
+	{ID: 57, Name: "Philemon", OSIS: "Phlm", USFM: "PHM", Chapters: 1, Abbreviations: []string{"Phm", "Philem", "Pm"}},
+	{ID: 58, Name: "Hebrews", OSIS: "Heb", USFM: "HEB", Chapters: 13, Abbreviations: []string{"He"}},
+	{ID: 59, Name: "James", OSIS: "Jas", USFM: "JAS", Chapters: 5, Abbreviations: []string{"Jm"}},
+	{ID: 60, Name: "1 Peter", OSIS: "1Pet", USFM: "1PE", Chapters: 5, Abbreviations: []string{"1Pe", "1Pt", "1P"}},
+	{ID: 61, Name: "2 Peter", OSIS: "2Pet", USFM: "2PE", Chapters: 3, Abbreviations: []string{"2Pe", "2Pt", "2P"}},
+	{ID: 62, Name: "1 John", OSIS: "1John", USFM: "1JN", Chapters: 5, Abbreviations: []string{"1Jn", "1Jhn", "1Jo"}},
+	{ID: 63, Name: "2 John", OSIS: "2John", USFM: "2JN", Chapters: 1, Abbreviations: []string{"2Jn", "2Jhn", "2Jo"}},
+	{ID: 64, Name: "3 John", OSIS: "3John", USFM: "3JN", Chapters: 1, Abbreviations: []string{"3Jn", "3Jhn", "3Jo"}},
+	{ID: 65, Name: "Jude", OSIS: "Jude", USFM: "JUD", Chapters: 1, Abbreviations: []string{"Jud", "Jd"}},
+	{ID: 66, Name: "Revelation", OSIS: "Rev", USFM: "REV", Chapters: 22, Abbreviations: []string{"Re", "Rv", "Revelations", "Apocalypse"}},
+}
+
+// ByID returns the book with the given book_id
+func ByID(id int) (Book, bool) {
+	if id < 1 || id > len(Books) {
+		return Book{}, false
+	}
+	return Books[id-1], true
+}
+
+// ByOSIS returns the book for an OSIS book code such as "Gen" or "1Cor"
+func ByOSIS(code string) (Book, bool) {
+	for _, book := range Books {
+		if strings.EqualFold(book.OSIS, code) {
+			return book, true
+		}
+	}
+	return Book{}, false
+}
+
+// ByUSFM returns the book for a three-character USFM book code such as "GEN"
+func ByUSFM(code string) (Book, bool) {
+	for _, book := range Books {
+		if strings.EqualFold(book.USFM, code) {
+			return book, true
+		}
+	}
+	return Book{}, false
+}
+
+// ordinals are spelled-out book number prefixes, e.g. "II Kings" or "First John"
+var ordinals = map[string]string{
+	"i": "1", "ii": "2", "iii": "3",
+	"first": "1", "second": "2", "third": "3",
+	"1st": "1", "2nd": "2", "3rd": "3",
+}
+
+// aliases maps normalized names, codes and abbreviations to book IDs
+var aliases = func() map[string]int {
+	m := map[string]int{}
+	for _, book := range Books {
+		keys := append([]string{book.Name, book.OSIS, book.USFM}, book.Abbreviations...)
+		for _, key := range keys {
+			key = normalizeName(key)
+			if id, ok := m[key]; ok && id != book.ID {
+				panic("canon: alias " + key + " is used by more than one book")
+			}
+			m[key] = book.ID
+		}
+	}
+	return m
+}()
+
+// Lookup finds a book by name, OSIS or USFM code, or common abbreviation.
+// Case, spaces and periods are ignored, ordinal prefixes such as "I", "First"
+// or "1st" are accepted, and an unambiguous prefix of the full name matches.
+func Lookup(name string) (Book, bool) {
+	key := normalizeName(name)
+	if key == "" {
+		return Book{}, false
+	}
+	if id, ok := aliases[key]; ok {
+		return Books[id-1], true
+	}
+
+	if len(key) < 2 {
+		return Book{}, false
+	}
+	var match *Book
+	for i := range Books {
+		if strings.HasPrefix(normalizeName(Books[i].Name), key) {
+			if match != nil {
+				return Book{}, false
+			}
+			match = &Books[i]
+		}
+	}
+	if match == nil {
+		return Book{}, false
+	}
+	return *match, true
+}
+
+func normalizeName(name string) string {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(name, ".", " ")))
+	if len(fields) > 1 {
+		if n, ok := ordinals[fields[0]]; ok {
+			fields[0] = n
+		}
+	}
+	return strings.Join(fields, "")
+}
+
+const (
+	OldTestament = "OT"
+	NewTestament = "NT"
+)
+
+// Testament returns OldTestament for Genesis-Malachi and NewTestament otherwise
+func (b Book) Testament() string {
+	if b.ID <= 39 {
+		return OldTestament
+	}
+	return NewTestament
+}
+
+// Genre returns the traditional literary grouping of the book
+func (b Book) Genre() string {
+	switch {
+	case b.ID <= 5:
+		return "Law"
+	case b.ID <= 17:
+		return "History"
+	case b.ID <= 22:
+		return "Wisdom"
+	case b.ID <= 27:
+		return "Major Prophets"
+	case b.ID <= 39:
+		return "Minor Prophets"
+	case b.ID <= 43:
+		return "Gospels"
+	case b.ID == 44:
+		return "History"
+	case b.ID <= 57:
+		return "Pauline Epistles"
+	case b.ID <= 65:
+		return "General Epistles"
+	}
+	return "Apocalyptic"
+}
@@ -0,0 +1,68 @@
+// Command explain-cache invalidates cached verse explanations, e.g. after a
+// prompt or model change that should not wait for entries to expire.
+//
+//	go run ./cmd/explain-cache -translation niv -book John
+//	go run ./cmd/explain-cache -all
+//	go run ./cmd/explain-cache -expired
+package main
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/config"
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/logging"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	var (
+		translationID = flag.String("translation", "", "only invalidate this translation")
+		bookName      = flag.String("book", "", "only invalidate this book, e.g. John or 43")
+		all           = flag.Bool("all", false, "invalidate every cached explanation")
+		expired       = flag.Bool("expired", false, "only remove entries whose TTL has passed")
+	)
+	flag.Parse()
+
+	if !*all && !*expired && *translationID == "" && *bookName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	bookID := 0
+	if *bookName != "" {
+		book, ok := canon.Lookup(*bookName)
+		if id, err := strconv.Atoi(*bookName); err == nil {
+			book, ok = canon.ByID(id)
+		}
+		if !ok {
+			log.Fatalf("unknown book %q", *bookName)
+		}
+		bookID = book.ID
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	dbClient, err := database.NewDatabaseClient(cfg.Database, logging.New(os.Stderr, cfg.Log.LoggerConfig()))
+	if err != nil {
+		log.Fatalf("failed to initialize Database Client: %v", err)
+	}
+
+	ctx := context.Background()
+	var deleted int64
+	if *expired {
+		deleted, err = dbClient.DeleteExpiredExplanations(ctx)
+	} else {
+		deleted, err = dbClient.DeleteExplanations(ctx, strings.ToLower(*translationID), bookID)
+	}
+	if err != nil {
+		log.Fatalf("failed to invalidate explanations: %v", err)
+	}
+	log.Printf("Removed %d cached explanations", deleted)
+}
@@ -0,0 +1,110 @@
+// Command import loads an OSIS, USFM or Zefania Bible text into the verses
+// table. Re-running it for the same translation replaces the previous import.
+//
+//	go run ./cmd/import -file kjv.osis.xml -translation kjv -name "King James Version" -language en
+package main
+
+import (
+	"bible_reading_backend_nkv/config"
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/importer"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/models"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	var (
+		file          = flag.String("file", "", "path to the Bible text (required)")
+		format        = flag.String("format", "", "osis, usfm or zefania (detected from the file if empty)")
+		translationID = flag.String("translation", "", "translation id, e.g. kjv (required)")
+		name          = flag.String("name", "", "translation name (required)")
+		language      = flag.String("language", "en", "ISO 639 language code")
+		copyright     = flag.String("copyright", "", "copyright notice")
+		versification = flag.String("versification", "KJV", "versification scheme")
+		dryRun        = flag.Bool("dry-run", false, "parse and validate without writing to the database")
+		strict        = flag.Bool("strict", false, "abort if any verse is skipped")
+	)
+	flag.Parse()
+
+	if *file == "" || *translationID == "" || *name == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *file, err)
+	}
+
+	var f importer.Format
+	if *format != "" {
+		f, err = importer.ParseFormat(*format)
+	} else {
+		f, err = importer.DetectFormat(*file, data)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	parsed, issues, err := importer.Parse(f, bytes.NewReader(data))
+	if err != nil {
+		log.Fatalf("failed to parse %s: %v", *file, err)
+	}
+	verses, invalid := importer.Validate(parsed)
+	issues = append(issues, invalid...)
+
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", issue)
+	}
+	log.Printf("Parsed %d verses from %s (%s), skipped %d", len(verses), *file, f, len(issues))
+
+	if *strict && len(issues) > 0 {
+		log.Fatalf("aborting: %d verses skipped in strict mode", len(issues))
+	}
+	if len(verses) == 0 {
+		log.Fatalf("aborting: no verses found in %s", *file)
+	}
+	if *dryRun {
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	dbClient, err := database.NewDatabaseClient(cfg.Database, logging.New(os.Stderr, cfg.Log.LoggerConfig()))
+	if err != nil {
+		log.Fatalf("failed to initialize Database Client: %v", err)
+	}
+	client, ok := dbClient.(*database.Client)
+	if !ok {
+		log.Fatalf("failed to get database client")
+	}
+	migrator, err := client.Migrator()
+	if err != nil {
+		log.Fatalf("failed to migrate database: %s", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		log.Fatalf("failed to migrate database: %s", err)
+	}
+
+	translation := &models.Translation{
+		ID:            strings.ToLower(*translationID),
+		Name:          *name,
+		Language:      *language,
+		Copyright:     *copyright,
+		Versification: *versification,
+	}
+	rows := importer.ToModels(translation.ID, verses)
+	if err := client.ReplaceTranslation(context.Background(), translation, rows); err != nil {
+		log.Fatalf("failed to import %s: %v", translation.ID, err)
+	}
+	log.Printf("Imported %d verses into translation %q", len(rows), translation.ID)
+}
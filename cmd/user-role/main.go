@@ -0,0 +1,49 @@
+// Command user-role changes a user's role, e.g. to create the first admin,
+// who can then manage roles through /api/admin.
+//
+//	go run ./cmd/user-role -email john@example.com -role admin
+package main
+
+import (
+	"bible_reading_backend_nkv/config"
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/models"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	var (
+		email = flag.String("email", "", "email address of the user")
+		role  = flag.String("role", "", "new role: user, moderator or admin")
+	)
+	flag.Parse()
+
+	if *email == "" || !models.ValidRole(*role) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	dbClient, err := database.NewDatabaseClient(cfg.Database, logging.New(os.Stderr, cfg.Log.LoggerConfig()))
+	if err != nil {
+		log.Fatalf("failed to initialize Database Client: %v", err)
+	}
+
+	ctx := context.Background()
+	user, err := dbClient.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(*email)))
+	if err != nil {
+		log.Fatalf("failed to find user %s: %v", *email, err)
+	}
+	if err := dbClient.UpdateUser(ctx, user.ID, map[string]interface{}{"role": *role}); err != nil {
+		log.Fatalf("failed to update role: %v", err)
+	}
+	log.Printf("Set role of %s (user %d) to %s", user.Email, user.ID, *role)
+}
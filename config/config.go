@@ -0,0 +1,450 @@
+// Package config loads the application settings once at startup. Values come
+// from, in increasing order of precedence, built-in defaults, an optional
+// YAML file, a .env file and the process environment.
+package config
+
+import (
+	"bible_reading_backend_nkv/explain"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/mailer"
+	"bible_reading_backend_nkv/tracing"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when CONFIG_FILE is not set, if it exists
+const DefaultFile = "config.yaml"
+
+// Config holds every setting of the server and the command line tools. The
+// env tag names the environment variable that overrides each field.
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Database  Database  `yaml:"database"`
+	JWT       JWT       `yaml:"jwt"`
+	Auth      Auth      `yaml:"auth"`
+	Explain   Explain   `yaml:"explain"`
+	LLM       LLM       `yaml:"llm"`
+	Mail      Mail      `yaml:"mail"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+	Log       Log       `yaml:"log"`
+	Metrics   Metrics   `yaml:"metrics"`
+	Tracing   Tracing   `yaml:"tracing"`
+}
+
+type Server struct {
+	// Host is the interface to listen on; empty means all of them
+	Host string `yaml:"host" env:"LISTEN_HOST"`
+	Port int    `yaml:"port" env:"PORT"`
+	// TLSCertFile and TLSKeyFile serve HTTPS when both are set
+	TLSCertFile string `yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+	// Timeouts of the HTTP server; 0 means none. The write timeout bounds
+	// whole responses, so it has to cover streamed explanations.
+	ReadTimeoutSeconds       int `yaml:"read_timeout_seconds" env:"HTTP_READ_TIMEOUT_SECONDS"`
+	ReadHeaderTimeoutSeconds int `yaml:"read_header_timeout_seconds" env:"HTTP_READ_HEADER_TIMEOUT_SECONDS"`
+	WriteTimeoutSeconds      int `yaml:"write_timeout_seconds" env:"HTTP_WRITE_TIMEOUT_SECONDS"`
+	IdleTimeoutSeconds       int `yaml:"idle_timeout_seconds" env:"HTTP_IDLE_TIMEOUT_SECONDS"`
+	// ShutdownTimeoutSeconds is how long in-flight requests may run after
+	// SIGTERM or SIGINT before their connections are closed
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds" env:"SHUTDOWN_TIMEOUT_SECONDS"`
+	// APIURL is the public address of this API, used in email links
+	APIURL string `yaml:"api_url" env:"API_URL"`
+	// FrontendURL is the address of the web app, used in email links
+	FrontendURL string   `yaml:"frontend_url" env:"FRONTEND_URL"`
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ALLOWED_ORIGINS"`
+	// TrustProxy takes client IPs from X-Forwarded-For; only enable it
+	// behind a reverse proxy that sets the header
+	TrustProxy bool `yaml:"trust_proxy" env:"TRUST_PROXY"`
+}
+
+type Database struct {
+	DSN string `yaml:"dsn" env:"DB_DSN"`
+	// AutoMigrate applies pending schema migrations when the server starts;
+	// turn it off to run "server migrate up" as a separate deploy step
+	AutoMigrate bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+	// SlowQueryMilliseconds is the duration above which queries are logged
+	// as warnings; faster ones are logged at debug level
+	SlowQueryMilliseconds int `yaml:"slow_query_milliseconds" env:"DB_SLOW_QUERY_MS"`
+}
+
+type JWT struct {
+	Secret              string `yaml:"secret" env:"JWT_SECRET"`
+	AccessExpiryMinutes int    `yaml:"access_expiry_minutes" env:"JWT_ACCESS_EXPIRY_MINUTES"`
+	RefreshExpiryDays   int    `yaml:"refresh_expiry_days" env:"JWT_REFRESH_EXPIRY_DAYS"`
+}
+
+type Auth struct {
+	// EmailVerification is "optional" or "required"; when required,
+	// unverified users cannot log in
+	EmailVerification          string `yaml:"email_verification" env:"EMAIL_VERIFICATION"`
+	PasswordResetExpiryMinutes int    `yaml:"password_reset_expiry_minutes" env:"PASSWORD_RESET_EXPIRY_MINUTES"`
+	// LoginMaxAttempts failures for one email lock it for LoginLockoutMinutes
+	LoginMaxAttempts int `yaml:"login_max_attempts" env:"LOGIN_MAX_ATTEMPTS"`
+	// LoginIPMaxAttempts failures from one IP, across any emails, block it
+	LoginIPMaxAttempts  int `yaml:"login_ip_max_attempts" env:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginLockoutMinutes int `yaml:"login_lockout_minutes" env:"LOGIN_LOCKOUT_MINUTES"`
+}
+
+type Explain struct {
+	// CacheTTLHours is how long explanations are cached; 0 disables the cache
+	CacheTTLHours int `yaml:"cache_ttl_hours" env:"EXPLAIN_CACHE_TTL_HOURS"`
+	// DailyQuota and DailyQuotaAnonymous limit uncached explanations per
+	// user and per client IP each UTC day; 0 means unlimited
+	DailyQuota          int `yaml:"daily_quota" env:"EXPLAIN_DAILY_QUOTA"`
+	DailyQuotaAnonymous int `yaml:"daily_quota_anonymous" env:"EXPLAIN_DAILY_QUOTA_ANONYMOUS"`
+}
+
+type LLM struct {
+	Provider string `yaml:"provider" env:"LLM_PROVIDER"`
+	Model    string `yaml:"model" env:"LLM_MODEL"`
+	BaseURL  string `yaml:"base_url" env:"LLM_BASE_URL"`
+	// APIKey falls back to OPENAI_API_KEY
+	APIKey         string `yaml:"api_key" env:"LLM_API_KEY,OPENAI_API_KEY"`
+	MaxTokens      int    `yaml:"max_tokens" env:"LLM_MAX_TOKENS"`
+	TimeoutSeconds int    `yaml:"timeout_seconds" env:"LLM_TIMEOUT_SECONDS"`
+}
+
+type Mail struct {
+	Driver       string `yaml:"driver" env:"MAIL_DRIVER"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+}
+
+// RateLimit holds request rates such as "60/m", or "off"
+type RateLimit struct {
+	// API applies to every /api request
+	API string `yaml:"api" env:"RATE_LIMIT_API"`
+	// Auth applies to login, registration and the other credential endpoints
+	Auth string `yaml:"auth" env:"RATE_LIMIT_AUTH"`
+	// Explain applies to the LLM-backed explain endpoints
+	Explain string `yaml:"explain" env:"RATE_LIMIT_EXPLAIN"`
+}
+
+type Log struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// Format is json, or text for reading logs in a terminal
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+type Metrics struct {
+	// Enabled serves Prometheus metrics at /metrics
+	Enabled bool `yaml:"enabled" env:"METRICS_ENABLED"`
+	// Token, when set, must be sent as a bearer token to read /metrics
+	Token string `yaml:"token" env:"METRICS_TOKEN"`
+}
+
+type Tracing struct {
+	// Exporter is none, otlp or stdout
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint is the OTLP/HTTP collector URL; empty falls back to
+	// OTEL_EXPORTER_OTLP_ENDPOINT, then http://localhost:4318
+	Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	// SampleRatio is the share of new traces that are recorded, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// Default returns the settings used for anything that is not configured.
+// Database.DSN and JWT.Secret have no default.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:                     8000,
+			ReadTimeoutSeconds:       30,
+			ReadHeaderTimeoutSeconds: 10,
+			WriteTimeoutSeconds:      120,
+			IdleTimeoutSeconds:       120,
+			ShutdownTimeoutSeconds:   30,
+			APIURL:                   "http://localhost:8000",
+			FrontendURL:              "http://localhost:3000",
+			CORSOrigins: []string{
+				"https://ashley-samuel.in",
+				"http://13.203.234.131:3000",
+				"http://localhost:3000", // React dev server
+			},
+		},
+		Database: Database{
+			AutoMigrate:           true,
+			SlowQueryMilliseconds: 200,
+		},
+		JWT: JWT{
+			AccessExpiryMinutes: 15,
+			RefreshExpiryDays:   30,
+		},
+		Auth: Auth{
+			EmailVerification:          "optional",
+			PasswordResetExpiryMinutes: 60,
+			LoginMaxAttempts:           10,
+			LoginIPMaxAttempts:         50,
+			LoginLockoutMinutes:        15,
+		},
+		Explain: Explain{
+			CacheTTLHours:       720,
+			DailyQuota:          50,
+			DailyQuotaAnonymous: 10,
+		},
+		LLM: LLM{
+			MaxTokens:      500,
+			TimeoutSeconds: 30,
+		},
+		Mail: Mail{
+			Driver:   mailer.DriverLog,
+			SMTPPort: 587,
+			From:     "no-reply@localhost",
+		},
+		RateLimit: RateLimit{
+			API:     "300/m",
+			Auth:    "20/m",
+			Explain: "10/m",
+		},
+		Log: Log{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		Metrics: Metrics{
+			Enabled: true,
+		},
+		Tracing: Tracing{
+			Exporter:    tracing.ExporterNone,
+			SampleRatio: 1,
+			ServiceName: "bible-reading-backend",
+		},
+	}
+}
+
+// Load reads .env into the environment, then builds the configuration from
+// the YAML file named by CONFIG_FILE (default config.yaml, skipped if
+// missing) and the environment, and validates it
+func Load() (*Config, error) {
+	// A missing .env is fine; variables may come from the environment
+	godotenv.Load()
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = DefaultFile
+	}
+	return load(path, explicit, os.LookupEnv)
+}
+
+// load builds the configuration from the YAML file at path and lookup. The
+// file is required when explicit is set.
+func load(path string, explicit bool, lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := yaml.Unmarshal(data, &cfg); err != nil {
+				return nil, fmt.Errorf("config file %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("config file: %w", err)
+		}
+	}
+
+	if err := applyEnv(&cfg, lookup); err != nil {
+		return nil, err
+	}
+	// JWT_EXPIRY_HOURS is the older way of setting the access token lifetime
+	if v, _ := lookup("JWT_ACCESS_EXPIRY_MINUTES"); v == "" {
+		if v, _ := lookup("JWT_EXPIRY_HOURS"); v != "" {
+			hours, err := parseInt("JWT_EXPIRY_HOURS", v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.JWT.AccessExpiryMinutes = hours * 60
+		}
+	}
+
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// normalize trims values and lower-cases the ones that are matched as keywords
+func (c *Config) normalize() {
+	c.Server.Host = strings.TrimSpace(c.Server.Host)
+	c.Server.TLSCertFile = strings.TrimSpace(c.Server.TLSCertFile)
+	c.Server.TLSKeyFile = strings.TrimSpace(c.Server.TLSKeyFile)
+	c.Server.APIURL = strings.TrimRight(strings.TrimSpace(c.Server.APIURL), "/")
+	c.Server.FrontendURL = strings.TrimRight(strings.TrimSpace(c.Server.FrontendURL), "/")
+	c.Auth.EmailVerification = strings.ToLower(strings.TrimSpace(c.Auth.EmailVerification))
+	c.LLM.Provider = strings.ToLower(strings.TrimSpace(c.LLM.Provider))
+	c.LLM.Model = strings.TrimSpace(c.LLM.Model)
+	c.LLM.BaseURL = strings.TrimSpace(c.LLM.BaseURL)
+	// Keys pasted into .env files often keep their quotes
+	c.LLM.APIKey = strings.TrimSpace(strings.Trim(strings.TrimSpace(c.LLM.APIKey), `"'`))
+	c.Mail.Driver = strings.ToLower(strings.TrimSpace(c.Mail.Driver))
+	c.Mail.SMTPHost = strings.TrimSpace(c.Mail.SMTPHost)
+	c.Mail.From = strings.TrimSpace(c.Mail.From)
+	if c.Mail.Driver == "" {
+		c.Mail.Driver = mailer.DriverLog
+	}
+	if c.Mail.From == "" {
+		c.Mail.From = "no-reply@localhost"
+	}
+	c.Log.Level = strings.ToLower(strings.TrimSpace(c.Log.Level))
+	c.Log.Format = strings.ToLower(strings.TrimSpace(c.Log.Format))
+	c.Metrics.Token = strings.TrimSpace(c.Metrics.Token)
+	c.Tracing.Exporter = strings.ToLower(strings.TrimSpace(c.Tracing.Exporter))
+	c.Tracing.Endpoint = strings.TrimSpace(c.Tracing.Endpoint)
+	c.Tracing.ServiceName = strings.TrimSpace(c.Tracing.ServiceName)
+	if c.Tracing.Exporter == "" {
+		c.Tracing.Exporter = tracing.ExporterNone
+	}
+}
+
+// Validate reports every missing or invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Database.DSN != "", "DB_DSN is required")
+	check(c.JWT.Secret != "", "JWT_SECRET is required")
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "PORT must be between 1 and 65535")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""),
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.Server.ReadTimeoutSeconds >= 0, "HTTP_READ_TIMEOUT_SECONDS must not be negative")
+	check(c.Server.ReadHeaderTimeoutSeconds >= 0, "HTTP_READ_HEADER_TIMEOUT_SECONDS must not be negative")
+	check(c.Server.WriteTimeoutSeconds >= 0, "HTTP_WRITE_TIMEOUT_SECONDS must not be negative")
+	check(c.Server.IdleTimeoutSeconds >= 0, "HTTP_IDLE_TIMEOUT_SECONDS must not be negative")
+	check(c.Server.ShutdownTimeoutSeconds > 0, "SHUTDOWN_TIMEOUT_SECONDS must be positive")
+	check(c.Database.SlowQueryMilliseconds > 0, "DB_SLOW_QUERY_MS must be positive")
+	check(c.JWT.AccessExpiryMinutes > 0, "JWT_ACCESS_EXPIRY_MINUTES must be positive")
+	check(c.JWT.RefreshExpiryDays > 0, "JWT_REFRESH_EXPIRY_DAYS must be positive")
+	check(c.Auth.EmailVerification == "optional" || c.Auth.EmailVerification == "required",
+		"EMAIL_VERIFICATION must be optional or required")
+	check(c.Auth.PasswordResetExpiryMinutes > 0, "PASSWORD_RESET_EXPIRY_MINUTES must be positive")
+	check(c.Auth.LoginMaxAttempts > 0, "LOGIN_MAX_ATTEMPTS must be positive")
+	check(c.Auth.LoginIPMaxAttempts > 0, "LOGIN_IP_MAX_ATTEMPTS must be positive")
+	check(c.Auth.LoginLockoutMinutes > 0, "LOGIN_LOCKOUT_MINUTES must be positive")
+	check(c.Explain.CacheTTLHours >= 0, "EXPLAIN_CACHE_TTL_HOURS must not be negative")
+	check(c.Explain.DailyQuota >= 0, "EXPLAIN_DAILY_QUOTA must not be negative")
+	check(c.Explain.DailyQuotaAnonymous >= 0, "EXPLAIN_DAILY_QUOTA_ANONYMOUS must not be negative")
+	check(c.LLM.MaxTokens > 0, "LLM_MAX_TOKENS must be positive")
+	check(c.LLM.TimeoutSeconds > 0, "LLM_TIMEOUT_SECONDS must be positive")
+	check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort < 65536, "SMTP_PORT must be between 1 and 65535")
+	for name, rate := range map[string]string{
+		"RATE_LIMIT_API":     c.RateLimit.API,
+		"RATE_LIMIT_AUTH":    c.RateLimit.Auth,
+		"RATE_LIMIT_EXPLAIN": c.RateLimit.Explain,
+	} {
+		_, err := ParseRate(rate)
+		check(err == nil, "%s: %v", name, err)
+	}
+	_, err := logging.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL: %v", err)
+	check(c.Log.Format == logging.FormatJSON || c.Log.Format == logging.FormatText,
+		"LOG_FORMAT must be json or text")
+	check(c.Tracing.Exporter == tracing.ExporterNone || c.Tracing.Exporter == tracing.ExporterOTLP ||
+		c.Tracing.Exporter == tracing.ExporterStdout, "TRACING_EXPORTER must be none, otlp or stdout")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME must not be empty")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Address is the host:port to listen on
+func (s Server) Address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// TLS reports whether the server should serve HTTPS
+func (s Server) TLS() bool {
+	return s.TLSCertFile != ""
+}
+
+func (s Server) ShutdownTimeout() time.Duration {
+	return time.Duration(s.ShutdownTimeoutSeconds) * time.Second
+}
+
+// AccessExpiry is the lifetime of access tokens
+func (j JWT) AccessExpiry() time.Duration {
+	return time.Duration(j.AccessExpiryMinutes) * time.Minute
+}
+
+// RefreshExpiry is the lifetime of refresh tokens
+func (j JWT) RefreshExpiry() time.Duration {
+	return time.Duration(j.RefreshExpiryDays) * 24 * time.Hour
+}
+
+func (a Auth) PasswordResetExpiry() time.Duration {
+	return time.Duration(a.PasswordResetExpiryMinutes) * time.Minute
+}
+
+func (a Auth) LoginLockout() time.Duration {
+	return time.Duration(a.LoginLockoutMinutes) * time.Minute
+}
+
+// CacheTTL is how long explanations are cached; zero disables the cache
+func (e Explain) CacheTTL() time.Duration {
+	return time.Duration(e.CacheTTLHours) * time.Hour
+}
+
+// SlowQuery is the duration above which queries are logged as warnings
+func (d Database) SlowQuery() time.Duration {
+	return time.Duration(d.SlowQueryMilliseconds) * time.Millisecond
+}
+
+// ExplainerConfig returns the settings for explain.New
+func (l LLM) ExplainerConfig() explain.Config {
+	return explain.Config{
+		Provider:  l.Provider,
+		Model:     l.Model,
+		BaseURL:   l.BaseURL,
+		APIKey:    l.APIKey,
+		MaxTokens: l.MaxTokens,
+		Timeout:   time.Duration(l.TimeoutSeconds) * time.Second,
+	}
+}
+
+// MailerConfig returns the settings for mailer.New
+func (m Mail) MailerConfig() mailer.Config {
+	return mailer.Config{
+		Driver:   m.Driver,
+		Host:     m.SMTPHost,
+		Port:     m.SMTPPort,
+		Username: m.SMTPUsername,
+		Password: m.SMTPPassword,
+		From:     m.From,
+	}
+}
+
+// LoggerConfig returns the settings for logging.New; the level must have
+// been validated
+func (l Log) LoggerConfig() logging.Config {
+	level, _ := logging.ParseLevel(l.Level)
+	return logging.Config{Format: l.Format, Level: level}
+}
+
+// TracerConfig returns the settings for tracing.Setup
+func (t Tracing) TracerConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    t.Exporter,
+		Endpoint:    t.Endpoint,
+		SampleRatio: t.SampleRatio,
+		ServiceName: t.ServiceName,
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/tracing"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+var required = map[string]string{"DB_DSN": "user:pass@tcp(db:3306)/bible", "JWT_SECRET": "secret"}
+
+func withRequired(vars map[string]string) map[string]string {
+	out := map[string]string{}
+	for k, v := range required {
+		out[k] = v
+	}
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load("", false, env(required))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8000", cfg.Server.Address())
+	assert.False(t, cfg.Server.TLS())
+	assert.Equal(t, 30*time.Second, cfg.Server.ShutdownTimeout())
+	assert.True(t, cfg.Database.AutoMigrate)
+	assert.Equal(t, 200*time.Millisecond, cfg.Database.SlowQuery())
+	assert.Equal(t, "secret", cfg.JWT.Secret)
+	assert.Equal(t, 15*time.Minute, cfg.JWT.AccessExpiry())
+	assert.Equal(t, 30*24*time.Hour, cfg.JWT.RefreshExpiry())
+	assert.Equal(t, "optional", cfg.Auth.EmailVerification)
+	assert.Equal(t, 720*time.Hour, cfg.Explain.CacheTTL())
+	assert.Equal(t, "log", cfg.Mail.Driver)
+	assert.Contains(t, cfg.Server.CORSOrigins, "http://localhost:3000")
+	assert.Equal(t, logging.Config{Format: logging.FormatJSON, Level: slog.LevelInfo}, cfg.Log.LoggerConfig())
+	assert.True(t, cfg.Metrics.Enabled)
+	assert.Empty(t, cfg.Metrics.Token)
+	assert.Equal(t, tracing.Config{Exporter: tracing.ExporterNone, SampleRatio: 1, ServiceName: "bible-reading-backend"},
+		cfg.Tracing.TracerConfig())
+}
+
+func TestLoadRequired(t *testing.T) {
+	_, err := load("", false, env(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DB_DSN is required")
+	assert.Contains(t, err.Error(), "JWT_SECRET is required")
+}
+
+func TestLoadEnv(t *testing.T) {
+	cfg, err := load("", false, env(withRequired(map[string]string{
+		"LISTEN_HOST":          "127.0.0.1",
+		"PORT":                 "9000",
+		"TLS_CERT_FILE":        "/etc/tls/cert.pem",
+		"TLS_KEY_FILE":         "/etc/tls/key.pem",
+		"CORS_ALLOWED_ORIGINS": "https://a.example, https://b.example",
+		"TRUST_PROXY":          "true",
+		"API_URL":              "https://api.example/",
+		"JWT_EXPIRY_HOURS":     "2",
+		"EMAIL_VERIFICATION":   "Required",
+		"OPENAI_API_KEY":       `"sk-test"`,
+		"MAIL_DRIVER":          "SMTP",
+		"SMTP_PORT":            "2525",
+		"RATE_LIMIT_AUTH":      "off",
+		"DB_AUTO_MIGRATE":      "false",
+		"LOG_LEVEL":            "Debug",
+		"LOG_FORMAT":           "text",
+		"METRICS_ENABLED":      "false",
+		"METRICS_TOKEN":        " scrape ",
+		"TRACING_EXPORTER":     "OTLP",
+		"TRACING_ENDPOINT":     "http://collector:4318",
+		"TRACING_SAMPLE_RATIO": "0.25",
+		"OTEL_SERVICE_NAME":    "bible-api",
+	})))
+	require.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1:9000", cfg.Server.Address())
+	assert.True(t, cfg.Server.TLS())
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.Server.CORSOrigins)
+	assert.True(t, cfg.Server.TrustProxy)
+	assert.Equal(t, "https://api.example", cfg.Server.APIURL)
+	assert.Equal(t, 2*time.Hour, cfg.JWT.AccessExpiry())
+	assert.Equal(t, "required", cfg.Auth.EmailVerification)
+	assert.Equal(t, "sk-test", cfg.LLM.APIKey)
+	assert.Equal(t, "smtp", cfg.Mail.Driver)
+	assert.Equal(t, 2525, cfg.Mail.SMTPPort)
+	assert.Equal(t, "off", cfg.RateLimit.Auth)
+	assert.False(t, cfg.Database.AutoMigrate)
+	assert.Equal(t, logging.Config{Format: logging.FormatText, Level: slog.LevelDebug}, cfg.Log.LoggerConfig())
+	assert.False(t, cfg.Metrics.Enabled)
+	assert.Equal(t, "scrape", cfg.Metrics.Token)
+	assert.Equal(t, tracing.Config{Exporter: tracing.ExporterOTLP, Endpoint: "http://collector:4318", SampleRatio: 0.25, ServiceName: "bible-api"},
+		cfg.Tracing.TracerConfig())
+
+	// The current variable wins over the older one
+	cfg, err = load("", false, env(withRequired(map[string]string{
+		"JWT_ACCESS_EXPIRY_MINUTES": "5",
+		"JWT_EXPIRY_HOURS":          "2",
+		"LLM_API_KEY":               "sk-llm",
+		"OPENAI_API_KEY":            "sk-openai",
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.JWT.AccessExpiry())
+	assert.Equal(t, "sk-llm", cfg.LLM.APIKey)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := load("", false, env(withRequired(map[string]string{"PORT": "eighty"})))
+	assert.ErrorContains(t, err, "PORT must be a whole number")
+	_, err = load("", false, env(withRequired(map[string]string{"TRACING_SAMPLE_RATIO": "half"})))
+	assert.ErrorContains(t, err, "TRACING_SAMPLE_RATIO must be a number")
+
+	_, err = load("", false, env(withRequired(map[string]string{
+		"EMAIL_VERIFICATION": "sometimes",
+		"RATE_LIMIT_API":     "lots",
+		"LOGIN_MAX_ATTEMPTS": "0",
+		"TLS_CERT_FILE":      "/etc/tls/cert.pem",
+		"LOG_LEVEL":          "loud",
+		"TRACING_EXPORTER":   "zipkin",
+	})))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "EMAIL_VERIFICATION")
+	assert.Contains(t, err.Error(), "RATE_LIMIT_API")
+	assert.Contains(t, err.Error(), "LOGIN_MAX_ATTEMPTS")
+	assert.Contains(t, err.Error(), "TLS_CERT_FILE and TLS_KEY_FILE")
+	assert.Contains(t, err.Error(), "LOG_LEVEL")
+	assert.Contains(t, err.Error(), "TRACING_EXPORTER")
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: 8080
+  cors_origins: [https://app.example]
+database:
+  dsn: from-file
+jwt:
+  secret: file-secret
+explain:
+  daily_quota: 5
+`), 0o600))
+
+	cfg, err := load(path, true, env(map[string]string{"JWT_SECRET": "env-secret"}))
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, []string{"https://app.example"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, "from-file", cfg.Database.DSN)
+	assert.Equal(t, 5, cfg.Explain.DailyQuota)
+	// The environment overrides the file; unset values keep their defaults
+	assert.Equal(t, "env-secret", cfg.JWT.Secret)
+	assert.Equal(t, 10, cfg.Explain.DailyQuotaAnonymous)
+
+	// The default file is optional, a named one is not
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	_, err = load(missing, false, env(required))
+	assert.NoError(t, err)
+	_, err = load(missing, true, env(required))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// applyEnv overrides the fields of cfg that have an env tag with the value of
+// the first of the tag's variables that is set. Lists are comma separated.
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	return applyEnvValue(reflect.ValueOf(cfg).Elem(), lookup)
+}
+
+func applyEnvValue(v reflect.Value, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvValue(field, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tag := t.Field(i).Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		for _, name := range strings.Split(tag, ",") {
+			value, ok := lookup(name)
+			if !ok || value == "" {
+				continue
+			}
+			if err := setField(field, name, value); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, name, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := parseInt(name, value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", name, value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", name, value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s: unsupported field type %s", name, field.Type())
+	}
+	return nil
+}
+
+func parseInt(name, value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a whole number, got %q", name, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate allows Limit requests per Period. The zero Rate disables limiting.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRate parses rates such as "10/s", "60/m" or "1000/h". "off" and "0"
+// return the zero Rate.
+func ParseRate(s string) (Rate, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "off" || s == "0" {
+		return Rate{}, nil
+	}
+
+	count, unit, ok := strings.Cut(s, "/")
+	limit, err := strconv.Atoi(count)
+	if !ok || err != nil || limit <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q, expected e.g. 60/m", s)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+	period, ok := periods[unit]
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q, unit must be s, m, h or d", s)
+	}
+	return Rate{Limit: limit, Period: period}, nil
+}
+
+func (r Rate) String() string {
+	if r.Limit == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d per %s", r.Limit, r.Period)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{"10/s", Rate{10, time.Second}},
+		{" 60/M ", Rate{60, time.Minute}},
+		{"1000/h", Rate{1000, time.Hour}},
+		{"5/d", Rate{5, 24 * time.Hour}},
+		{"off", Rate{}},
+		{"0", Rate{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, in := range []string{"", "10", "ten/m", "-1/m", "10/w"} {
+		_, err := ParseRate(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// usersQuery filters users by a case-insensitive substring of their email or
+// name and, if role is set, by role
+func (c Client) usersQuery(ctx context.Context, search, role string) *gorm.DB {
+	query := c.DB.WithContext(ctx).Model(&models.User{})
+	if search = strings.TrimSpace(search); search != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(search)) + "%"
+		query = query.Where(
+			"LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?",
+			pattern, pattern, pattern,
+		)
+	}
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	return query
+}
+
+func (c Client) SearchUsers(ctx context.Context, search, role string, limit, offset int) ([]models.User, error) {
+	var users []models.User
+	result := c.usersQuery(ctx, search, role).
+		Order("id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users)
+	return users, result.Error
+}
+
+func (c Client) CountUsers(ctx context.Context, search, role string) (int64, error) {
+	var count int64
+	result := c.usersQuery(ctx, search, role).Count(&count)
+	return count, result.Error
+}
+
+// SetUserDisabled disables or re-enables an account. Disabling also revokes
+// all of the user's sessions.
+func (c Client) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		var disabledAt *time.Time
+		if disabled {
+			disabledAt = &now
+		}
+		result := tx.Model(&models.User{}).Where("id = ?", userID).Update("disabled_at", disabledAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if !disabled {
+			return nil
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// ForcePasswordReset replaces the user's password with a random one nobody
+// knows, revokes their sessions and earlier reset links and stores reset so
+// that the emailed link is the only way back in
+func (c Client) ForcePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	// bcrypt only uses the first 72 bytes, so the raw bytes are fine as a password
+	hashedPassword, err := bcrypt.GenerateFromPassword(random, bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		result := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", string(hashedPassword))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", reset.UserID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+// GetUserStats counts a user's favorites, highlights and active sessions and
+// looks up when they last logged in or refreshed a token and when they last read
+func (c Client) GetUserStats(ctx context.Context, userID int) (*models.UserStats, error) {
+	db := c.DB.WithContext(ctx)
+	var stats models.UserStats
+
+	if err := db.Model(&models.UserFavoriteVerse{}).Where("user_id = ?", userID).Count(&stats.Favorites).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.UserHighlightedVerse{}).Where("user_id = ?", userID).Count(&stats.Highlights).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now().UTC()).
+		Count(&stats.ActiveSessions).Error; err != nil {
+		return nil, err
+	}
+
+	// Logins and refreshes both create a session row
+	var lastSession models.Session
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Limit(1).Find(&lastSession).Error
+	if err != nil {
+		return nil, err
+	}
+	if lastSession.ID != 0 {
+		stats.LastActiveAt = &lastSession.CreatedAt
+	}
+
+	var lastRead models.UserLastRead
+	if err := db.Where("user_id = ?", userID).Limit(1).Find(&lastRead).Error; err != nil {
+		return nil, err
+	}
+	if lastRead.ID != 0 {
+		stats.LastReadAt = &lastRead.UpdatedAt
+	}
+	return &stats, nil
+}
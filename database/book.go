@@ -0,0 +1,115 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (c Client) GetAllBook(ctx context.Context, translationID string) ([]models.Book, error) {
+	var books []models.Book
+	result := c.DB.WithContext(ctx).
+		Where("translation_id = ?", translationID).
+		Order("canonical_order").
+		Find(&books)
+	return books, result.Error
+}
+
+func (c Client) GetBook(ctx context.Context, translationID string, bookId int) (*models.Book, error) {
+	var book models.Book
+	result := c.DB.WithContext(ctx).
+		Where("translation_id = ? AND id = ?", translationID, bookId).
+		First(&book)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &book, nil
+}
+
+func (c Client) GetAllChapter(ctx context.Context, translationID string, bookId int) (ChapterMaxDTO, error) {
+	book, err := c.GetBook(ctx, translationID, bookId)
+	if err != nil {
+		return ChapterMaxDTO{}, err
+	}
+	return ChapterMaxDTO{MaxChapter: int64(book.Chapters)}, nil
+}
+
+// RefreshBooks rebuilds the book catalogue of a translation from its verses
+func (c Client) RefreshBooks(ctx context.Context, translationID string) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return refreshBooks(tx, translationID)
+	})
+}
+
+// chapterCount is one row of the per-chapter verse count query
+type chapterCount struct {
+	BookID  int
+	Book    string
+	Chapter int
+	Verses  int
+}
+
+func refreshBooks(tx *gorm.DB, translationID string) error {
+	var counts []chapterCount
+	err := tx.Model(&models.Verse{}).
+		Select("book_id, MAX(book) AS book, chapter, COUNT(*) AS verses").
+		Where("translation_id = ?", translationID).
+		Group("book_id, chapter").
+		Order("book_id, chapter").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	books := catalogue(translationID, counts)
+	if err := tx.Where("translation_id = ?", translationID).Delete(&models.Book{}).Error; err != nil {
+		return err
+	}
+	if len(books) == 0 {
+		return nil
+	}
+	return tx.Create(&books).Error
+}
+
+// catalogue builds the book catalogue from per-chapter verse counts ordered
+// by book and chapter
+func catalogue(translationID string, counts []chapterCount) []models.Book {
+	var books []models.Book
+	for _, count := range counts {
+		if n := len(books); n == 0 || books[n-1].ID != count.BookID {
+			books = append(books, newCatalogueBook(translationID, count.BookID, count.Book))
+		}
+		book := &books[len(books)-1]
+		for len(book.VerseCounts) < count.Chapter {
+			book.VerseCounts = append(book.VerseCounts, 0)
+		}
+		book.VerseCounts[count.Chapter-1] = count.Verses
+		book.Chapters = len(book.VerseCounts)
+	}
+	return books
+}
+
+// newCatalogueBook fills in the canon metadata for a book. The name comes from
+// the translation's own text so that, e.g., "Psalm" vs "Psalms" is preserved.
+func newCatalogueBook(translationID string, bookID int, name string) models.Book {
+	book := models.Book{
+		TranslationID:  translationID,
+		ID:             bookID,
+		Name:           name,
+		CanonicalOrder: bookID,
+		Aliases:        []string{},
+		VerseCounts:    []int{},
+	}
+	if entry, ok := canon.ByID(bookID); ok {
+		if book.Name == "" {
+			book.Name = entry.Name
+		}
+		book.OSIS = entry.OSIS
+		book.Testament = entry.Testament()
+		book.Genre = entry.Genre()
+		book.Aliases = append([]string{entry.USFM}, entry.Abbreviations...)
+	}
+	return book
+}
@@ -2,14 +2,13 @@ package database
 
 import (
 	// "context"
+	"bible_reading_backend_nkv/config"
 	"bible_reading_backend_nkv/models"
 	"context"
 	"fmt"
-	"os"
+	"log/slog"
 	"time"
 
-	"github.com/joho/godotenv"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
@@ -17,10 +16,30 @@ import (
 // Interface for our DB client
 type DatabaseClient interface {
 	Ready() bool
-	GetAllVerse(ctx context.Context) ([]models.NIV, error)
-	GetAllVerseByChapter(ctx context.Context, bookId int, chapterId int ) ([]models.NIV, error)
-	GetAllBook(ctx context.Context) ([]BookDTO , error)
-	GetAllChapter(ctx context.Context, bookId int) (ChapterMaxDTO , error)
+	Close() error
+	GetAllVerse(ctx context.Context, translationID string) ([]models.Verse, error)
+	GetAllVerseByChapter(ctx context.Context, translationID string, bookId int, chapterId int) ([]models.Verse, error)
+	GetAllBook(ctx context.Context, translationID string) ([]models.Book, error)
+	GetBook(ctx context.Context, translationID string, bookId int) (*models.Book, error)
+	GetAllChapter(ctx context.Context, translationID string, bookId int) (ChapterMaxDTO, error)
+	GetVersesInRange(ctx context.Context, translationID string, bookId, startChapter, startVerse, endChapter, endVerse int) ([]models.Verse, error)
+
+	// Translation registry methods
+	GetTranslations(ctx context.Context) ([]models.Translation, error)
+	GetTranslation(ctx context.Context, id string) (*models.Translation, error)
+	ReplaceTranslation(ctx context.Context, translation *models.Translation, verses []models.Verse) error
+
+	// Explanation cache methods
+	GetExplanation(ctx context.Context, key models.Explanation) (*models.Explanation, error)
+	SaveExplanation(ctx context.Context, explanation *models.Explanation) error
+	DeleteExplanations(ctx context.Context, translationID string, bookID int) (int64, error)
+	DeleteExpiredExplanations(ctx context.Context) (int64, error)
+
+	// LLM quota methods
+	ReserveLLMRequest(ctx context.Context, subject, day string, limit int) (int, error)
+	ReleaseLLMRequest(ctx context.Context, subject, day string) error
+	RecordLLMTokens(ctx context.Context, subject, day string, promptTokens, completionTokens int) error
+	GetLLMUsage(ctx context.Context, subject, day string) (*models.LLMUsage, error)
 	
 	// User management methods
 	CreateUser(ctx context.Context, user *models.User) error
@@ -29,6 +48,40 @@ type DatabaseClient interface {
 	UpdateUser(ctx context.Context, id int, updates map[string]interface{}) error
 	DeleteUser(ctx context.Context, id int) error
 	VerifyPassword(ctx context.Context, email, password string) (*models.User, error)
+
+	// Login throttling methods
+	GetLoginThrottles(ctx context.Context, keys ...string) ([]models.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, key string, threshold int, window, lockout time.Duration) (*models.LoginThrottle, error)
+	ReserveLoginAttempt(ctx context.Context, key string, threshold int, window, lockout time.Duration, delay func(failures int) time.Duration) (*models.LoginThrottle, bool, error)
+	ClearLoginFailures(ctx context.Context, keys ...string) error
+
+	// User administration methods
+	SearchUsers(ctx context.Context, search, role string, limit, offset int) ([]models.User, error)
+	CountUsers(ctx context.Context, search, role string) (int64, error)
+	SetUserDisabled(ctx context.Context, userID int, disabled bool) error
+	ForcePasswordReset(ctx context.Context, reset *models.PasswordReset) error
+	GetUserStats(ctx context.Context, userID int) (*models.UserStats, error)
+
+	// Session methods
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error)
+	RotateSession(ctx context.Context, old *models.Session, next *models.Session) error
+	RevokeSessionFamily(ctx context.Context, familyID string) error
+	RevokeUserSessions(ctx context.Context, userID int) error
+	IsSessionActive(ctx context.Context, familyID string) (bool, error)
+
+	// Password reset methods
+	CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error
+	ResetPassword(ctx context.Context, tokenHash, password string) (int, error)
+
+	// Two-factor authentication methods
+	GetUserTOTP(ctx context.Context, userID int) (*models.UserTOTP, error)
+	SaveUserTOTP(ctx context.Context, t *models.UserTOTP) error
+	EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
 	
 	// Favorite verses methods
 	AddFavoriteVerse(ctx context.Context, userID, bookID, chapter, verse int) error
@@ -55,25 +108,39 @@ type Client struct {
 	DB *gorm.DB
 }
 
-// NewDatabaseClient creates a new MySQL database client
-func NewDatabaseClient() (DatabaseClient, error) {
-	godotenv.Load()
-
-	dsn := os.Getenv("DB_DSN")
-	if dsn == "" {
-		return nil, fmt.Errorf("MYSQL_DSN not set in environment")
+// NewDatabaseClient creates a database client for MySQL or SQLite, depending
+// on the DSN. Queries are logged to logger, see queryLogger, and their
+// durations and spans are recorded, see instrumentation.
+func NewDatabaseClient(cfg config.Database, logger *slog.Logger) (DatabaseClient, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("DB_DSN not configured")
 	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(openDialector(cfg.DSN), &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{},
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
 		},
 		QueryFields: true,
+		// Report unique key violations as gorm.ErrDuplicatedKey
+		TranslateError: true,
+		Logger:         newQueryLogger(logger, cfg.SlowQuery()),
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Use(newInstrumentation()); err != nil {
+		return nil, err
+	}
+	if db.Dialector.Name() == "sqlite" {
+		// SQLite allows one writer at a time, and each connection to
+		// :memory: would otherwise open a database of its own
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
 
 	client := &Client{DB: db}
 	return client, nil
@@ -91,3 +158,12 @@ func (c Client) Ready() bool {
 	}
 	return false
 }
+
+// Close closes the connection pool; in-flight queries are allowed to finish
+func (c Client) Close() error {
+	sqlDB, err := c.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
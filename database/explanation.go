@@ -0,0 +1,54 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// GetExplanation returns the unexpired cache entry matching the key columns
+// of key, or gorm.ErrRecordNotFound
+func (c Client) GetExplanation(ctx context.Context, key models.Explanation) (*models.Explanation, error) {
+	var explanation models.Explanation
+	result := c.DB.WithContext(ctx).
+		Where("translation_id = ? AND book_id = ? AND chapter = ? AND start_verse = ? AND end_verse = ?",
+			key.TranslationID, key.BookID, key.Chapter, key.StartVerse, key.EndVerse).
+		Where("age_bucket = ? AND belief = ? AND prompt_version = ? AND model = ?",
+			key.AgeBucket, key.Belief, key.PromptVersion, key.Model).
+		Where("expires_at > ?", time.Now().UTC()).
+		First(&explanation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &explanation, nil
+}
+
+// SaveExplanation stores an explanation, replacing any entry with the same key
+func (c Client) SaveExplanation(ctx context.Context, explanation *models.Explanation) error {
+	upsert := clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"text", "finish_reason", "prompt_tokens", "completion_tokens", "created_at", "expires_at"}),
+	}
+	return c.DB.WithContext(ctx).Clauses(upsert).Create(explanation).Error
+}
+
+// DeleteExplanations invalidates cached explanations. An empty translationID
+// matches every translation and a bookID of 0 every book.
+func (c Client) DeleteExplanations(ctx context.Context, translationID string, bookID int) (int64, error) {
+	query := c.DB.WithContext(ctx).Where("1 = 1")
+	if translationID != "" {
+		query = query.Where("translation_id = ?", translationID)
+	}
+	if bookID != 0 {
+		query = query.Where("book_id = ?", bookID)
+	}
+	result := query.Delete(&models.Explanation{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpiredExplanations removes entries whose TTL has passed
+func (c Client) DeleteExpiredExplanations(ctx context.Context) (int64, error) {
+	result := c.DB.WithContext(ctx).Where("expires_at <= ?", time.Now().UTC()).Delete(&models.Explanation{})
+	return result.RowsAffected, result.Error
+}
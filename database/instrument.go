@@ -0,0 +1,101 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/metrics"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	// queryStartKey and querySpanKey store the start time and the span of a
+	// statement in its gorm.DB instance
+	queryStartKey = "instrument:query_start"
+	querySpanKey  = "instrument:query_span"
+)
+
+// instrumentation is a GORM plugin that records the duration of every
+// statement in metrics.DBQueryDuration and traces it as a child of the span
+// in the statement's context
+type instrumentation struct {
+	tracer trace.Tracer
+}
+
+func newInstrumentation() instrumentation {
+	return instrumentation{tracer: otel.Tracer("bible_reading_backend_nkv/database")}
+}
+
+func (instrumentation) Name() string {
+	return "instrumentation"
+}
+
+func (i instrumentation) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("instrument:before_create", i.before("create")),
+		callbacks.Create().After("gorm:create").Register("instrument:after_create", i.after("create")),
+		callbacks.Query().Before("gorm:query").Register("instrument:before_query", i.before("query")),
+		callbacks.Query().After("gorm:query").Register("instrument:after_query", i.after("query")),
+		callbacks.Update().Before("gorm:update").Register("instrument:before_update", i.before("update")),
+		callbacks.Update().After("gorm:update").Register("instrument:after_update", i.after("update")),
+		callbacks.Delete().Before("gorm:delete").Register("instrument:before_delete", i.before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("instrument:after_delete", i.after("delete")),
+		callbacks.Row().Before("gorm:row").Register("instrument:before_row", i.before("row")),
+		callbacks.Row().After("gorm:row").Register("instrument:after_row", i.after("row")),
+		callbacks.Raw().Before("gorm:raw").Register("instrument:before_raw", i.before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("instrument:after_raw", i.after("raw")),
+	)
+}
+
+func (i instrumentation) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		name := operation
+		if table := db.Statement.Table; table != "" {
+			name += " " + table
+		}
+		_, span := i.tracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", db.Dialector.Name()),
+				attribute.String("db.operation.name", operation),
+				attribute.String("db.collection.name", db.Statement.Table),
+			),
+		)
+		db.InstanceSet(querySpanKey, span)
+		db.InstanceSet(queryStartKey, time.Now())
+	}
+}
+
+func (i instrumentation) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		// As in queryLogger, missing rows and duplicate keys are not failures
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) && !errors.Is(db.Error, gorm.ErrDuplicatedKey)
+
+		if value, ok := db.InstanceGet(querySpanKey); ok {
+			span := value.(trace.Span)
+			// The SQL has placeholders, not the values bound to them
+			span.SetAttributes(
+				attribute.String("db.query.text", db.Statement.SQL.String()),
+				attribute.Int64("db.rows_affected", db.RowsAffected),
+			)
+			if failed {
+				span.RecordError(db.Error)
+				span.SetStatus(codes.Error, db.Error.Error())
+			}
+			span.End()
+		}
+
+		if value, ok := db.InstanceGet(queryStartKey); ok {
+			status := "ok"
+			if failed {
+				status = "error"
+			}
+			metrics.DBQueryDuration.WithLabelValues(operation, db.Statement.Table, status).Observe(time.Since(value.(time.Time)).Seconds())
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/config"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/metrics"
+	"bible_reading_backend_nkv/models"
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	clientmodel "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestQueriesAreInstrumented(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db, err := NewDatabaseClient(config.Database{DSN: "sqlite::memory:", SlowQueryMilliseconds: 200}, logging.Discard())
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	before := queryCount(t, "query", "verses", "error")
+	_, err = db.GetAllVerse(ctx, models.DefaultTranslationID)
+	require.Error(t, err, "the schema has not been migrated")
+	parent.End()
+
+	assert.Equal(t, before+1, queryCount(t, "query", "verses", "error"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	query := spans[0]
+	assert.Equal(t, "query verses", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, codes.Error, query.Status().Code)
+	assert.Contains(t, query.Attributes(), attribute.String("db.system.name", "sqlite"))
+	assert.Contains(t, query.Attributes(), attribute.String("db.collection.name", "verses"))
+}
+
+// queryCount returns the number of queries recorded with the given labels
+func queryCount(t *testing.T, labels ...string) uint64 {
+	var m clientmodel.Metric
+	require.NoError(t, metrics.DBQueryDuration.WithLabelValues(labels...).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuotaExceeded is returned by ReserveLLMRequest when the caller has used
+// their quota for the day
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// ReserveLLMRequest counts one request for subject on day if fewer than
+// limit have been made, and returns the number made including this one. The
+// check and increment are a single UPDATE, so concurrent requests cannot
+// overshoot the limit.
+func (c Client) ReserveLLMRequest(ctx context.Context, subject, day string, limit int) (int, error) {
+	db := c.DB.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.LLMUsage{Subject: subject, Day: day}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Model(&models.LLMUsage{}).
+		Where("subject = ? AND day = ? AND requests < ?", subject, day, limit).
+		Update("requests", gorm.Expr("requests + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return limit, ErrQuotaExceeded
+	}
+
+	usage, err := c.GetLLMUsage(ctx, subject, day)
+	if err != nil {
+		return 0, err
+	}
+	return usage.Requests, nil
+}
+
+// ReleaseLLMRequest gives back a request reserved for a call that failed
+func (c Client) ReleaseLLMRequest(ctx context.Context, subject, day string) error {
+	return c.DB.WithContext(ctx).Model(&models.LLMUsage{}).
+		Where("subject = ? AND day = ? AND requests > 0", subject, day).
+		Update("requests", gorm.Expr("requests - 1")).Error
+}
+
+// RecordLLMTokens adds the tokens used by a request to subject's usage on day
+func (c Client) RecordLLMTokens(ctx context.Context, subject, day string, promptTokens, completionTokens int) error {
+	return c.DB.WithContext(ctx).Model(&models.LLMUsage{}).
+		Where("subject = ? AND day = ?", subject, day).
+		Updates(map[string]interface{}{
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", promptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", completionTokens),
+		}).Error
+}
+
+// GetLLMUsage returns subject's usage on day, or a zero usage if there is none
+func (c Client) GetLLMUsage(ctx context.Context, subject, day string) (*models.LLMUsage, error) {
+	usage := models.LLMUsage{Subject: subject, Day: day}
+	result := c.DB.WithContext(ctx).Where("subject = ? AND day = ?", subject, day).Limit(1).Find(&usage)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &usage, nil
+}
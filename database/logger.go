@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// queryLogger sends GORM's logs to slog. Queries are logged with the
+// context they ran with, so they carry the request ID of the request that
+// made them. Failed queries are logged as errors, slow ones as warnings and
+// the rest at debug level.
+type queryLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+}
+
+func newQueryLogger(logger *slog.Logger, slowThreshold time.Duration) gormlogger.Interface {
+	return queryLogger{logger: logger.With("component", "database"), slowThreshold: slowThreshold}
+}
+
+// LogMode is ignored; the slog handler's level decides what is logged
+func (l queryLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l queryLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l queryLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l queryLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	level, msg := slog.LevelDebug, "query"
+	switch {
+	// Missing rows and duplicate keys are expected outcomes that callers
+	// handle, such as registering an email that is taken
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, gorm.ErrDuplicatedKey):
+		level, msg = slog.LevelError, "query failed"
+	case elapsed > l.slowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/config"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueriesAreLoggedWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.Config{Format: logging.FormatJSON, Level: slog.LevelDebug})
+	db, err := NewDatabaseClient(config.Database{DSN: "sqlite::memory:", SlowQueryMilliseconds: 200}, logger)
+	require.NoError(t, err)
+	defer db.Close()
+
+	buf.Reset()
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	_, err = db.GetAllVerse(ctx, models.DefaultTranslationID)
+	require.Error(t, err, "the schema has not been migrated")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.Split(buf.Bytes(), []byte("\n"))[0], &line))
+	assert.Equal(t, "ERROR", line["level"])
+	assert.Equal(t, "query failed", line["msg"])
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "database", line["component"])
+	assert.Contains(t, line["sql"], "verses")
+	assert.Contains(t, line, "error")
+}
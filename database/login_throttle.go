@@ -0,0 +1,131 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetLoginThrottles returns the failure counters that exist for keys
+func (c Client) GetLoginThrottles(ctx context.Context, keys ...string) ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	result := c.DB.WithContext(ctx).Where("throttle_key IN ?", keys).Find(&throttles)
+	return throttles, result.Error
+}
+
+// RecordLoginFailure counts a failed login for key. A counter whose last
+// failure is older than window starts again from zero; reaching threshold
+// locks the key for lockout. The count is a single upsert, so concurrent
+// failures for a new key are neither lost nor rejected as duplicates.
+func (c Client) RecordLoginFailure(ctx context.Context, key string, threshold int, window, lockout time.Duration) (*models.LoginThrottle, error) {
+	now := time.Now().UTC()
+	first := firstLoginFailure(key, now, threshold, lockout)
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "throttle_key"}},
+		DoUpdates: loginFailureSet(now, threshold, window, lockout),
+	}
+
+	var throttle models.LoginThrottle
+	err := c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(upsert).Create(&first).Error; err != nil {
+			return err
+		}
+		return tx.Where("throttle_key = ?", key).First(&throttle).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// ReserveLoginAttempt counts an attempt for key as failed before it is
+// verified, so that concurrent attempts cannot all get past the same counter.
+// While key is locked, or its last failure is less than delay(failures) ago,
+// nothing is counted and the counter is returned as it stands with false.
+// Otherwise the attempt is counted as in RecordLoginFailure and the new
+// counter is returned with true. Each attempt only counts if the failures it
+// checked are still there, and checks again if another one came first.
+func (c Client) ReserveLoginAttempt(ctx context.Context, key string, threshold int, window, lockout time.Duration, delay func(failures int) time.Duration) (*models.LoginThrottle, bool, error) {
+	db := c.DB.WithContext(ctx)
+	for {
+		now := time.Now().UTC()
+		var throttle models.LoginThrottle
+		err := db.Where("throttle_key = ?", key).First(&throttle).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			first := firstLoginFailure(key, now, threshold, lockout)
+			result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&first)
+			if result.Error != nil {
+				return nil, false, result.Error
+			}
+			if result.RowsAffected == 1 {
+				return &first, true, nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if loginBlocked(throttle, now, delay) {
+			return &throttle, false, nil
+		}
+
+		result := db.Model(&models.LoginThrottle{}).
+			Where("throttle_key = ? AND failures = ?", key, throttle.Failures).
+			Clauses(loginFailureSet(now, threshold, window, lockout)).
+			Updates(map[string]interface{}{})
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			err := db.Where("throttle_key = ?", key).First(&throttle).Error
+			if err != nil {
+				return nil, false, err
+			}
+			return &throttle, true, nil
+		}
+	}
+}
+
+// loginBlocked reports whether t is locked at now or its last failure is
+// less than delay(failures) ago
+func loginBlocked(t models.LoginThrottle, now time.Time, delay func(failures int) time.Duration) bool {
+	if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+		return true
+	}
+	return now.Before(t.LastFailureAt.Add(delay(t.Failures)))
+}
+
+// firstLoginFailure is the counter for a key's first failure
+func firstLoginFailure(key string, now time.Time, threshold int, lockout time.Duration) models.LoginThrottle {
+	first := models.LoginThrottle{Key: key, Failures: 1, LastFailureAt: now}
+	if first.Failures >= threshold {
+		until := now.Add(lockout)
+		first.LockedUntil = &until
+	}
+	return first
+}
+
+// loginFailureSet counts one more failure for an existing counter
+func loginFailureSet(now time.Time, threshold int, window, lockout time.Duration) clause.Set {
+	cutoff := now.Add(-window)
+	until := now.Add(lockout)
+	// MySQL applies the assignments in order, so each one only reads columns
+	// that are assigned after it
+	failures := "CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END"
+	return clause.Set{
+		{Column: clause.Column{Name: "locked_until"}, Value: gorm.Expr(
+			"CASE WHEN "+failures+" >= ? THEN ? WHEN last_failure_at < ? THEN NULL ELSE locked_until END",
+			cutoff, threshold, until, cutoff)},
+		{Column: clause.Column{Name: "failures"}, Value: gorm.Expr(failures, cutoff)},
+		{Column: clause.Column{Name: "last_failure_at"}, Value: now},
+	}
+}
+
+// ClearLoginFailures forgets the failures and lockouts of keys
+func (c Client) ClearLoginFailures(ctx context.Context, keys ...string) error {
+	return c.DB.WithContext(ctx).Where("throttle_key IN ?", keys).Delete(&models.LoginThrottle{}).Error
+}
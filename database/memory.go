@@ -0,0 +1,535 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryClient is a DatabaseClient that keeps everything in memory. It
+// behaves like Client, including the errors it returns, so handlers can be
+// tested without a database server. Faults can be injected with Fail.
+type MemoryClient struct {
+	mu     sync.Mutex
+	nextID int
+	fault  func(method string) error
+
+	translations map[string]models.Translation
+	verses       []models.Verse
+	books        map[string][]models.Book
+	explanations []models.Explanation
+	llmUsage     map[string]*models.LLMUsage
+
+	users      map[int]*models.User
+	favorites  []models.UserFavoriteVerse
+	highlights []models.UserHighlightedVerse
+	lastRead   map[int]models.UserLastRead
+
+	sessions      []*models.Session
+	resets        []*models.PasswordReset
+	totp          map[int]*models.UserTOTP
+	recoveryCodes []*models.RecoveryCode
+	throttles     map[string]models.LoginThrottle
+}
+
+var _ DatabaseClient = (*MemoryClient)(nil)
+
+// NewMemoryClient returns an empty in-memory database
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		translations: map[string]models.Translation{},
+		books:        map[string][]models.Book{},
+		llmUsage:     map[string]*models.LLMUsage{},
+		users:        map[int]*models.User{},
+		lastRead:     map[int]models.UserLastRead{},
+		totp:         map[int]*models.UserTOTP{},
+		throttles:    map[string]models.LoginThrottle{},
+	}
+}
+
+// SetFault installs fn to be called with the method name, e.g.
+// "GetAllVerse", at the start of every call. A non-nil error is returned by
+// the call instead of running it; for "Ready", Ready reports false. A nil fn
+// clears it.
+func (m *MemoryClient) SetFault(fn func(method string) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fault = fn
+}
+
+// Fail makes the named methods, or every method if none are named, return err
+func (m *MemoryClient) Fail(err error, methods ...string) {
+	m.SetFault(func(method string) error {
+		if len(methods) == 0 {
+			return err
+		}
+		for _, name := range methods {
+			if name == method {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// check returns the injected fault for method, if any. m.mu must be held.
+func (m *MemoryClient) check(method string) error {
+	if m.fault == nil {
+		return nil
+	}
+	return m.fault(method)
+}
+
+func (m *MemoryClient) newID() int {
+	m.nextID++
+	return m.nextID
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// page applies a limit and offset like SQL does; a negative limit means none
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func (m *MemoryClient) Ready() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.check("Ready") == nil
+}
+
+func (m *MemoryClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.check("Close")
+}
+
+// Bible text
+
+func (m *MemoryClient) versesWhere(match func(v models.Verse) bool) []models.Verse {
+	verses := []models.Verse{}
+	for _, v := range m.verses {
+		if match(v) {
+			verses = append(verses, v)
+		}
+	}
+	return verses
+}
+
+func (m *MemoryClient) GetAllVerse(ctx context.Context, translationID string) ([]models.Verse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetAllVerse"); err != nil {
+		return nil, err
+	}
+	return m.versesWhere(func(v models.Verse) bool {
+		return v.TranslationID == translationID
+	}), nil
+}
+
+func (m *MemoryClient) GetAllVerseByChapter(ctx context.Context, translationID string, bookId int, chapterId int) ([]models.Verse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetAllVerseByChapter"); err != nil {
+		return nil, err
+	}
+	return m.versesWhere(func(v models.Verse) bool {
+		return v.TranslationID == translationID && v.BookID == bookId && v.Chapter == chapterId
+	}), nil
+}
+
+func (m *MemoryClient) GetVersesInRange(ctx context.Context, translationID string, bookId, startChapter, startVerse, endChapter, endVerse int) ([]models.Verse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetVersesInRange"); err != nil {
+		return nil, err
+	}
+	return m.versesWhere(func(v models.Verse) bool {
+		if v.TranslationID != translationID || v.BookID != bookId {
+			return false
+		}
+		if v.Chapter < startChapter || (v.Chapter == startChapter && v.Verse < startVerse) {
+			return false
+		}
+		if endVerse == 0 {
+			return v.Chapter <= endChapter
+		}
+		return v.Chapter < endChapter || (v.Chapter == endChapter && v.Verse <= endVerse)
+	}), nil
+}
+
+func (m *MemoryClient) GetAllBook(ctx context.Context, translationID string) ([]models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetAllBook"); err != nil {
+		return nil, err
+	}
+	return append([]models.Book{}, m.books[translationID]...), nil
+}
+
+func (m *MemoryClient) GetBook(ctx context.Context, translationID string, bookId int) (*models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetBook"); err != nil {
+		return nil, err
+	}
+	for _, book := range m.books[translationID] {
+		if book.ID == bookId {
+			return &book, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MemoryClient) GetAllChapter(ctx context.Context, translationID string, bookId int) (ChapterMaxDTO, error) {
+	book, err := m.GetBook(ctx, translationID, bookId)
+	if err != nil {
+		return ChapterMaxDTO{}, err
+	}
+	return ChapterMaxDTO{MaxChapter: int64(book.Chapters)}, nil
+}
+
+func (m *MemoryClient) GetTranslations(ctx context.Context) ([]models.Translation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetTranslations"); err != nil {
+		return nil, err
+	}
+	translations := make([]models.Translation, 0, len(m.translations))
+	for _, t := range m.translations {
+		translations = append(translations, t)
+	}
+	sort.Slice(translations, func(i, j int) bool { return translations[i].ID < translations[j].ID })
+	return translations, nil
+}
+
+func (m *MemoryClient) GetTranslation(ctx context.Context, id string) (*models.Translation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetTranslation"); err != nil {
+		return nil, err
+	}
+	t, ok := m.translations[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &t, nil
+}
+
+// SeedTranslations registers the default translations and builds missing
+// book catalogues, like Client.SeedTranslations
+func (m *MemoryClient) SeedTranslations(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("SeedTranslations"); err != nil {
+		return err
+	}
+	for _, translation := range defaultTranslations {
+		if _, ok := m.translations[translation.ID]; !ok {
+			translation.CreatedAt, translation.UpdatedAt = utcNow(), utcNow()
+			m.translations[translation.ID] = translation
+		}
+	}
+	for id := range m.translations {
+		if len(m.books[id]) == 0 {
+			m.refreshBooks(id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryClient) ReplaceTranslation(ctx context.Context, translation *models.Translation, verses []models.Verse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ReplaceTranslation"); err != nil {
+		return err
+	}
+
+	translation.UpdatedAt = utcNow()
+	if existing, ok := m.translations[translation.ID]; ok {
+		translation.CreatedAt = existing.CreatedAt
+	} else if translation.CreatedAt.IsZero() {
+		translation.CreatedAt = translation.UpdatedAt
+	}
+	m.translations[translation.ID] = *translation
+
+	kept := m.verses[:0]
+	for _, v := range m.verses {
+		if v.TranslationID != translation.ID {
+			kept = append(kept, v)
+		}
+	}
+	m.verses = append(kept, verses...)
+	sort.SliceStable(m.verses, func(i, j int) bool {
+		a, b := m.verses[i], m.verses[j]
+		if a.TranslationID != b.TranslationID {
+			return a.TranslationID < b.TranslationID
+		}
+		if a.BookID != b.BookID {
+			return a.BookID < b.BookID
+		}
+		if a.Chapter != b.Chapter {
+			return a.Chapter < b.Chapter
+		}
+		return a.Verse < b.Verse
+	})
+
+	m.deleteExplanations(translation.ID, 0)
+	m.refreshBooks(translation.ID)
+	return nil
+}
+
+// refreshBooks rebuilds a translation's catalogue. m.mu must be held.
+func (m *MemoryClient) refreshBooks(translationID string) {
+	var counts []chapterCount
+	for _, v := range m.verses {
+		if v.TranslationID != translationID {
+			continue
+		}
+		if n := len(counts); n > 0 && counts[n-1].BookID == v.BookID && counts[n-1].Chapter == v.Chapter {
+			counts[n-1].Verses++
+			continue
+		}
+		counts = append(counts, chapterCount{BookID: v.BookID, Book: v.Book, Chapter: v.Chapter, Verses: 1})
+	}
+	m.books[translationID] = catalogue(translationID, counts)
+}
+
+// Explanation cache
+
+func sameExplanationKey(a, b models.Explanation) bool {
+	return a.TranslationID == b.TranslationID && a.BookID == b.BookID && a.Chapter == b.Chapter &&
+		a.StartVerse == b.StartVerse && a.EndVerse == b.EndVerse && a.AgeBucket == b.AgeBucket &&
+		a.Belief == b.Belief && a.PromptVersion == b.PromptVersion && a.Model == b.Model
+}
+
+func (m *MemoryClient) GetExplanation(ctx context.Context, key models.Explanation) (*models.Explanation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetExplanation"); err != nil {
+		return nil, err
+	}
+	for _, e := range m.explanations {
+		if sameExplanationKey(e, key) && e.ExpiresAt.After(utcNow()) {
+			return &e, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MemoryClient) SaveExplanation(ctx context.Context, explanation *models.Explanation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("SaveExplanation"); err != nil {
+		return err
+	}
+	if explanation.CreatedAt.IsZero() {
+		explanation.CreatedAt = utcNow()
+	}
+	for i, e := range m.explanations {
+		if sameExplanationKey(e, *explanation) {
+			explanation.ID = e.ID
+			m.explanations[i] = *explanation
+			return nil
+		}
+	}
+	explanation.ID = m.newID()
+	m.explanations = append(m.explanations, *explanation)
+	return nil
+}
+
+func (m *MemoryClient) DeleteExplanations(ctx context.Context, translationID string, bookID int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("DeleteExplanations"); err != nil {
+		return 0, err
+	}
+	return m.deleteExplanations(translationID, bookID), nil
+}
+
+// deleteExplanations drops matching cache entries. m.mu must be held.
+func (m *MemoryClient) deleteExplanations(translationID string, bookID int) int64 {
+	var deleted int64
+	kept := m.explanations[:0]
+	for _, e := range m.explanations {
+		if (translationID == "" || e.TranslationID == translationID) && (bookID == 0 || e.BookID == bookID) {
+			deleted++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.explanations = kept
+	return deleted
+}
+
+func (m *MemoryClient) DeleteExpiredExplanations(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("DeleteExpiredExplanations"); err != nil {
+		return 0, err
+	}
+	var deleted int64
+	kept := m.explanations[:0]
+	for _, e := range m.explanations {
+		if !e.ExpiresAt.After(utcNow()) {
+			deleted++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.explanations = kept
+	return deleted, nil
+}
+
+// LLM quota
+
+func (m *MemoryClient) usage(subject, day string) *models.LLMUsage {
+	key := subject + "\x00" + day
+	usage, ok := m.llmUsage[key]
+	if !ok {
+		usage = &models.LLMUsage{ID: m.newID(), Subject: subject, Day: day}
+		m.llmUsage[key] = usage
+	}
+	return usage
+}
+
+func (m *MemoryClient) ReserveLLMRequest(ctx context.Context, subject, day string, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ReserveLLMRequest"); err != nil {
+		return 0, err
+	}
+	usage := m.usage(subject, day)
+	if usage.Requests >= limit {
+		return limit, ErrQuotaExceeded
+	}
+	usage.Requests++
+	usage.UpdatedAt = utcNow()
+	return usage.Requests, nil
+}
+
+func (m *MemoryClient) ReleaseLLMRequest(ctx context.Context, subject, day string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ReleaseLLMRequest"); err != nil {
+		return err
+	}
+	if usage := m.usage(subject, day); usage.Requests > 0 {
+		usage.Requests--
+		usage.UpdatedAt = utcNow()
+	}
+	return nil
+}
+
+func (m *MemoryClient) RecordLLMTokens(ctx context.Context, subject, day string, promptTokens, completionTokens int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RecordLLMTokens"); err != nil {
+		return err
+	}
+	usage := m.usage(subject, day)
+	usage.PromptTokens += promptTokens
+	usage.CompletionTokens += completionTokens
+	usage.UpdatedAt = utcNow()
+	return nil
+}
+
+func (m *MemoryClient) GetLLMUsage(ctx context.Context, subject, day string) (*models.LLMUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetLLMUsage"); err != nil {
+		return nil, err
+	}
+	if usage, ok := m.llmUsage[subject+"\x00"+day]; ok {
+		copied := *usage
+		return &copied, nil
+	}
+	return &models.LLMUsage{Subject: subject, Day: day}, nil
+}
+
+// Login throttling
+
+func (m *MemoryClient) GetLoginThrottles(ctx context.Context, keys ...string) ([]models.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetLoginThrottles"); err != nil {
+		return nil, err
+	}
+	throttles := []models.LoginThrottle{}
+	for _, key := range keys {
+		if t, ok := m.throttles[key]; ok {
+			throttles = append(throttles, t)
+		}
+	}
+	return throttles, nil
+}
+
+func (m *MemoryClient) RecordLoginFailure(ctx context.Context, key string, threshold int, window, lockout time.Duration) (*models.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RecordLoginFailure"); err != nil {
+		return nil, err
+	}
+	throttle := m.countLoginFailure(key, threshold, window, lockout)
+	return &throttle, nil
+}
+
+// countLoginFailure counts a failure like Client.RecordLoginFailure. m.mu must
+// be held.
+func (m *MemoryClient) countLoginFailure(key string, threshold int, window, lockout time.Duration) models.LoginThrottle {
+	t := utcNow()
+	throttle, ok := m.throttles[key]
+	if !ok {
+		throttle = models.LoginThrottle{Key: key}
+	}
+	if t.Sub(throttle.LastFailureAt) > window {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = t
+	if throttle.Failures >= threshold {
+		until := t.Add(lockout)
+		throttle.LockedUntil = &until
+	}
+	m.throttles[key] = throttle
+	return throttle
+}
+
+func (m *MemoryClient) ReserveLoginAttempt(ctx context.Context, key string, threshold int, window, lockout time.Duration, delay func(failures int) time.Duration) (*models.LoginThrottle, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ReserveLoginAttempt"); err != nil {
+		return nil, false, err
+	}
+	if throttle, ok := m.throttles[key]; ok && loginBlocked(throttle, utcNow(), delay) {
+		return &throttle, false, nil
+	}
+	throttle := m.countLoginFailure(key, threshold, window, lockout)
+	return &throttle, true, nil
+}
+
+func (m *MemoryClient) ClearLoginFailures(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ClearLoginFailures"); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(m.throttles, key)
+	}
+	return nil
+}
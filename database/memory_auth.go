@@ -0,0 +1,299 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Sessions
+
+// createSession stores a copy of session with a new ID. m.mu must be held.
+func (m *MemoryClient) createSession(session *models.Session) error {
+	for _, s := range m.sessions {
+		if s.TokenHash == session.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	session.ID = m.newID()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = utcNow()
+	}
+	stored := *session
+	m.sessions = append(m.sessions, &stored)
+	return nil
+}
+
+func (m *MemoryClient) CreateSession(ctx context.Context, session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("CreateSession"); err != nil {
+		return err
+	}
+	return m.createSession(session)
+}
+
+func (m *MemoryClient) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetSessionByTokenHash"); err != nil {
+		return nil, err
+	}
+	for _, s := range m.sessions {
+		if s.TokenHash == tokenHash {
+			copied := *s
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MemoryClient) RotateSession(ctx context.Context, old *models.Session, next *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RotateSession"); err != nil {
+		return err
+	}
+	for _, s := range m.sessions {
+		if s.ID == old.ID && s.RevokedAt == nil {
+			if err := m.createSession(next); err != nil {
+				return err
+			}
+			t := utcNow()
+			s.RevokedAt = &t
+			return nil
+		}
+	}
+	return ErrSessionRevoked
+}
+
+// revokeSessions revokes the unrevoked sessions that match. m.mu must be held.
+func (m *MemoryClient) revokeSessions(match func(s *models.Session) bool) {
+	t := utcNow()
+	for _, s := range m.sessions {
+		if s.RevokedAt == nil && match(s) {
+			s.RevokedAt = &t
+		}
+	}
+}
+
+func (m *MemoryClient) revokeUserSessions(userID int) {
+	m.revokeSessions(func(s *models.Session) bool { return s.UserID == userID })
+}
+
+func (m *MemoryClient) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RevokeSessionFamily"); err != nil {
+		return err
+	}
+	m.revokeSessions(func(s *models.Session) bool { return s.FamilyID == familyID })
+	return nil
+}
+
+func (m *MemoryClient) RevokeUserSessions(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RevokeUserSessions"); err != nil {
+		return err
+	}
+	m.revokeUserSessions(userID)
+	return nil
+}
+
+func (m *MemoryClient) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("IsSessionActive"); err != nil {
+		return false, err
+	}
+	for _, s := range m.sessions {
+		if s.FamilyID == familyID && s.RevokedAt == nil && s.ExpiresAt.After(utcNow()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Password resets
+
+// usePasswordResets marks the user's unused reset links as used at t. m.mu
+// must be held.
+func (m *MemoryClient) usePasswordResets(userID int, t time.Time) {
+	for _, r := range m.resets {
+		if r.UserID == userID && r.UsedAt == nil {
+			r.UsedAt = &t
+		}
+	}
+}
+
+// createPasswordReset stores a copy of reset with a new ID. m.mu must be held.
+func (m *MemoryClient) createPasswordReset(reset *models.PasswordReset) error {
+	for _, r := range m.resets {
+		if r.TokenHash == reset.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	reset.ID = m.newID()
+	if reset.CreatedAt.IsZero() {
+		reset.CreatedAt = utcNow()
+	}
+	stored := *reset
+	m.resets = append(m.resets, &stored)
+	return nil
+}
+
+func (m *MemoryClient) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("CreatePasswordReset"); err != nil {
+		return err
+	}
+	return m.createPasswordReset(reset)
+}
+
+func (m *MemoryClient) ResetPassword(ctx context.Context, tokenHash, password string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ResetPassword"); err != nil {
+		return 0, err
+	}
+
+	t := utcNow()
+	var reset *models.PasswordReset
+	for _, r := range m.resets {
+		if r.TokenHash == tokenHash && r.UsedAt == nil && r.ExpiresAt.After(t) {
+			reset = r
+		}
+	}
+	if reset == nil {
+		return 0, ErrInvalidResetToken
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), memoryBcryptCost)
+	if err != nil {
+		return 0, err
+	}
+
+	m.usePasswordResets(reset.UserID, t)
+	if user, ok := m.users[reset.UserID]; ok {
+		user.Password = string(hashedPassword)
+	}
+	m.revokeUserSessions(reset.UserID)
+	return reset.UserID, nil
+}
+
+// Two-factor authentication
+
+func (m *MemoryClient) GetUserTOTP(ctx context.Context, userID int) (*models.UserTOTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetUserTOTP"); err != nil {
+		return nil, err
+	}
+	t, ok := m.totp[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (m *MemoryClient) SaveUserTOTP(ctx context.Context, t *models.UserTOTP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("SaveUserTOTP"); err != nil {
+		return err
+	}
+	t.UpdatedAt = utcNow()
+	if existing, ok := m.totp[t.UserID]; ok {
+		t.CreatedAt = existing.CreatedAt
+	} else if t.CreatedAt.IsZero() {
+		t.CreatedAt = t.UpdatedAt
+	}
+	stored := *t
+	m.totp[t.UserID] = &stored
+	return nil
+}
+
+func (m *MemoryClient) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("EnableTOTP"); err != nil {
+		return err
+	}
+	if t, ok := m.totp[userID]; ok {
+		enabledAt := utcNow()
+		t.EnabledAt = &enabledAt
+		t.LastUsedStep = step
+		t.UpdatedAt = enabledAt
+	}
+	m.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (m *MemoryClient) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ReplaceRecoveryCodes"); err != nil {
+		return err
+	}
+	m.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+// replaceRecoveryCodes swaps a user's recovery codes. m.mu must be held.
+func (m *MemoryClient) replaceRecoveryCodes(userID int, codeHashes []string) {
+	m.recoveryCodes = filter(m.recoveryCodes, func(c *models.RecoveryCode) bool { return c.UserID != userID })
+	for _, hash := range codeHashes {
+		m.recoveryCodes = append(m.recoveryCodes, &models.RecoveryCode{
+			ID:        m.newID(),
+			UserID:    userID,
+			CodeHash:  hash,
+			CreatedAt: utcNow(),
+		})
+	}
+}
+
+func (m *MemoryClient) DisableTOTP(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("DisableTOTP"); err != nil {
+		return err
+	}
+	m.replaceRecoveryCodes(userID, nil)
+	delete(m.totp, userID)
+	return nil
+}
+
+func (m *MemoryClient) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("UseTOTPStep"); err != nil {
+		return err
+	}
+	t, ok := m.totp[userID]
+	if !ok || t.LastUsedStep >= step {
+		return ErrCodeReused
+	}
+	t.LastUsedStep = step
+	return nil
+}
+
+func (m *MemoryClient) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("UseRecoveryCode"); err != nil {
+		return err
+	}
+	for _, c := range m.recoveryCodes {
+		if c.UserID == userID && c.CodeHash == codeHash && c.UsedAt == nil {
+			usedAt := utcNow()
+			c.UsedAt = &usedAt
+			return nil
+		}
+	}
+	return ErrInvalidRecoveryCode
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMemoryClientFaults(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryClient()
+	assert.True(t, db.Ready())
+
+	down := errors.New("down")
+	db.Fail(down, "GetAllBook")
+	_, err := db.GetAllBook(ctx, models.DefaultTranslationID)
+	assert.ErrorIs(t, err, down)
+	_, err = db.GetAllVerse(ctx, models.DefaultTranslationID)
+	assert.NoError(t, err, "only the named method fails")
+	assert.True(t, db.Ready())
+
+	db.Fail(down)
+	assert.False(t, db.Ready())
+	db.SetFault(nil)
+	assert.True(t, db.Ready())
+	_, err = db.GetAllBook(ctx, models.DefaultTranslationID)
+	assert.NoError(t, err)
+}
+
+func TestMemoryClientUsers(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryClient()
+
+	user := &models.User{Email: "Ann@Example.com", Password: "secret123", FirstName: "Ann"}
+	require.NoError(t, db.CreateUser(ctx, user))
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, models.RoleUser, user.Role)
+	assert.ErrorIs(t, db.CreateUser(ctx, &models.User{Email: "ann@example.com", Password: "x"}), gorm.ErrDuplicatedKey)
+
+	found, err := db.VerifyPassword(ctx, "ann@example.com", "secret123")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	_, err = db.VerifyPassword(ctx, "ann@example.com", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	require.NoError(t, db.UpdateUser(ctx, user.ID, map[string]interface{}{"first_name": "Anne", "email_verified_at": time.Now()}))
+	found, err = db.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Anne", found.FirstName)
+	assert.NotNil(t, found.EmailVerifiedAt)
+	assert.Error(t, db.UpdateUser(ctx, user.ID, map[string]interface{}{"nickname": "A"}))
+
+	users, err := db.SearchUsers(ctx, "ANNE", "", 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	require.NoError(t, db.AddFavoriteVerse(ctx, user.ID, 1, 1, 1))
+	assert.ErrorIs(t, db.AddFavoriteVerse(ctx, user.ID, 1, 1, 1), ErrAlreadyFavorite)
+	require.NoError(t, db.DeleteUser(ctx, user.ID))
+	_, err = db.GetUserByEmail(ctx, "ann@example.com")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	count, err := db.GetFavoriteVersesCount(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count, "favorites are deleted with the user")
+}
+
+func TestMemoryClientHighlights(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryClient()
+
+	assert.ErrorIs(t, db.UpdateHighlightedVerse(ctx, 1, 1, 1, 1, "note", ""), ErrHighlightNotFound)
+	require.NoError(t, db.AddHighlightedVerse(ctx, 1, 1, 1, 1, "", ""))
+	assert.ErrorIs(t, db.AddHighlightedVerse(ctx, 1, 1, 1, 1, "", "blue"), ErrAlreadyHighlighted)
+	require.NoError(t, db.UpdateHighlightedVerse(ctx, 1, 1, 1, 1, "note", ""))
+
+	highlights, err := db.GetHighlightedVerses(ctx, 1, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, highlights, 1)
+	assert.Equal(t, "yellow", highlights[0].Color)
+	assert.Equal(t, "note", highlights[0].Note)
+}
+
+func TestMemoryClientSessions(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryClient()
+
+	old := &models.Session{UserID: 1, FamilyID: "f", TokenHash: "a", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, db.CreateSession(ctx, old))
+	require.NoError(t, db.RotateSession(ctx, old, &models.Session{UserID: 1, FamilyID: "f", TokenHash: "b", ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.ErrorIs(t, db.RotateSession(ctx, old, &models.Session{UserID: 1, FamilyID: "f", TokenHash: "c"}), ErrSessionRevoked)
+
+	active, err := db.IsSessionActive(ctx, "f")
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	require.NoError(t, db.RevokeSessionFamily(ctx, "f"))
+	active, err = db.IsSessionActive(ctx, "f")
+	require.NoError(t, err)
+	assert.False(t, active)
+}
+
+func TestMemoryClientTOTP(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryClient()
+
+	require.NoError(t, db.SaveUserTOTP(ctx, &models.UserTOTP{UserID: 1, Secret: "s"}))
+	require.NoError(t, db.EnableTOTP(ctx, 1, 10, []string{"h1", "h2"}))
+	assert.ErrorIs(t, db.UseTOTPStep(ctx, 1, 10), ErrCodeReused)
+	assert.NoError(t, db.UseTOTPStep(ctx, 1, 11))
+
+	require.NoError(t, db.UseRecoveryCode(ctx, 1, "h1"))
+	assert.ErrorIs(t, db.UseRecoveryCode(ctx, 1, "h1"), ErrInvalidRecoveryCode)
+
+	require.NoError(t, db.DisableTOTP(ctx, 1))
+	_, err := db.GetUserTOTP(ctx, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, db.UseRecoveryCode(ctx, 1, "h2"), ErrInvalidRecoveryCode)
+}
+
+func TestMemoryClientForcePasswordReset(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryClient()
+	user := &models.User{Email: "ann@example.com", Password: "secret123"}
+	require.NoError(t, db.CreateUser(ctx, user))
+
+	expires := time.Now().Add(time.Hour)
+	require.NoError(t, db.CreatePasswordReset(ctx, &models.PasswordReset{UserID: user.ID, TokenHash: "earlier", ExpiresAt: expires}))
+	require.NoError(t, db.ForcePasswordReset(ctx, &models.PasswordReset{UserID: user.ID, TokenHash: "forced", ExpiresAt: expires}))
+
+	_, err := db.ResetPassword(ctx, "earlier", "new-secret")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	userID, err := db.ResetPassword(ctx, "forced", "new-secret")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+}
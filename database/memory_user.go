@@ -0,0 +1,548 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// memoryBcryptCost keeps password hashing fast in tests; VerifyPassword
+// accepts hashes of any cost
+const memoryBcryptCost = bcrypt.MinCost
+
+// userByEmail finds a user the way MySQL's case-insensitive collation does.
+// m.mu must be held.
+func (m *MemoryClient) userByEmail(email string) *models.User {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+func (m *MemoryClient) CreateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("CreateUser"); err != nil {
+		return err
+	}
+	if m.userByEmail(user.Email) != nil {
+		return gorm.ErrDuplicatedKey
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), memoryBcryptCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	user.ID = m.newID()
+	user.CreatedAt, user.UpdatedAt = utcNow(), utcNow()
+
+	stored := *user
+	m.users[user.ID] = &stored
+	return nil
+}
+
+func (m *MemoryClient) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetUserByID"); err != nil {
+		return nil, err
+	}
+	user, ok := m.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *MemoryClient) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetUserByEmail"); err != nil {
+		return nil, err
+	}
+	user := m.userByEmail(email)
+	if user == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// UpdateUser sets the given columns of a user, as Client.UpdateUser does.
+// Only the columns of models.User are known.
+func (m *MemoryClient) UpdateUser(ctx context.Context, id int, updates map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("UpdateUser"); err != nil {
+		return err
+	}
+	user, ok := m.users[id]
+	if !ok {
+		return nil
+	}
+	updated := *user
+	for column, value := range updates {
+		if err := setUserColumn(&updated, column, value); err != nil {
+			return err
+		}
+	}
+	updated.UpdatedAt = utcNow()
+	*user = updated
+	return nil
+}
+
+func setUserColumn(user *models.User, column string, value interface{}) error {
+	var ok bool
+	switch column {
+	case "email":
+		user.Email, ok = value.(string)
+	case "password":
+		user.Password, ok = value.(string)
+	case "first_name":
+		user.FirstName, ok = value.(string)
+	case "last_name":
+		user.LastName, ok = value.(string)
+	case "role":
+		user.Role, ok = value.(string)
+	case "age":
+		user.Age, ok = value.(int)
+	case "believer_category":
+		user.BelieverCategory, ok = value.(int)
+	case "email_verified_at":
+		user.EmailVerifiedAt, ok = timeValue(value)
+	case "disabled_at":
+		user.DisabledAt, ok = timeValue(value)
+	default:
+		return fmt.Errorf("memory database: unknown users column %q", column)
+	}
+	if !ok {
+		return fmt.Errorf("memory database: unsupported value %T for users.%s", value, column)
+	}
+	return nil
+}
+
+// timeValue accepts the values GORM accepts for a nullable datetime column
+func timeValue(value interface{}) (*time.Time, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case time.Time:
+		return &v, true
+	case *time.Time:
+		return v, true
+	}
+	return nil, false
+}
+
+func (m *MemoryClient) DeleteUser(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("DeleteUser"); err != nil {
+		return err
+	}
+	m.favorites = filter(m.favorites, func(f models.UserFavoriteVerse) bool { return f.UserID != id })
+	m.highlights = filter(m.highlights, func(h models.UserHighlightedVerse) bool { return h.UserID != id })
+	m.sessions = filter(m.sessions, func(s *models.Session) bool { return s.UserID != id })
+	m.resets = filter(m.resets, func(r *models.PasswordReset) bool { return r.UserID != id })
+	m.recoveryCodes = filter(m.recoveryCodes, func(c *models.RecoveryCode) bool { return c.UserID != id })
+	delete(m.lastRead, id)
+	delete(m.totp, id)
+	delete(m.users, id)
+	return nil
+}
+
+// filter keeps the items for which keep returns true
+func filter[T any](items []T, keep func(T) bool) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (m *MemoryClient) VerifyPassword(ctx context.Context, email, password string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("VerifyPassword"); err != nil {
+		return nil, err
+	}
+	user := m.userByEmail(email)
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// User administration
+
+// searchUsers filters users like Client.usersQuery. m.mu must be held.
+func (m *MemoryClient) searchUsers(search, role string) []models.User {
+	search = strings.ToLower(strings.TrimSpace(search))
+	users := []models.User{}
+	for _, user := range m.users {
+		if role != "" && user.Role != role {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(user.Email), search) &&
+			!strings.Contains(strings.ToLower(user.FirstName), search) &&
+			!strings.Contains(strings.ToLower(user.LastName), search) {
+			continue
+		}
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (m *MemoryClient) SearchUsers(ctx context.Context, search, role string, limit, offset int) ([]models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("SearchUsers"); err != nil {
+		return nil, err
+	}
+	return page(m.searchUsers(search, role), limit, offset), nil
+}
+
+func (m *MemoryClient) CountUsers(ctx context.Context, search, role string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("CountUsers"); err != nil {
+		return 0, err
+	}
+	return int64(len(m.searchUsers(search, role))), nil
+}
+
+func (m *MemoryClient) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("SetUserDisabled"); err != nil {
+		return err
+	}
+	user, ok := m.users[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.DisabledAt = nil
+	if disabled {
+		t := utcNow()
+		user.DisabledAt = &t
+		m.revokeUserSessions(userID)
+	}
+	user.UpdatedAt = utcNow()
+	return nil
+}
+
+func (m *MemoryClient) ForcePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("ForcePasswordReset"); err != nil {
+		return err
+	}
+	user, ok := m.users[reset.UserID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword(random, memoryBcryptCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	m.revokeUserSessions(reset.UserID)
+	m.usePasswordResets(reset.UserID, utcNow())
+	return m.createPasswordReset(reset)
+}
+
+func (m *MemoryClient) GetUserStats(ctx context.Context, userID int) (*models.UserStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetUserStats"); err != nil {
+		return nil, err
+	}
+	var stats models.UserStats
+	for _, f := range m.favorites {
+		if f.UserID == userID {
+			stats.Favorites++
+		}
+	}
+	for _, h := range m.highlights {
+		if h.UserID == userID {
+			stats.Highlights++
+		}
+	}
+	for _, s := range m.sessions {
+		if s.UserID != userID {
+			continue
+		}
+		if s.RevokedAt == nil && s.ExpiresAt.After(utcNow()) {
+			stats.ActiveSessions++
+		}
+		if stats.LastActiveAt == nil || s.CreatedAt.After(*stats.LastActiveAt) {
+			createdAt := s.CreatedAt
+			stats.LastActiveAt = &createdAt
+		}
+	}
+	if lastRead, ok := m.lastRead[userID]; ok {
+		stats.LastReadAt = &lastRead.UpdatedAt
+	}
+	return &stats, nil
+}
+
+// Favorite verses
+
+func (m *MemoryClient) AddFavoriteVerse(ctx context.Context, userID, bookID, chapter, verse int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("AddFavoriteVerse"); err != nil {
+		return err
+	}
+	for _, f := range m.favorites {
+		if f.UserID == userID && f.BookID == bookID && f.Chapter == chapter && f.Verse == verse {
+			return ErrAlreadyFavorite
+		}
+	}
+	m.favorites = append(m.favorites, models.UserFavoriteVerse{
+		ID:        m.newID(),
+		UserID:    userID,
+		BookID:    bookID,
+		Chapter:   chapter,
+		Verse:     verse,
+		CreatedAt: utcNow(),
+	})
+	return nil
+}
+
+func (m *MemoryClient) GetFavoriteVerses(ctx context.Context, userID, limit, offset int) ([]models.UserFavoriteVerse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetFavoriteVerses"); err != nil {
+		return nil, err
+	}
+	favorites := []models.UserFavoriteVerse{}
+	for _, f := range m.favorites {
+		if f.UserID == userID {
+			favorites = append(favorites, f)
+		}
+	}
+	// Newest first; IDs break ties between favorites added in the same instant
+	sort.Slice(favorites, func(i, j int) bool {
+		if !favorites[i].CreatedAt.Equal(favorites[j].CreatedAt) {
+			return favorites[i].CreatedAt.After(favorites[j].CreatedAt)
+		}
+		return favorites[i].ID > favorites[j].ID
+	})
+	return page(favorites, limit, offset), nil
+}
+
+func (m *MemoryClient) GetFavoriteVersesCount(ctx context.Context, userID int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetFavoriteVersesCount"); err != nil {
+		return 0, err
+	}
+	var count int64
+	for _, f := range m.favorites {
+		if f.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryClient) RemoveFavoriteVerse(ctx context.Context, userID, bookID, chapter, verse int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RemoveFavoriteVerse"); err != nil {
+		return err
+	}
+	m.favorites = filter(m.favorites, func(f models.UserFavoriteVerse) bool {
+		return !(f.UserID == userID && f.BookID == bookID && f.Chapter == chapter && f.Verse == verse)
+	})
+	return nil
+}
+
+func (m *MemoryClient) IsFavoriteVerse(ctx context.Context, userID, bookID, chapter, verse int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("IsFavoriteVerse"); err != nil {
+		return false, err
+	}
+	for _, f := range m.favorites {
+		if f.UserID == userID && f.BookID == bookID && f.Chapter == chapter && f.Verse == verse {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Highlighted verses
+
+func (m *MemoryClient) AddHighlightedVerse(ctx context.Context, userID, bookID, chapter, verse int, note, color string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("AddHighlightedVerse"); err != nil {
+		return err
+	}
+	if color == "" {
+		color = "yellow"
+	}
+	for _, h := range m.highlights {
+		if h.UserID == userID && h.BookID == bookID && h.Chapter == chapter && h.Verse == verse {
+			return ErrAlreadyHighlighted
+		}
+	}
+	m.highlights = append(m.highlights, models.UserHighlightedVerse{
+		ID:        m.newID(),
+		UserID:    userID,
+		BookID:    bookID,
+		Chapter:   chapter,
+		Verse:     verse,
+		Note:      note,
+		Color:     color,
+		CreatedAt: utcNow(),
+		UpdatedAt: utcNow(),
+	})
+	return nil
+}
+
+func (m *MemoryClient) GetHighlightedVerses(ctx context.Context, userID, limit, offset int) ([]models.UserHighlightedVerse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetHighlightedVerses"); err != nil {
+		return nil, err
+	}
+	highlights := []models.UserHighlightedVerse{}
+	for _, h := range m.highlights {
+		if h.UserID == userID {
+			highlights = append(highlights, h)
+		}
+	}
+	sort.Slice(highlights, func(i, j int) bool {
+		if !highlights[i].UpdatedAt.Equal(highlights[j].UpdatedAt) {
+			return highlights[i].UpdatedAt.After(highlights[j].UpdatedAt)
+		}
+		return highlights[i].ID > highlights[j].ID
+	})
+	return page(highlights, limit, offset), nil
+}
+
+func (m *MemoryClient) GetHighlightedVersesCount(ctx context.Context, userID int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetHighlightedVersesCount"); err != nil {
+		return 0, err
+	}
+	var count int64
+	for _, h := range m.highlights {
+		if h.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryClient) UpdateHighlightedVerse(ctx context.Context, userID, bookID, chapter, verse int, note, color string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("UpdateHighlightedVerse"); err != nil {
+		return err
+	}
+	if note == "" && color == "" {
+		return nil
+	}
+	for i, h := range m.highlights {
+		if h.UserID == userID && h.BookID == bookID && h.Chapter == chapter && h.Verse == verse {
+			if note != "" {
+				m.highlights[i].Note = note
+			}
+			if color != "" {
+				m.highlights[i].Color = color
+			}
+			m.highlights[i].UpdatedAt = utcNow()
+			return nil
+		}
+	}
+	return ErrHighlightNotFound
+}
+
+func (m *MemoryClient) RemoveHighlightedVerse(ctx context.Context, userID, bookID, chapter, verse int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("RemoveHighlightedVerse"); err != nil {
+		return err
+	}
+	m.highlights = filter(m.highlights, func(h models.UserHighlightedVerse) bool {
+		return !(h.UserID == userID && h.BookID == bookID && h.Chapter == chapter && h.Verse == verse)
+	})
+	return nil
+}
+
+// Last read position
+
+func (m *MemoryClient) UpdateLastRead(ctx context.Context, userID, bookID int, bookName string, chapter, verse int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("UpdateLastRead"); err != nil {
+		return err
+	}
+	lastRead, ok := m.lastRead[userID]
+	if !ok {
+		lastRead = models.UserLastRead{ID: m.newID(), UserID: userID}
+	}
+	lastRead.BookID = bookID
+	lastRead.BookName = bookName
+	lastRead.Chapter = chapter
+	lastRead.Verse = verse
+	lastRead.UpdatedAt = utcNow()
+	m.lastRead[userID] = lastRead
+	return nil
+}
+
+func (m *MemoryClient) GetLastRead(ctx context.Context, userID int) (*models.UserLastRead, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetLastRead"); err != nil {
+		return nil, err
+	}
+	lastRead, ok := m.lastRead[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &lastRead, nil
+}
+
+func (m *MemoryClient) GetLastReadVerses(ctx context.Context, userID int) ([]models.UserLastRead, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("GetLastReadVerses"); err != nil {
+		return nil, err
+	}
+	lastReads := []models.UserLastRead{}
+	if lastRead, ok := m.lastRead[userID]; ok {
+		lastReads = append(lastReads, lastRead)
+	}
+	return lastReads, nil
+}
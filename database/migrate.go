@@ -0,0 +1,12 @@
+package database
+
+import "bible_reading_backend_nkv/migrations"
+
+// Migrator returns a schema migrator for the client's database
+func (c Client) Migrator() (*migrations.Migrator, error) {
+	db, err := c.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(db, c.DB.Dialector.Name())
+}
@@ -2,33 +2,40 @@ package database
 
 import (
 	"bible_reading_backend_nkv/models"
+
 	"golang.org/x/net/context"
 )
 
-
-func (c Client) GetAllVerse(ctx context.Context) ([]models.NIV , error){
-	var verse []  models.NIV
-	result:= c.DB.WithContext(ctx).Find(&verse)
+func (c Client) GetAllVerse(ctx context.Context, translationID string) ([]models.Verse, error) {
+	var verse []models.Verse
+	result := c.DB.WithContext(ctx).
+		Where("translation_id = ?", translationID).
+		Order("book_id, chapter, verse").
+		Find(&verse)
 	return verse, result.Error
 }
 
-func (c Client) GetAllVerseByChapter(ctx context.Context, bookId int, chapterId int) ([]models.NIV , error){
-	var verse []  models.NIV
-	result:= c.DB.WithContext(ctx).Where("chapter = ? AND book_id = ?", chapterId, bookId).Find(&verse)
+func (c Client) GetAllVerseByChapter(ctx context.Context, translationID string, bookId int, chapterId int) ([]models.Verse, error) {
+	var verse []models.Verse
+	result := c.DB.WithContext(ctx).
+		Where("translation_id = ? AND chapter = ? AND book_id = ?", translationID, chapterId, bookId).
+		Order("verse").
+		Find(&verse)
 	return verse, result.Error
 }
 
-func (c Client) GetAllBook(ctx context.Context) ([]BookDTO , error){
-	var verse []  BookDTO
-	result:= c.DB.WithContext(ctx).Model(&models.NIV{}).Distinct("book_id, book").Find(&verse)
+// GetVersesInRange returns the verses of one book from startChapter:startVerse
+// through endChapter:endVerse. An endVerse of 0 means the end of endChapter.
+func (c Client) GetVersesInRange(ctx context.Context, translationID string, bookId, startChapter, startVerse, endChapter, endVerse int) ([]models.Verse, error) {
+	var verse []models.Verse
+	query := c.DB.WithContext(ctx).
+		Where("translation_id = ? AND book_id = ?", translationID, bookId).
+		Where("(chapter > ? OR (chapter = ? AND verse >= ?))", startChapter, startChapter, startVerse)
+	if endVerse == 0 {
+		query = query.Where("chapter <= ?", endChapter)
+	} else {
+		query = query.Where("(chapter < ? OR (chapter = ? AND verse <= ?))", endChapter, endChapter, endVerse)
+	}
+	result := query.Order("chapter, verse").Find(&verse)
 	return verse, result.Error
 }
-func (c Client) GetAllChapter(ctx context.Context, bookId int) ( ChapterMaxDTO , error){
-	var chap  ChapterMaxDTO
-
-
-    query := "SELECT MAX(chapter) as maxChapter FROM niv WHERE book_id = ? "
-    result := c.DB.WithContext(ctx).Raw(query, bookId).Scan(&chap)
-
-	return chap, result.Error
-}
\ No newline at end of file
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidResetToken is returned for unknown, used or expired reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+func (c Client) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	return c.DB.WithContext(ctx).Create(reset).Error
+}
+
+// ResetPassword consumes the reset token with the given hash and sets the
+// user's new password. Other outstanding reset tokens and all sessions of the
+// user are revoked in the same transaction.
+func (c Client) ResetPassword(ctx context.Context, tokenHash, password string) (int, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int
+	err = c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		var reset models.PasswordReset
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(&reset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		userID = reset.UserID
+
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", reset.UserID).
+			Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", reset.UserID).
+			Update("revoked_at", now).Error
+	})
+	return userID, err
+}
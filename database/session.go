@@ -0,0 +1,70 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionRevoked is returned by RotateSession when the session was already
+// rotated or revoked, e.g. by a concurrent refresh with the same token
+var ErrSessionRevoked = errors.New("session revoked")
+
+func (c Client) CreateSession(ctx context.Context, session *models.Session) error {
+	return c.DB.WithContext(ctx).Create(session).Error
+}
+
+func (c Client) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	result := c.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &session, nil
+}
+
+// RotateSession revokes old and stores next in one transaction
+func (c Client) RotateSession(ctx context.Context, old *models.Session, next *models.Session) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Update("revoked_at", time.Now().UTC())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSessionRevoked
+		}
+		return tx.Create(next).Error
+	})
+}
+
+// RevokeSessionFamily revokes every token descended from one login
+func (c Client) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	return c.DB.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// RevokeUserSessions revokes all of a user's sessions
+func (c Client) RevokeUserSessions(ctx context.Context, userID int) error {
+	return c.DB.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// IsSessionActive reports whether a session family still has an unrevoked,
+// unexpired refresh token
+func (c Client) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	var count int64
+	result := c.DB.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", familyID, time.Now().UTC()).
+		Count(&count)
+	return count > 0, result.Error
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultTranslations are registered on startup if they are missing
+var defaultTranslations = []models.Translation{
+	{
+		ID:            models.DefaultTranslationID,
+		Name:          "New International Version",
+		Language:      "en",
+		Copyright:     "Holy Bible, New International Version®, NIV® Copyright ©1973, 1978, 1984, 2011 by Biblica, Inc.®",
+		Versification: "KJV",
+	},
+}
+
+func (c Client) GetTranslations(ctx context.Context) ([]models.Translation, error) {
+	var translations []models.Translation
+	result := c.DB.WithContext(ctx).Order("id").Find(&translations)
+	return translations, result.Error
+}
+
+func (c Client) GetTranslation(ctx context.Context, id string) (*models.Translation, error) {
+	var translation models.Translation
+	result := c.DB.WithContext(ctx).Where("id = ?", id).First(&translation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &translation, nil
+}
+
+// SeedTranslations registers the default translations, copies the legacy
+// niv table into the verses table the first time it runs against a database
+// that still only has the single-translation layout, and builds missing book
+// catalogues.
+func (c Client) SeedTranslations(ctx context.Context) error {
+	db := c.DB.WithContext(ctx)
+
+	for _, translation := range defaultTranslations {
+		var existing models.Translation
+		err := db.Where("id = ?", translation.ID).First(&existing).Error
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := db.Create(&translation).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := c.backfillNIV(db); err != nil {
+		return err
+	}
+
+	// Build the book catalogue for translations loaded before it existed
+	translations, err := c.GetTranslations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, translation := range translations {
+		var books int64
+		if err := db.Model(&models.Book{}).Where("translation_id = ?", translation.ID).Count(&books).Error; err != nil {
+			return err
+		}
+		if books == 0 {
+			if err := c.RefreshBooks(ctx, translation.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backfillNIV copies the legacy niv table into the verses table once
+func (c Client) backfillNIV(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.NIV{}) {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Verse{}).Where("translation_id = ?", models.DefaultTranslationID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Exec(
+		"INSERT INTO verses (translation_id, book_id, book, chapter, verse, text) "+
+			"SELECT ?, book_id, book, chapter, verse, text FROM niv",
+		models.DefaultTranslationID,
+	).Error
+}
+
+// ReplaceTranslation registers or updates a translation and replaces all of
+// its verses in one transaction, so re-importing a text is idempotent. Cached
+// explanations of the old text are dropped as well.
+func (c Client) ReplaceTranslation(ctx context.Context, translation *models.Translation, verses []models.Verse) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upsert := clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "language", "copyright", "versification", "updated_at"}),
+		}
+		if err := tx.Clauses(upsert).Create(translation).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("translation_id = ?", translation.ID).Delete(&models.Verse{}).Error; err != nil {
+			return err
+		}
+		if len(verses) > 0 {
+			if err := tx.CreateInBatches(verses, 1000).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("translation_id = ?", translation.ID).Delete(&models.Explanation{}).Error; err != nil {
+			return err
+		}
+		return refreshBooks(tx, translation.ID)
+	})
+}
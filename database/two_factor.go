@@ -0,0 +1,114 @@
+package database
+
+import (
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCodeReused is returned by UseTOTPStep when a code at or before step
+	// has already been accepted
+	ErrCodeReused = errors.New("authentication code already used")
+	// ErrInvalidRecoveryCode is returned by UseRecoveryCode for unknown or
+	// used codes
+	ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+)
+
+func (c Client) GetUserTOTP(ctx context.Context, userID int) (*models.UserTOTP, error) {
+	var t models.UserTOTP
+	result := c.DB.WithContext(ctx).Where("user_id = ?", userID).First(&t)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &t, nil
+}
+
+// SaveUserTOTP starts enrolment with a new secret, replacing any unconfirmed one
+func (c Client) SaveUserTOTP(ctx context.Context, t *models.UserTOTP) error {
+	return c.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_used_step", "updated_at"}),
+	}).Create(t).Error
+}
+
+// EnableTOTP turns on 2FA for userID, recording step as used, and replaces
+// the user's recovery codes with codeHashes
+func (c Client) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.UserTOTP{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"enabled_at":     time.Now().UTC(),
+			"last_used_step": step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores codeHashes
+func (c Client) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// DisableTOTP removes the user's secret and recovery codes
+func (c Client) DisableTOTP(ctx context.Context, userID int) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTOTP{}).Error
+	})
+}
+
+// UseTOTPStep records that the code for step was used. Conditioning the update
+// on the previous step makes concurrent logins with the same code fail.
+func (c Client) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	result := c.DB.WithContext(ctx).
+		Model(&models.UserTOTP{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCodeReused
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used
+func (c Client) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	result := c.DB.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidRecoveryCode
+	}
+	return nil
+}
@@ -9,6 +9,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrInvalidCredentials is returned by VerifyPassword for an unknown email or
+// a wrong password, so callers cannot tell the two apart
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 func (c Client) CreateUser(ctx context.Context, user *models.User) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -43,23 +47,39 @@ func (c Client) UpdateUser(ctx context.Context, id int, updates map[string]inter
 	return result.Error
 }
 
+// DeleteUser deletes a user together with their favorites, highlights, last
+// read position, sessions, reset tokens and two-factor settings
 func (c Client) DeleteUser(ctx context.Context, id int) error {
-	result := c.DB.WithContext(ctx).Delete(&models.User{}, id)
-	return result.Error
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.UserFavoriteVerse{},
+			&models.UserHighlightedVerse{},
+			&models.UserLastRead{},
+			&models.Session{},
+			&models.PasswordReset{},
+			&models.UserTOTP{},
+			&models.RecoveryCode{},
+		} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
 }
 
 func (c Client) VerifyPassword(ctx context.Context, email, password string) (*models.User, error) {
 	user, err := c.GetUserByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid credentials")
+			return nil, ErrInvalidCredentials
 		}
 		return nil, err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	return user, nil
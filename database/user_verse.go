@@ -8,6 +8,12 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrAlreadyFavorite    = errors.New("verse already in favorites")
+	ErrAlreadyHighlighted = errors.New("verse already highlighted")
+	ErrHighlightNotFound  = errors.New("highlight not found")
+)
+
 // Favorite Verses Methods
 
 func (c Client) AddFavoriteVerse(ctx context.Context, userID, bookID, chapter, verse int) error {
@@ -21,7 +27,7 @@ func (c Client) AddFavoriteVerse(ctx context.Context, userID, bookID, chapter, v
 	result := c.DB.WithContext(ctx).Create(&favorite)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-			return errors.New("verse already in favorites")
+			return ErrAlreadyFavorite
 		}
 		return result.Error
 	}
@@ -85,7 +91,7 @@ func (c Client) AddHighlightedVerse(ctx context.Context, userID, bookID, chapter
 	result := c.DB.WithContext(ctx).Create(&highlight)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-			return errors.New("verse already highlighted")
+			return ErrAlreadyHighlighted
 		}
 		return result.Error
 	}
@@ -135,7 +141,7 @@ func (c Client) UpdateHighlightedVerse(ctx context.Context, userID, bookID, chap
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("highlight not found")
+		return ErrHighlightNotFound
 	}
 	return nil
 }
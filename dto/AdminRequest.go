@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// AdminUserResponse is a user as seen through the admin API
+type AdminUserResponse struct {
+	UserResponse
+	Disabled   bool       `json:"disabled"`
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
@@ -14,12 +14,39 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// LoginResponse carries either tokens or, for users with two-factor
+// authentication, a challenge to exchange at /api/login/2fa
 type LoginResponse struct {
-	Access string `json:"access"`
+	Access      string `json:"access,omitempty"`
+	Refresh     string `json:"refresh,omitempty"`
+	ExpiresIn   int    `json:"expires_in,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	Challenge   string `json:"challenge,omitempty"`
 }
 
+// RegisterResponse omits the tokens when the deployment requires a verified
+// email before logging in
 type RegisterResponse struct {
-	Access string      `json:"access"`
-	User   UserResponse `json:"user"`
+	Access               string       `json:"access,omitempty"`
+	Refresh              string       `json:"refresh,omitempty"`
+	ExpiresIn            int          `json:"expires_in,omitempty"`
+	VerificationRequired bool         `json:"verification_required"`
+	User                 UserResponse `json:"user"`
 }
 
+type RefreshRequest struct {
+	Refresh string `json:"refresh" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
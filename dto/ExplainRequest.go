@@ -9,10 +9,21 @@ type ExplainRequest struct {
 	Belief     int    `json:"belief"`
 }
 
+type ExplainResponse struct {
+	Explanation string `json:"explanation"`
+	Cached      bool   `json:"cached"`
+}
+
 type OpenAIRequest struct {
-	Model     string        `json:"model"`
-	Messages  []ChatMessage `json:"messages"`
-	MaxTokens int           `json:"max_tokens"`
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	MaxTokens     int            `json:"max_tokens"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type ChatMessage struct {
@@ -22,6 +33,36 @@ type ChatMessage struct {
 
 type OpenAIResponse struct {
 	Choices []struct {
-		Message ChatMessage `json:"message"`
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *TokenUsage `json:"usage,omitempty"`
+}
+
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// OpenAIStreamChunk is one "data:" line of a streamed chat completion. With
+// stream_options.include_usage the last chunk has no choices and carries usage.
+type OpenAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *TokenUsage `json:"usage,omitempty"`
+}
+
+type ExplainDeltaEvent struct {
+	Content string `json:"content"`
+}
+
+type ExplainDoneEvent struct {
+	FinishReason string      `json:"finish_reason"`
+	Usage        *TokenUsage `json:"usage"`
+	Cached       bool        `json:"cached"`
 }
@@ -0,0 +1,24 @@
+package dto
+
+type PassageResponse struct {
+	Reference   string         `json:"reference"`
+	Translation string         `json:"translation"`
+	Passages    []PassageRange `json:"passages"`
+}
+
+type PassageRange struct {
+	Reference    string                   `json:"reference"`
+	BookID       int                      `json:"book_id"`
+	BookName     string                   `json:"book_name"`
+	StartChapter int                      `json:"start_chapter"`
+	StartVerse   int                      `json:"start_verse"`
+	EndChapter   int                      `json:"end_chapter"`
+	EndVerse     int                      `json:"end_verse"`
+	Verses       []VerseReferenceResponse `json:"verses"`
+}
+
+type ParseErrorResponse struct {
+	Error  string `json:"error"`
+	Token  string `json:"token"`
+	Offset int    `json:"offset"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+type SearchResult struct {
+	BookID     int         `json:"book_id"`
+	BookName   string      `json:"book_name"`
+	Chapter    int         `json:"chapter"`
+	Verse      int         `json:"verse"`
+	Text       string      `json:"text"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// Highlight is a match in Text, as [start, end) offsets in Unicode code points
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
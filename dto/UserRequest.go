@@ -10,14 +10,16 @@ type UpdateUserRequest struct {
 }
 
 type UserResponse struct {
-	ID               int       `json:"id"`
-	Email            string    `json:"email"`
-	FirstName        string    `json:"first_name"`
-	LastName         string    `json:"last_name"`
-	Name             string    `json:"name"`
-	Age              int       `json:"age"`
-	BelieverCategory int       `json:"believer_category"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID               int        `json:"id"`
+	Email            string     `json:"email"`
+	FirstName        string     `json:"first_name"`
+	LastName         string     `json:"last_name"`
+	Name             string     `json:"name"`
+	Age              int        `json:"age"`
+	BelieverCategory int        `json:"believer_category"`
+	Role             string     `json:"role"`
+	EmailVerified    bool       `json:"email_verified"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
-
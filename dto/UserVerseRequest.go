@@ -16,6 +16,11 @@ type AddHighlightRequest struct {
 	Color   string `json:"color,omitempty" validate:"omitempty,max=20"`
 }
 
+type UpdateHighlightRequest struct {
+	Note  string `json:"note,omitempty"`
+	Color string `json:"color,omitempty" validate:"omitempty,max=20"`
+}
+
 type UpdateLastReadRequest struct {
 	BookID   int    `json:"book_id" validate:"required,min=1"`
 	BookName string `json:"book_name" validate:"required,min=1,max=255"`
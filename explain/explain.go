@@ -0,0 +1,119 @@
+// Package explain generates passage explanations with a configurable LLM
+// provider.
+package explain
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Request describes the passage to explain and the reader it is written for.
+// Passage holds the text of StartVerse through EndVerse; Before and After are
+// neighbouring verses sent only as context.
+type Request struct {
+	Translation string
+	BookID      int
+	Book        string
+	Chapter     int
+	StartVerse  int
+	EndVerse    int
+	Age         int
+	Belief      int
+	Passage     []models.Verse
+	Before      []models.Verse
+	After       []models.Verse
+}
+
+// Result is a finished explanation
+type Result struct {
+	Text         string
+	FinishReason string
+	Model        string
+	Usage        *dto.TokenUsage
+}
+
+// Explainer is implemented by every LLM provider
+type Explainer interface {
+	// Explain waits for the whole completion
+	Explain(ctx context.Context, req Request) (*Result, error)
+	// Stream calls onDelta with each piece of text as it is generated and
+	// returns the complete result. Returning an error from onDelta aborts
+	// the stream. Cancelling ctx cancels the upstream request.
+	Stream(ctx context.Context, req Request, onDelta func(string) error) (*Result, error)
+	// Model returns the model name requests are sent to
+	Model() string
+}
+
+// ConfigError means the provider is misconfigured; its message is safe to
+// return to clients
+type ConfigError struct {
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return e.Message
+}
+
+// UpstreamError is a non-200 response from the provider's API
+type UpstreamError struct {
+	StatusCode int
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream returned status %d", e.StatusCode)
+}
+
+const (
+	ProviderOpenAI           = "openai"
+	ProviderOpenAICompatible = "openai-compatible"
+	ProviderFake             = "fake"
+)
+
+// Config selects and configures a provider
+type Config struct {
+	Provider  string
+	Model     string
+	BaseURL   string
+	APIKey    string
+	MaxTokens int
+	Timeout   time.Duration
+}
+
+// New builds the provider described by cfg. An empty provider means OpenAI.
+func New(cfg Config) (Explainer, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		return NewOpenAI(cfg)
+	case ProviderOpenAICompatible:
+		return NewOpenAICompatible(cfg)
+	case ProviderFake:
+		return Fake{}, nil
+	}
+	return nil, &ConfigError{Message: fmt.Sprintf("Unknown LLM provider %q", cfg.Provider)}
+}
+
+// Unavailable is an Explainer that fails every call with err. It stands in
+// for a provider that could not be configured, so the rest of the server can
+// still start.
+func Unavailable(err error) Explainer {
+	return unavailable{err: err}
+}
+
+type unavailable struct {
+	err error
+}
+
+func (u unavailable) Explain(ctx context.Context, req Request) (*Result, error) {
+	return nil, u.err
+}
+
+func (u unavailable) Stream(ctx context.Context, req Request, onDelta func(string) error) (*Result, error) {
+	return nil, u.err
+}
+
+func (u unavailable) Model() string {
+	return ""
+}
@@ -0,0 +1,249 @@
+package explain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/metrics"
+	"bible_reading_backend_nkv/models"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var testRequest = Request{Book: "John", Chapter: 3, StartVerse: 16, EndVerse: 17, Age: 25, Belief: 3}
+
+func TestFakeIsDeterministic(t *testing.T) {
+	var deltas []string
+	streamed, err := Fake{}.Stream(context.Background(), testRequest, func(s string) error {
+		deltas = append(deltas, s)
+		return nil
+	})
+	require.NoError(t, err)
+
+	explained, err := Fake{}.Explain(context.Background(), testRequest)
+	require.NoError(t, err)
+
+	assert.Equal(t, explained, streamed)
+	assert.Equal(t, streamed.Text, strings.Join(deltas, ""))
+	assert.Contains(t, streamed.Text, "John 3:16-17")
+	assert.Equal(t, "stop", streamed.FinishReason)
+	require.NotNil(t, streamed.Usage)
+	assert.Equal(t, len(deltas), streamed.Usage.CompletionTokens)
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		message string
+	}{
+		{"missing openai key", Config{}, "OpenAI API key not configured"},
+		{"bad openai key", Config{APIKey: "abc"}, "Invalid API key format"},
+		{"compatible without url", Config{Provider: ProviderOpenAICompatible, Model: "llama3"}, "LLM base URL not configured"},
+		{"compatible without model", Config{Provider: ProviderOpenAICompatible, BaseURL: "http://localhost"}, "LLM model not configured"},
+		{"unknown provider", Config{Provider: "other"}, `Unknown LLM provider "other"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			var configErr *ConfigError
+			require.ErrorAs(t, err, &configErr)
+			assert.Equal(t, tt.message, configErr.Message)
+		})
+	}
+
+	e, err := New(Config{APIKey: "sk-test"})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", e.Model())
+}
+
+// compatibleServer answers chat-completions requests the way OpenAI does
+func compatibleServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+
+		var req dto.OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "llama3", req.Model)
+		assert.NotEmpty(t, req.Messages)
+
+		if !req.Stream {
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"God loves the world."},"finish_reason":"stop"}],
+				"usage":{"prompt_tokens":10,"completion_tokens":4,"total_tokens":14}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"content":"God "}}]}`,
+			`{"choices":[{"delta":{"content":"loves."},"finish_reason":"stop"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestOpenAICompatible(t *testing.T) {
+	srv := compatibleServer(t)
+	defer srv.Close()
+
+	e, err := New(Config{Provider: ProviderOpenAICompatible, BaseURL: srv.URL + "/v1/", Model: "llama3"})
+	require.NoError(t, err)
+
+	result, err := e.Explain(context.Background(), testRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "God loves the world.", result.Text)
+	assert.Equal(t, "stop", result.FinishReason)
+	assert.Equal(t, 14, result.Usage.TotalTokens)
+
+	var deltas []string
+	result, err = e.Stream(context.Background(), testRequest, func(s string) error {
+		deltas = append(deltas, s)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"God ", "loves."}, deltas)
+	assert.Equal(t, "God loves.", result.Text)
+	assert.Equal(t, "stop", result.FinishReason)
+	assert.Equal(t, 12, result.Usage.TotalTokens)
+}
+
+func TestStreamEndingEarly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// The connection closes without a finish reason or [DONE]
+		fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"delta":{"content":"God "}}]}`)
+	}))
+	defer srv.Close()
+
+	e, err := New(Config{Provider: ProviderOpenAICompatible, BaseURL: srv.URL, Model: "llama3"})
+	require.NoError(t, err)
+
+	var deltas []string
+	_, err = e.Stream(context.Background(), testRequest, func(s string) error {
+		deltas = append(deltas, s)
+		return nil
+	})
+	assert.EqualError(t, err, "stream ended before [DONE]")
+	assert.Equal(t, []string{"God "}, deltas)
+}
+
+func TestRequestIDIsForwarded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "req-1", r.Header.Get("X-Client-Request-Id"))
+		assert.Equal(t, "req-1", r.Header.Get("X-Request-ID"))
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	e, err := New(Config{Provider: ProviderOpenAICompatible, BaseURL: srv.URL, Model: "llama3"})
+	require.NoError(t, err)
+	_, err = e.Explain(logging.WithRequestID(context.Background(), "req-1"), testRequest)
+	require.NoError(t, err)
+}
+
+func TestTraceIsPropagated(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	e, err := New(Config{Provider: ProviderOpenAICompatible, BaseURL: srv.URL, Model: "llama3"})
+	require.NoError(t, err)
+	_, err = e.Explain(ctx, testRequest)
+	require.NoError(t, err)
+}
+
+func TestUpstreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	e, err := New(Config{Provider: ProviderOpenAICompatible, BaseURL: srv.URL, Model: "llama3"})
+	require.NoError(t, err)
+
+	_, err = e.Explain(context.Background(), testRequest)
+	var upstream *UpstreamError
+	require.ErrorAs(t, err, &upstream)
+	assert.Equal(t, http.StatusTooManyRequests, upstream.StatusCode)
+}
+
+func TestWithMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req dto.OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Stream {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":4,"total_tokens":14}}`)
+	}))
+	defer srv.Close()
+
+	e, err := New(Config{Provider: ProviderOpenAICompatible, BaseURL: srv.URL, Model: "metrics-test"})
+	require.NoError(t, err)
+	e = WithMetrics(e)
+
+	_, err = e.Explain(context.Background(), testRequest)
+	require.NoError(t, err)
+	_, err = e.Stream(context.Background(), testRequest, func(string) error { return nil })
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.LLMRequests.WithLabelValues("metrics-test", "complete", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.LLMRequests.WithLabelValues("metrics-test", "stream", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.LLMUpstreamErrors.WithLabelValues("metrics-test", "503")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(metrics.LLMTokens.WithLabelValues("metrics-test", "prompt")))
+	assert.Equal(t, 4.0, testutil.ToFloat64(metrics.LLMTokens.WithLabelValues("metrics-test", "completion")))
+}
+
+func TestMessagesEmbedVerseText(t *testing.T) {
+	req := testRequest
+	req.Translation = "niv"
+	req.Passage = []models.Verse{
+		{Chapter: 3, Verse: 16, Text: "For God so loved the world"},
+		{Chapter: 3, Verse: 17, Text: "For God did not send his Son"},
+	}
+	req.Before = []models.Verse{{Chapter: 3, Verse: 15, Text: "that everyone who believes"}}
+
+	messages := Messages(req)
+	require.Len(t, messages, 2)
+	prompt := messages[1].Content
+	assert.Contains(t, prompt, "Passage (NIV):\n3:16 For God so loved the world\n3:17 For God did not send his Son\n")
+	assert.Contains(t, prompt, "for context only")
+	assert.Contains(t, prompt, "3:15 that everyone who believes")
+	assert.Less(t, strings.Index(prompt, "3:17"), strings.Index(prompt, "3:15"))
+}
+
+func TestAgeBucket(t *testing.T) {
+	assert.Equal(t, AgeBucket(18), AgeBucket(25))
+	assert.NotEqual(t, AgeBucket(25), AgeBucket(26))
+	assert.Equal(t, 10, AgeBucket(5))
+	assert.Equal(t, 70, AgeBucket(90))
+}
@@ -0,0 +1,50 @@
+package explain
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Fake returns a deterministic explanation without any network access, so
+// the server and its tests can run offline
+type Fake struct{}
+
+func (Fake) Model() string {
+	return "fake"
+}
+
+func (f Fake) Explain(ctx context.Context, req Request) (*Result, error) {
+	return f.Stream(ctx, req, func(string) error { return nil })
+}
+
+func (f Fake) Stream(ctx context.Context, req Request, onDelta func(string) error) (*Result, error) {
+	text := fmt.Sprintf("%s %d:%d-%d explained for a reader aged %d with belief %d/5.",
+		req.Book, req.Chapter, req.StartVerse, req.EndVerse, req.Age, req.Belief)
+
+	words := strings.SplitAfter(text, " ")
+	for _, word := range words {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onDelta(word); err != nil {
+			return nil, err
+		}
+	}
+
+	prompt := 0
+	for _, m := range Messages(req) {
+		prompt += len(strings.Fields(m.Content))
+	}
+	return &Result{
+		Text:         text,
+		FinishReason: "stop",
+		Model:        f.Model(),
+		Usage: &dto.TokenUsage{
+			PromptTokens:     prompt,
+			CompletionTokens: len(words),
+			TotalTokens:      prompt + len(words),
+		},
+	}, nil
+}
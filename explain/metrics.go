@@ -0,0 +1,57 @@
+package explain
+
+import (
+	"bible_reading_backend_nkv/metrics"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// WithMetrics records the calls made through e: their count, latency,
+// upstream error codes and token usage, see metrics.LLMRequests
+func WithMetrics(e Explainer) Explainer {
+	return instrumented{Explainer: e}
+}
+
+type instrumented struct {
+	Explainer
+}
+
+func (i instrumented) Explain(ctx context.Context, req Request) (*Result, error) {
+	started := time.Now()
+	result, err := i.Explainer.Explain(ctx, req)
+	i.observe("complete", started, result, err)
+	return result, err
+}
+
+func (i instrumented) Stream(ctx context.Context, req Request, onDelta func(string) error) (*Result, error) {
+	started := time.Now()
+	result, err := i.Explainer.Stream(ctx, req, onDelta)
+	i.observe("stream", started, result, err)
+	return result, err
+}
+
+func (i instrumented) observe(mode string, started time.Time, result *Result, err error) {
+	model := i.Model()
+	metrics.LLMRequestDuration.WithLabelValues(model, mode).Observe(time.Since(started).Seconds())
+
+	status := "ok"
+	var upstream *UpstreamError
+	switch {
+	// A reader who leaves mid-stream is not a provider failure
+	case errors.Is(err, context.Canceled):
+		status = "canceled"
+	case errors.As(err, &upstream):
+		status = "error"
+		metrics.LLMUpstreamErrors.WithLabelValues(model, strconv.Itoa(upstream.StatusCode)).Inc()
+	case err != nil:
+		status = "error"
+	}
+	metrics.LLMRequests.WithLabelValues(model, mode, status).Inc()
+
+	if result != nil && result.Usage != nil {
+		metrics.LLMTokens.WithLabelValues(model, "prompt").Add(float64(result.Usage.PromptTokens))
+		metrics.LLMTokens.WithLabelValues(model, "completion").Add(float64(result.Usage.CompletionTokens))
+	}
+}
@@ -0,0 +1,225 @@
+package explain
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/logging"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	openAIBaseURL      = "https://api.openai.com/v1"
+	defaultOpenAIModel = "gpt-4o-mini"
+
+	// streamTimeout bounds a whole streamed completion, since the response
+	// body stays open for as long as the model is generating
+	streamTimeout = 2 * time.Minute
+)
+
+// OpenAI talks to the chat-completions API of OpenAI or of any server that
+// implements it, such as llama.cpp or Ollama
+type OpenAI struct {
+	baseURL   string
+	apiKey    string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+// NewOpenAI configures the hosted OpenAI API, which requires an sk- key
+func NewOpenAI(cfg Config) (*OpenAI, error) {
+	if cfg.APIKey == "" {
+		return nil, &ConfigError{Message: "OpenAI API key not configured"}
+	}
+	if !strings.HasPrefix(cfg.APIKey, "sk-") {
+		return nil, &ConfigError{Message: "Invalid API key format"}
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = openAIBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaultOpenAIModel
+	}
+	return newOpenAI(cfg), nil
+}
+
+// NewOpenAICompatible configures a self-hosted OpenAI-compatible server.
+// LLM_BASE_URL and LLM_MODEL are required; the API key is optional.
+func NewOpenAICompatible(cfg Config) (*OpenAI, error) {
+	if cfg.BaseURL == "" {
+		return nil, &ConfigError{Message: "LLM base URL not configured"}
+	}
+	if cfg.Model == "" {
+		return nil, &ConfigError{Message: "LLM model not configured"}
+	}
+	return newOpenAI(cfg), nil
+}
+
+func newOpenAI(cfg Config) *OpenAI {
+	return &OpenAI{
+		baseURL:   strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:    cfg.APIKey,
+		model:     cfg.Model,
+		maxTokens: cfg.MaxTokens,
+		client:    &http.Client{Timeout: cfg.Timeout, Transport: tracedTransport()},
+	}
+}
+
+// tracedTransport traces each request as a child of the span in its
+// context and passes the trace on in the traceparent header
+func tracedTransport() http.RoundTripper {
+	return otelhttp.NewTransport(http.DefaultTransport,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+}
+
+func (o *OpenAI) Model() string {
+	return o.model
+}
+
+func (o *OpenAI) Explain(ctx context.Context, req Request) (*Result, error) {
+	resp, err := o.post(ctx, o.client, dto.OpenAIRequest{
+		Model:     o.model,
+		Messages:  Messages(req),
+		MaxTokens: o.maxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var aiResp dto.OpenAIResponse
+	if err := json.Unmarshal(respBody, &aiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if len(aiResp.Choices) == 0 {
+		return nil, errors.New("empty response")
+	}
+
+	return &Result{
+		Text:         aiResp.Choices[0].Message.Content,
+		FinishReason: aiResp.Choices[0].FinishReason,
+		Model:        o.model,
+		Usage:        aiResp.Usage,
+	}, nil
+}
+
+func (o *OpenAI) Stream(ctx context.Context, req Request, onDelta func(string) error) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamTimeout)
+	defer cancel()
+
+	// The per-request timeout would cut long streams short; ctx bounds them instead
+	resp, err := o.post(ctx, &http.Client{Transport: o.client.Transport}, dto.OpenAIRequest{
+		Model:         o.model,
+		Messages:      Messages(req),
+		MaxTokens:     o.maxTokens,
+		Stream:        true,
+		StreamOptions: &dto.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Result{Model: o.model}
+	var text strings.Builder
+	done := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk dto.OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("parsing stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			text.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading stream: %w", err)
+	}
+	// A connection closed early ends the body cleanly, leaving a partial text
+	if !done {
+		return nil, errors.New("stream ended before [DONE]")
+	}
+
+	result.Text = text.String()
+	return result, nil
+}
+
+// post sends a chat-completions request and checks the status code; the
+// caller must close the response body
+func (o *OpenAI) post(ctx context.Context, client *http.Client, body dto.OpenAIRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	reqHTTP, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	reqHTTP.Header.Set("Content-Type", "application/json")
+	if body.Stream {
+		reqHTTP.Header.Set("Accept", "text/event-stream")
+	}
+	if o.apiKey != "" {
+		reqHTTP.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	// Tie the provider's logs to ours: OpenAI records X-Client-Request-Id,
+	// proxies and self-hosted servers commonly log X-Request-ID
+	if id := logging.RequestID(ctx); id != "" {
+		reqHTTP.Header.Set("X-Client-Request-Id", id)
+		reqHTTP.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := client.Do(reqHTTP)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &UpstreamError{StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
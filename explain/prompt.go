@@ -0,0 +1,74 @@
+package explain
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"fmt"
+	"strings"
+)
+
+// PromptVersion identifies the wording of Messages. Bump it whenever the
+// prompt changes so cached explanations written for the old prompt are no
+// longer served.
+const PromptVersion = 2
+
+// ageBuckets groups readers into audiences; the prompt is written for the
+// representative age of the reader's bucket, so everyone in it shares cached
+// explanations
+var ageBuckets = []struct {
+	maxAge         int
+	representative int
+}{
+	{12, 10},
+	{17, 15},
+	{25, 21},
+	{39, 32},
+	{59, 50},
+}
+
+// AgeBucket returns the representative age of the bucket age falls into
+func AgeBucket(age int) int {
+	for _, b := range ageBuckets {
+		if age <= b.maxAge {
+			return b.representative
+		}
+	}
+	return 70
+}
+
+const systemPrompt = "You are a helpful assistant that explains Bible verses clearly and simply. " +
+	"Base your explanation only on the passage text you are given."
+
+// Messages builds the chat prompt for a request. The verse text is embedded so
+// the model explains the requested translation rather than recalling one.
+func Messages(req Request) []dto.ChatMessage {
+	var b strings.Builder
+	fmt.Fprintf(&b,
+		"Context: Book %s, Chapter %d, Verses %d-%d, Age %d, Belief %d/5. "+
+			"Use age and belief only to adjust tone and depth. "+
+			"Do not mention them in the response. "+
+			"Give a clear summary and explain the verses in a simple, relevant way.",
+		req.Book, req.Chapter, req.StartVerse, req.EndVerse, req.Age, req.Belief,
+	)
+
+	if len(req.Passage) > 0 {
+		fmt.Fprintf(&b, "\n\nPassage (%s):\n", strings.ToUpper(req.Translation))
+		writeVerses(&b, req.Passage)
+	}
+	if len(req.Before) > 0 || len(req.After) > 0 {
+		b.WriteString("\nSurrounding verses, for context only; do not explain them:\n")
+		writeVerses(&b, req.Before)
+		writeVerses(&b, req.After)
+	}
+
+	return []dto.ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+func writeVerses(b *strings.Builder, verses []models.Verse) {
+	for _, v := range verses {
+		fmt.Fprintf(b, "%d:%d %s\n", v.Chapter, v.Verse, v.Text)
+	}
+}
@@ -0,0 +1,172 @@
+// Package importer parses OSIS, USFM and Zefania Bible texts into verse rows
+// for the verses table.
+package importer
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/models"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatOSIS    Format = "osis"
+	FormatUSFM    Format = "usfm"
+	FormatZefania Format = "zefania"
+)
+
+// Verse is a single parsed verse before it is attached to a translation
+type Verse struct {
+	BookID  int
+	Chapter int
+	Verse   int
+	Text    string
+	Line    int
+}
+
+// Ref returns the verse reference in "Book C:V" form
+func (v Verse) Ref() string {
+	name := fmt.Sprintf("book %d", v.BookID)
+	if book, ok := canon.ByID(v.BookID); ok {
+		name = book.Name
+	}
+	return fmt.Sprintf("%s %d:%d", name, v.Chapter, v.Verse)
+}
+
+// Issue describes a verse that was skipped during parsing or validation
+type Issue struct {
+	Line   int
+	Ref    string
+	Reason string
+}
+
+func (i Issue) String() string {
+	if i.Ref == "" {
+		return fmt.Sprintf("line %d: %s", i.Line, i.Reason)
+	}
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Ref, i.Reason)
+}
+
+// ParseFormat converts a -format flag value into a Format
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatOSIS, FormatUSFM, FormatZefania:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected osis, usfm or zefania)", s)
+}
+
+// DetectFormat guesses the format from the file extension and, for XML
+// files, from the root element found in the first bytes of the file.
+func DetectFormat(path string, head []byte) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".usfm", ".sfm":
+		return FormatUSFM, nil
+	}
+
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	switch {
+	case bytes.Contains(head, []byte("<osis")):
+		return FormatOSIS, nil
+	case bytes.Contains(bytes.ToUpper(head), []byte("<XMLBIBLE")):
+		return FormatZefania, nil
+	case bytes.Contains(head, []byte(`\id `)):
+		return FormatUSFM, nil
+	}
+	return "", fmt.Errorf("could not detect format of %s", path)
+}
+
+// Parse reads a text in the given format. Verses that cannot be mapped to
+// the canon are reported as issues; a malformed document is an error.
+func Parse(format Format, r io.Reader) ([]Verse, []Issue, error) {
+	switch format {
+	case FormatOSIS:
+		return parseOSIS(r)
+	case FormatUSFM:
+		return parseUSFM(r)
+	case FormatZefania:
+		return parseZefania(r)
+	}
+	return nil, nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Validate drops verses that are empty, duplicated or out of order. Books
+// must be contiguous, and chapters and verses must increase within them.
+func Validate(verses []Verse) ([]Verse, []Issue) {
+	var (
+		valid     []Verse
+		issues    []Issue
+		seenBooks = map[int]bool{}
+		last      *Verse
+	)
+
+	for i := range verses {
+		v := verses[i]
+		skip := func(reason string) {
+			issues = append(issues, Issue{Line: v.Line, Ref: v.Ref(), Reason: reason})
+		}
+
+		switch {
+		case v.Chapter < 1 || v.Verse < 1:
+			skip("chapter and verse must be positive")
+			continue
+		case v.Text == "":
+			skip("empty verse text")
+			continue
+		}
+
+		if last == nil || v.BookID != last.BookID {
+			if seenBooks[v.BookID] {
+				skip("book appears more than once")
+				continue
+			}
+			seenBooks[v.BookID] = true
+		} else {
+			switch {
+			case v.Chapter < last.Chapter:
+				skip(fmt.Sprintf("chapter out of order after %s", last.Ref()))
+				continue
+			case v.Chapter == last.Chapter && v.Verse == last.Verse:
+				skip("duplicate verse")
+				continue
+			case v.Chapter == last.Chapter && v.Verse < last.Verse:
+				skip(fmt.Sprintf("verse out of order after %s", last.Ref()))
+				continue
+			}
+		}
+
+		valid = append(valid, v)
+		last = &valid[len(valid)-1]
+	}
+
+	return valid, issues
+}
+
+// ToModels attaches parsed verses to a translation
+func ToModels(translationID string, verses []Verse) []models.Verse {
+	rows := make([]models.Verse, 0, len(verses))
+	for _, v := range verses {
+		book, _ := canon.ByID(v.BookID)
+		rows = append(rows, models.Verse{
+			TranslationID: translationID,
+			BookID:        v.BookID,
+			Book:          book.Name,
+			Chapter:       v.Chapter,
+			Verse:         v.Verse,
+			Text:          v.Text,
+		})
+	}
+	return rows
+}
+
+// normalizeSpace collapses runs of whitespace into single spaces
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
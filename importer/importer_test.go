@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOSIS(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<osis xmlns="http://www.bibletechnologies.net/2003/OSIS/namespace">
+<osisText osisIDWork="KJV">
+<div type="book" osisID="Gen">
+<chapter osisID="Gen.1">
+<verse osisID="Gen.1.1">In the beginning God created the heaven and the earth.</verse>
+<verse osisID="Gen.1.2">And the earth was without form<note>Or, empty</note>, and void.</verse>
+</chapter>
+</div>
+<div type="book" osisID="John">
+<chapter sID="John.3"/>
+<verse sID="John.3.16" osisID="John.3.16"/>For God so loved the world<verse eID="John.3.16"/>
+<verse sID="Xyz.1.1" osisID="Xyz.1.1"/>Unknown<verse eID="Xyz.1.1"/>
+<chapter eID="John.3"/>
+</div>
+</osisText>
+</osis>`
+
+	verses, issues, err := Parse(FormatOSIS, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	require.Len(t, verses, 3)
+	assert.Equal(t, Verse{BookID: 1, Chapter: 1, Verse: 1, Text: "In the beginning God created the heaven and the earth.", Line: 6}, verses[0])
+	assert.Equal(t, "And the earth was without form, and void.", verses[1].Text)
+	assert.Equal(t, 43, verses[2].BookID)
+	assert.Equal(t, "For God so loved the world", verses[2].Text)
+
+	require.Len(t, issues, 1)
+	assert.Equal(t, "unknown OSIS book", issues[0].Reason)
+}
+
+func TestParseUSFM(t *testing.T) {
+	doc := `\id JHN English
+\h John
+\mt1 The Gospel of John
+\c 3
+\s1 Jesus and Nicodemus
+\p
+\v 16 For God so \add loved\add* the world,\f + \fr 3:16 \ft Or only begotten\f*
+\q1 that he gave his \w one|strong="G3439"\w* Son,
+\v 17 For God did not send his Son
+into the world to condemn the world.
+\s1 A heading between verses
+\v x Broken
+`
+
+	verses, issues, err := Parse(FormatUSFM, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	require.Len(t, verses, 2)
+	assert.Equal(t, 43, verses[0].BookID)
+	assert.Equal(t, 3, verses[0].Chapter)
+	assert.Equal(t, 16, verses[0].Verse)
+	assert.Equal(t, "For God so loved the world, that he gave his one Son,", verses[0].Text)
+	assert.Equal(t, "For God did not send his Son into the world to condemn the world.", verses[1].Text)
+
+	require.Len(t, issues, 1)
+	assert.Equal(t, 12, issues[0].Line)
+}
+
+func TestParseZefania(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8"?>
+<XMLBIBLE biblename="Test">
+  <BIBLEBOOK bnumber="19" bname="Psalms">
+    <CHAPTER cnumber="23">
+      <VERS vnumber="1">The LORD is my shepherd; <NOTE>Heb. YHWH</NOTE>I shall not want.</VERS>
+      <VERS vnumber="2">He maketh me to lie down in green pastures.</VERS>
+    </CHAPTER>
+  </BIBLEBOOK>
+  <BIBLEBOOK bnumber="99" bname="Unknown">
+    <CHAPTER cnumber="1"><VERS vnumber="1">Skipped</VERS></CHAPTER>
+  </BIBLEBOOK>
+</XMLBIBLE>`
+
+	verses, issues, err := Parse(FormatZefania, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	require.Len(t, verses, 2)
+	assert.Equal(t, "The LORD is my shepherd; I shall not want.", verses[0].Text)
+	assert.Equal(t, Verse{BookID: 19, Chapter: 23, Verse: 2, Text: "He maketh me to lie down in green pastures.", Line: 6}, verses[1])
+	require.Len(t, issues, 1)
+}
+
+func TestParse_MalformedXML(t *testing.T) {
+	_, _, err := Parse(FormatZefania, strings.NewReader(`<XMLBIBLE><BIBLEBOOK bnumber="1"><CHAPTER`))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	verses := []Verse{
+		{BookID: 1, Chapter: 1, Verse: 1, Text: "a"},
+		{BookID: 1, Chapter: 1, Verse: 2, Text: "b"},
+		{BookID: 1, Chapter: 1, Verse: 2, Text: "duplicate"},
+		{BookID: 1, Chapter: 1, Verse: 1, Text: "backwards"},
+		{BookID: 1, Chapter: 2, Verse: 1, Text: ""},
+		{BookID: 1, Chapter: 2, Verse: 1, Text: "c"},
+		{BookID: 2, Chapter: 1, Verse: 1, Text: "d"},
+		{BookID: 1, Chapter: 3, Verse: 1, Text: "book repeated"},
+		{BookID: 2, Chapter: 0, Verse: 1, Text: "no chapter"},
+	}
+
+	valid, issues := Validate(verses)
+
+	texts := []string{}
+	for _, v := range valid {
+		texts = append(texts, v.Text)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, texts)
+
+	reasons := []string{}
+	for _, issue := range issues {
+		reasons = append(reasons, issue.Reason)
+	}
+	assert.Equal(t, []string{
+		"duplicate verse",
+		"verse out of order after Genesis 1:2",
+		"empty verse text",
+		"book appears more than once",
+		"chapter and verse must be positive",
+	}, reasons)
+}
+
+func TestDetectFormat(t *testing.T) {
+	f, err := DetectFormat("john.usfm", nil)
+	require.NoError(t, err)
+	assert.Equal(t, FormatUSFM, f)
+
+	f, err = DetectFormat("kjv.xml", []byte(`<?xml version="1.0"?><osis xmlns="">`))
+	require.NoError(t, err)
+	assert.Equal(t, FormatOSIS, f)
+
+	f, err = DetectFormat("kjv.xml", []byte(`<?xml version="1.0"?><XMLBIBLE>`))
+	require.NoError(t, err)
+	assert.Equal(t, FormatZefania, f)
+
+	_, err = DetectFormat("kjv.txt", []byte("plain text"))
+	assert.Error(t, err)
+}
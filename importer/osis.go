@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// osisParser collects verse text from an OSIS document. Both container
+// verses (<verse osisID="..">text</verse>) and milestones
+// (<verse sID=".."/>text<verse eID=".."/>) are supported.
+type osisParser struct {
+	dec       *xml.Decoder
+	verses    []Verse
+	issues    []Issue
+	current   *Verse
+	text      strings.Builder
+	noteDepth int
+}
+
+func parseOSIS(r io.Reader) ([]Verse, []Issue, error) {
+	p := &osisParser{dec: xml.NewDecoder(r)}
+	p.dec.Strict = false
+
+	// A milestone <verse sID/> or <verse eID/> is reported as a start and an
+	// end element; the end element must not close the verse.
+	skipEnd := false
+
+	for {
+		tok, err := p.dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid OSIS XML at line %d: %w", p.line(), err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "verse":
+				if attr(t, "eID") != "" {
+					p.finish()
+					skipEnd = true
+					continue
+				}
+				p.finish()
+				id := attr(t, "osisID")
+				if id == "" {
+					id = attr(t, "sID")
+				}
+				p.start(id)
+				skipEnd = attr(t, "sID") != ""
+			case "note":
+				p.noteDepth++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "verse":
+				if skipEnd {
+					skipEnd = false
+					continue
+				}
+				p.finish()
+			case "note":
+				p.noteDepth--
+			}
+		case xml.CharData:
+			if p.current != nil && p.noteDepth == 0 {
+				p.text.Write(t)
+			}
+		}
+	}
+	p.finish()
+
+	return p.verses, p.issues, nil
+}
+
+// start opens a verse from an osisID such as "Gen.1.1" or "Bible:Gen.1.1"
+func (p *osisParser) start(osisID string) {
+	line := p.line()
+
+	// Merged verses list several IDs; the text is stored under the first one
+	if fields := strings.Fields(osisID); len(fields) > 0 {
+		osisID = fields[0]
+	}
+	if i := strings.Index(osisID, ":"); i >= 0 {
+		osisID = osisID[i+1:]
+	}
+
+	parts := strings.Split(osisID, ".")
+	if len(parts) != 3 {
+		p.issues = append(p.issues, Issue{Line: line, Ref: osisID, Reason: "malformed osisID"})
+		return
+	}
+
+	book, ok := canon.ByOSIS(parts[0])
+	if !ok {
+		p.issues = append(p.issues, Issue{Line: line, Ref: osisID, Reason: "unknown OSIS book"})
+		return
+	}
+	chapter, errC := strconv.Atoi(parts[1])
+	verse, errV := strconv.Atoi(parts[2])
+	if errC != nil || errV != nil {
+		p.issues = append(p.issues, Issue{Line: line, Ref: osisID, Reason: "non-numeric chapter or verse"})
+		return
+	}
+
+	p.current = &Verse{BookID: book.ID, Chapter: chapter, Verse: verse, Line: line}
+	p.text.Reset()
+}
+
+func (p *osisParser) finish() {
+	if p.current == nil {
+		return
+	}
+	p.current.Text = normalizeSpace(p.text.String())
+	p.verses = append(p.verses, *p.current)
+	p.current = nil
+	p.text.Reset()
+}
+
+func (p *osisParser) line() int {
+	line, _ := p.dec.InputPos()
+	return line
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Footnotes, endnotes and cross references are not part of the verse text
+	usfmNoteRe   = regexp.MustCompile(`\\(f|fe|x|ef|ex)\s.*?\\(f|fe|x|ef|ex)\*`)
+	usfmMarkerRe = regexp.MustCompile(`\\(\+?[A-Za-z]+[0-9]*)(\*?)`)
+)
+
+// usfmSkippedMarkers start text that is not part of any verse: headings,
+// titles, introductions and book metadata.
+var usfmSkippedMarkers = map[string]bool{
+	"h": true, "toc": true, "toc1": true, "toc2": true, "toc3": true,
+	"mt": true, "mt1": true, "mt2": true, "mt3": true, "mte": true,
+	"ms": true, "ms1": true, "ms2": true, "mr": true,
+	"s": true, "s1": true, "s2": true, "s3": true, "s4": true, "sr": true, "r": true,
+	"d": true, "sp": true, "cl": true, "cp": true, "ca": true, "va": true,
+	"rem": true, "ide": true, "sts": true, "usfm": true,
+	"imt": true, "imt1": true, "imt2": true, "is": true, "is1": true, "is2": true,
+	"ip": true, "ipi": true, "im": true, "io": true, "io1": true, "io2": true, "ie": true,
+}
+
+// parseUSFM reads a USFM file. Verse text may continue over paragraph and
+// poetry markers; character markers are stripped and their text kept.
+func parseUSFM(r io.Reader) ([]Verse, []Issue, error) {
+	var (
+		verses  []Verse
+		issues  []Issue
+		bookID  int
+		bookRef string
+		chapter int
+		current *Verse
+		text    strings.Builder
+		heading bool
+	)
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		current.Text = normalizeSpace(text.String())
+		verses = append(verses, *current)
+		current = nil
+		text.Reset()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		content := usfmNoteRe.ReplaceAllString(scanner.Text(), "")
+
+		matches := usfmMarkerRe.FindAllStringSubmatchIndex(content, -1)
+		if len(matches) == 0 {
+			// A line without markers continues the previous one
+			if current != nil && !heading {
+				text.WriteString(" " + content)
+			}
+			continue
+		}
+		if current != nil && !heading {
+			text.WriteString(" " + content[:matches[0][0]])
+		}
+
+		for i, m := range matches {
+			marker := strings.TrimPrefix(content[m[2]:m[3]], "+")
+			closing := m[5] > m[4]
+			end := len(content)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			segment := content[m[1]:end]
+			if !closing {
+				heading = usfmSkippedMarkers[marker]
+			}
+
+			if closing {
+				if current != nil && !heading {
+					text.WriteString(segment)
+				}
+				continue
+			}
+
+			switch {
+			case marker == "id":
+				finish()
+				code := strings.Fields(segment)
+				bookID, bookRef, chapter = 0, "", 0
+				if len(code) == 0 {
+					issues = append(issues, Issue{Line: line, Reason: `\id without a book code`})
+					continue
+				}
+				bookRef = code[0]
+				book, ok := canon.ByUSFM(bookRef)
+				if !ok {
+					issues = append(issues, Issue{Line: line, Ref: bookRef, Reason: "unknown USFM book"})
+					continue
+				}
+				bookID = book.ID
+			case marker == "c":
+				finish()
+				fields := strings.Fields(segment)
+				chapter = 0
+				if len(fields) > 0 {
+					chapter, _ = strconv.Atoi(fields[0])
+				}
+				if chapter == 0 {
+					issues = append(issues, Issue{Line: line, Ref: bookRef, Reason: `malformed \c marker`})
+				}
+			case marker == "v":
+				finish()
+				if bookID == 0 {
+					continue
+				}
+				segment = strings.TrimLeft(segment, " ")
+				number, rest, _ := strings.Cut(segment, " ")
+				verse, err := strconv.Atoi(strings.SplitN(number, "-", 2)[0])
+				if err != nil || chapter == 0 {
+					issues = append(issues, Issue{Line: line, Ref: fmt.Sprintf("%s %d:%s", bookRef, chapter, number), Reason: `malformed \v marker`})
+					continue
+				}
+				current = &Verse{BookID: bookID, Chapter: chapter, Verse: verse, Line: line}
+				text.WriteString(rest)
+			case usfmSkippedMarkers[marker]:
+				// Headings end the current verse's text until the next marker
+			default:
+				if current != nil {
+					// Attributes such as \w word|strong="H430"\w* are dropped
+					if j := strings.Index(segment, "|"); j >= 0 {
+						segment = segment[:j]
+					}
+					text.WriteString(segment)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	finish()
+
+	return verses, issues, nil
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseZefania reads a Zefania XML document:
+// <XMLBIBLE><BIBLEBOOK bnumber="1"><CHAPTER cnumber="1"><VERS vnumber="1">
+func parseZefania(r io.Reader) ([]Verse, []Issue, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+
+	var (
+		verses    []Verse
+		issues    []Issue
+		bookID    int
+		bookRef   string
+		chapter   int
+		current   *Verse
+		text      strings.Builder
+		noteDepth int
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		line, _ := dec.InputPos()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid Zefania XML at line %d: %w", line, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch strings.ToUpper(t.Name.Local) {
+			case "BIBLEBOOK":
+				bookID, bookRef = 0, attr(t, "bname")
+				n, err := strconv.Atoi(attr(t, "bnumber"))
+				if _, ok := canon.ByID(n); err != nil || !ok {
+					issues = append(issues, Issue{Line: line, Ref: bookRef, Reason: fmt.Sprintf("unknown book number %q", attr(t, "bnumber"))})
+					continue
+				}
+				bookID = n
+			case "CHAPTER":
+				chapter, _ = strconv.Atoi(attr(t, "cnumber"))
+			case "VERS":
+				if bookID == 0 {
+					continue
+				}
+				verse, err := strconv.Atoi(attr(t, "vnumber"))
+				if err != nil {
+					issues = append(issues, Issue{Line: line, Ref: fmt.Sprintf("%s %d:%s", bookRef, chapter, attr(t, "vnumber")), Reason: "non-numeric verse number"})
+					continue
+				}
+				current = &Verse{BookID: bookID, Chapter: chapter, Verse: verse, Line: line}
+				text.Reset()
+			case "NOTE":
+				noteDepth++
+			}
+		case xml.EndElement:
+			switch strings.ToUpper(t.Name.Local) {
+			case "VERS":
+				if current != nil {
+					current.Text = normalizeSpace(text.String())
+					verses = append(verses, *current)
+					current = nil
+				}
+			case "NOTE":
+				noteDepth--
+			}
+		case xml.CharData:
+			if current != nil && noteDepth == 0 {
+				text.Write(t)
+			}
+		}
+	}
+
+	return verses, issues, nil
+}
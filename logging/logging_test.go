@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestIDIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Config{Format: FormatJSON}).With("component", "test")
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	logger.InfoContext(ctx, "hello", "n", 1)
+	logger.Info("no request")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first, second map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, "abc123", first["request_id"])
+	assert.Equal(t, "test", first["component"])
+	assert.EqualValues(t, 1, first["n"])
+	assert.NotContains(t, second, "request_id")
+}
+
+func TestTraceIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Config{Format: FormatJSON})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	logger.InfoContext(ctx, "traced")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", line["span_id"])
+}
+
+func TestLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	logger := New(&buf, Config{Format: FormatText, Level: level})
+	logger.Info("dropped")
+	logger.Warn("kept")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "msg=kept")
+}
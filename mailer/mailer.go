@@ -0,0 +1,93 @@
+// Package mailer sends transactional email such as password reset links.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by every mail transport
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+const (
+	DriverSMTP   = "smtp"
+	DriverLog    = "log"
+	DriverMemory = "memory"
+)
+
+// Config selects and configures a transport
+type Config struct {
+	Driver   string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// New builds the transport described by cfg
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTP(cfg)
+	case DriverLog, "":
+		return Log{}, nil
+	case DriverMemory:
+		return &Memory{}, nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+}
+
+// Log writes messages to the default slog logger instead of sending them. It
+// is meant for development, where the links in the messages can be copied
+// from the log.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// Memory keeps sent messages so tests can inspect them
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns every message sent so far
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the most recent message sent to the address to
+func (m *Memory) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if strings.EqualFold(m.messages[i].To, to) {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP accepts one message and sends the DATA section on the returned channel
+func fakeSMTP(t *testing.T) (host string, port int, data <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					b.WriteString(line)
+				}
+				out <- b.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, out
+}
+
+func TestSMTPSend(t *testing.T) {
+	host, port, data := fakeSMTP(t)
+
+	m, err := New(Config{Driver: DriverSMTP, Host: host, Port: port, From: "bible@example.com"})
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{
+		To:      "reader@example.com",
+		Subject: "Reset your password",
+		Body:    "Open this link:\nhttps://example.com/reset?token=abc",
+	})
+	require.NoError(t, err)
+
+	msg := <-data
+	assert.Contains(t, msg, "From: bible@example.com\r\n")
+	assert.Contains(t, msg, "To: reader@example.com\r\n")
+	assert.Contains(t, msg, "Subject: Reset your password\r\n")
+	assert.Contains(t, msg, "\r\n\r\nOpen this link:\r\nhttps://example.com/reset?token=abc\r\n")
+}
+
+func TestSMTPRejectsHeaderInjection(t *testing.T) {
+	m, err := NewSMTP(Config{Host: "127.0.0.1", Port: 1})
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "hi"})
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Driver: DriverSMTP})
+	assert.Error(t, err, "SMTP requires a host")
+
+	_, err = New(Config{Driver: "pigeon"})
+	assert.Error(t, err)
+
+	m, err := New(Config{Driver: DriverMemory})
+	require.NoError(t, err)
+	memory := m.(*Memory)
+	require.NoError(t, memory.Send(context.Background(), Message{To: "A@example.com", Subject: "first"}))
+	require.NoError(t, memory.Send(context.Background(), Message{To: "a@example.com", Subject: "second"}))
+
+	last, ok := memory.Last("a@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "second", last.Subject)
+	assert.Len(t, memory.Messages(), 2)
+}
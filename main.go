@@ -1,48 +1,123 @@
 package main
 
 import (
+	"bible_reading_backend_nkv/config"
 	"bible_reading_backend_nkv/database"
-	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/metrics"
 	"bible_reading_backend_nkv/server"
+	"bible_reading_backend_nkv/tracing"
+	"context"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 	_ "time/tzdata"
 )
 
 func main() {
-	// Validate JWT_SECRET is set
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Println("WARNING: JWT_SECRET not set, using default secret. This should be changed in production!")
+	// Load and validate configuration; missing required values stop startup
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	// Everything is logged as structured lines to stdout, including what
+	// other packages write with the standard log package
+	logger := logging.New(os.Stdout, cfg.Log.LoggerConfig())
+	slog.SetDefault(logger)
+
+	// Spans of requests, queries and LLM calls are exported as configured
+	shutdownTracing, err := tracing.Setup(context.Background(), os.Stdout, cfg.Tracing.TracerConfig())
+	if err != nil {
+		fatal(logger, "failed to set up tracing", err)
 	}
 
 	// Initialize database
-	dbClient, err := database.NewDatabaseClient()
+	dbClient, err := database.NewDatabaseClient(cfg.Database, logger)
 	if err != nil {
-		log.Fatalf("failed to initialize Database Client: %v", err)
+		fatal(logger, "failed to initialize database client", err)
 	}
 
-	// Get the underlying GORM DB instance for migrations
 	client, ok := dbClient.(*database.Client)
 	if !ok {
-		log.Fatalf("failed to get database client")
+		fatal(logger, "failed to get database client", nil)
+	}
+	sqlDB, err := client.DB.DB()
+	if err != nil {
+		fatal(logger, "failed to get database connection pool", err)
+	}
+	if err := metrics.RegisterDB(client.DB.Dialector.Name(), sqlDB); err != nil {
+		fatal(logger, "failed to register database metrics", err)
 	}
 
-	// Auto-migrate database tables
-	log.Println("Running database migrations...")
-	if err := client.DB.AutoMigrate(
-		&models.User{},
-		&models.UserFavoriteVerse{},
-		&models.UserHighlightedVerse{},
-		&models.UserLastRead{},
-	); err != nil {
-		log.Fatalf("failed to migrate database: %s", err)
+	// "server migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(client, os.Args[2:]); err != nil {
+			fatal(logger, "migrate failed", err)
+		}
+		return
 	}
-	log.Println("Database migrations completed successfully")
 
-	// Create and start server
-	serv := server.NewEchoServer(dbClient)
-	if err := serv.Start(); err != nil {
-		log.Fatal(err.Error())
+	if cfg.Database.AutoMigrate {
+		logger.Info("running database migrations")
+		if err := migrateUp(client); err != nil {
+			fatal(logger, "failed to migrate database", err)
+		}
+		logger.Info("database migrations completed")
+	}
+	if err := client.SeedTranslations(context.Background()); err != nil {
+		fatal(logger, "failed to seed translations", err)
+	}
+	if deleted, err := client.DeleteExpiredExplanations(context.Background()); err != nil {
+		logger.Warn("failed to remove expired explanations", "error", err)
+	} else if deleted > 0 {
+		logger.Info("removed expired explanations", "count", deleted)
+	}
+
+	// Create and start server; SIGTERM or SIGINT starts a graceful shutdown
+	serv := server.NewEchoServer(cfg, dbClient, logger)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serv.Start()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			fatal(logger, "server stopped", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	// A second signal kills the process straight away
+	stop()
+
+	logger.Info("shutting down, waiting for in-flight requests", "timeout", cfg.Server.ShutdownTimeout())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout())
+	defer cancel()
+	if err := serv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("server shutdown", "error", err)
+	}
+	if err := dbClient.Close(); err != nil {
+		logger.Warn("failed to close database", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("failed to flush traces", "error", err)
+	}
+	logger.Info("server stopped")
+}
+
+// fatal logs msg with err at error level and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	if err != nil {
+		logger.Error(msg, "error", err)
+	} else {
+		logger.Error(msg)
 	}
+	os.Exit(1)
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+// Package metrics holds the Prometheus collectors of the server. HTTP
+// requests, database queries and LLM calls are recorded by the packages that
+// make them; Handler serves everything in Prometheus' text format.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds the server's metrics and the Go runtime and process metrics.
+// It is separate from Prometheus' default registry so that only what is
+// registered here is exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts responses by method, route pattern and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration is the time from receiving a request to
+	// finishing its response, including streamed explanations
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueryDuration is the time GORM spends on each statement. Operation
+	// is create, query, update, delete, row or raw; status is ok or error.
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency by operation, table and status.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "status"})
+
+	// LLMRequests counts explain calls to the LLM provider. Mode is
+	// complete or stream; status is ok or error.
+	LLMRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_requests_total",
+		Help: "LLM explain calls by model, mode and status.",
+	}, []string{"model", "mode", "status"})
+
+	// LLMRequestDuration covers whole calls; for streams that is until the
+	// last token
+	LLMRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "llm_request_duration_seconds",
+		Help:    "LLM explain call latency by model and mode.",
+		Buckets: []float64{.25, .5, 1, 2, 4, 8, 15, 30, 60, 120},
+	}, []string{"model", "mode"})
+
+	// LLMUpstreamErrors counts non-200 responses from the provider by
+	// their status code, e.g. 429 for rate limiting
+	LLMUpstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_upstream_errors_total",
+		Help: "Non-200 responses from the LLM provider by model and status code.",
+	}, []string{"model", "code"})
+
+	// LLMTokens totals the usage reported by the provider. Type is prompt
+	// or completion.
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_tokens_total",
+		Help: "Tokens used by LLM explain calls by model and type.",
+	}, []string{"model", "type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		LLMRequests,
+		LLMRequestDuration,
+		LLMUpstreamErrors,
+		LLMTokens,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db, such as open,
+// in-use and idle connections and the time spent waiting for one, labelled
+// with name
+func RegisterDB(name string, db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics of Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
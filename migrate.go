@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bible_reading_backend_nkv/database"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [-steps N] revert the last N applied migrations (default 1)
+  status          list migrations and when they were applied`
+
+// runMigrate handles the migrate subcommand, so the schema can be managed
+// with the same binary and configuration as the server
+func runMigrate(client *database.Client, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrateUp(client)
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+		migrator, err := client.Migrator()
+		if err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			log.Printf("Reverted migration %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			log.Println("No migrations to revert")
+		}
+		return err
+
+	case "status":
+		migrator, err := client.Migrator()
+		if err != nil {
+			return err
+		}
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+	}
+}
+
+// migrateUp applies every pending migration and logs the ones it applied
+func migrateUp(client *database.Client) error {
+	migrator, err := client.Migrator()
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for name := range dialects {
+		migrations, err := load(files, name)
+		require.NoError(t, err, name)
+		require.NotEmpty(t, migrations, name)
+		for i, m := range migrations {
+			assert.Equal(t, i+1, m.Version, "%s migrations must be numbered without gaps", name)
+			assert.NotEmpty(t, statements(m.Up), m.Name)
+			assert.NotEmpty(t, statements(m.Down), m.Name)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	m, err := New(db, "sqlite")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.migrations))
+	_, err = db.Exec("INSERT INTO translations (id, name, language) VALUES ('kjv', 'King James Version', 'en')")
+	require.NoError(t, err)
+
+	// Running again is a no-op
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	require.NotNil(t, statuses[0].AppliedAt)
+	assert.WithinDuration(t, time.Now(), *statuses[0].AppliedAt, time.Minute)
+
+	reverted, err := m.Down(ctx, 100)
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(m.migrations))
+	assert.Equal(t, m.migrations[len(m.migrations)-1].Version, reverted[0].Version)
+	_, err = db.Exec("SELECT 1 FROM translations")
+	assert.Error(t, err, "the tables should be gone")
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	_, err = New(db, "postgres")
+	assert.Error(t, err)
+}
+
+// TestUpgradeFromAutoMigrate applies the MySQL migrations to a users table as
+// GORM's AutoMigrate created it before email verification, roles and
+// disabling were added. It needs TEST_MYSQL_DSN, naming a scratch database
+// whose tables it drops, e.g. root:secret@tcp(localhost:3306)/migrations_test.
+func TestUpgradeFromAutoMigrate(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := New(db, "mysql")
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = m.Down(ctx, len(m.migrations))
+	require.NoError(t, err)
+	defer m.Down(ctx, len(m.migrations))
+
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS users",
+		`CREATE TABLE users (
+			id bigint AUTO_INCREMENT,
+			email varchar(255) NOT NULL,
+			password varchar(255) NOT NULL,
+			first_name varchar(255) NOT NULL,
+			last_name varchar(255) NOT NULL,
+			age bigint NOT NULL,
+			believer_category bigint NOT NULL,
+			created_at datetime(3) NULL,
+			updated_at datetime(3) NULL,
+			PRIMARY KEY (id),
+			UNIQUE INDEX idx_users_email (email)
+		)`,
+		`INSERT INTO users (email, password, first_name, last_name, age, believer_category, created_at, updated_at)
+			VALUES ('old@example.com', 'x', 'Old', 'User', 30, 3, '2025-01-02 03:04:05', '2025-01-02 03:04:05')`,
+	} {
+		_, err := db.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.migrations))
+
+	// Existing users count as verified, and get the default role
+	var verified, enabled bool
+	var role string
+	err = db.QueryRowContext(ctx, "SELECT email_verified_at = created_at, role, disabled_at IS NULL FROM users WHERE email = 'old@example.com'").
+		Scan(&verified, &role, &enabled)
+	require.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, "user", role)
+	assert.True(t, enabled)
+
+	var indexes int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_role'`).Scan(&indexes)
+	require.NoError(t, err)
+	assert.Equal(t, 1, indexes)
+
+	// Once the columns exist, running 0002 again leaves unverified users alone
+	_, err = db.ExecContext(ctx, `INSERT INTO users (email, password, first_name, last_name, age, believer_category, created_at)
+		VALUES ('new@example.com', 'x', 'New', 'User', 30, 3, NOW(3))`)
+	require.NoError(t, err)
+	_, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	var unverified bool
+	err = db.QueryRowContext(ctx, "SELECT email_verified_at IS NULL FROM users WHERE email = 'new@example.com'").Scan(&unverified)
+	require.NoError(t, err)
+	assert.True(t, unverified)
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/0002_add_notes.up.sql":   {Data: []byte("ALTER TABLE a ADD notes text;")},
+		"db/0002_add_notes.down.sql": {Data: []byte("ALTER TABLE a DROP notes;")},
+		"db/0001_initial.up.sql":     {Data: []byte("CREATE TABLE a (id int);")},
+		"db/0001_initial.down.sql":   {Data: []byte("DROP TABLE a;")},
+	}
+	migrations, err := load(fsys, "db")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "initial", Up: "CREATE TABLE a (id int);", Down: "DROP TABLE a;"}, migrations[0])
+	assert.Equal(t, "add_notes", migrations[1].Name)
+
+	delete(fsys, "db/0002_add_notes.down.sql")
+	_, err = load(fsys, "db")
+	assert.ErrorContains(t, err, "0002_add_notes needs both an up and a down file")
+
+	fsys["db/0002_add_notes.down.sql"] = &fstest.MapFile{Data: []byte("--")}
+	fsys["db/notes.sql"] = &fstest.MapFile{}
+	_, err = load(fsys, "db")
+	assert.ErrorContains(t, err, "unexpected migration file notes.sql")
+}
+
+func TestStatements(t *testing.T) {
+	script := `-- Users
+CREATE TABLE a (
+  id int,
+  note varchar(10) DEFAULT 'a;b'
+);
+
+-- Index
+CREATE INDEX idx_a ON a (id);
+DROP TABLE b`
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n  id int,\n  note varchar(10) DEFAULT 'a;b'\n)",
+		"CREATE INDEX idx_a ON a (id)",
+		"DROP TABLE b",
+	}, statements(script))
+	assert.Empty(t, statements("-- nothing to do\n"))
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, want, parseTime(want))
+	assert.Equal(t, want, parseTime([]byte("2026-01-02 03:04:05")))
+	assert.Equal(t, want, parseTime("2026-01-02T03:04:05Z"))
+	assert.True(t, parseTime(nil).IsZero())
+}
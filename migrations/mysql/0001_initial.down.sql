@@ -0,0 +1,16 @@
+-- The legacy niv table is kept: it may hold the only copy of imported text.
+
+DROP TABLE IF EXISTS llm_usage;
+DROP TABLE IF EXISTS explanations;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS verses;
+DROP TABLE IF EXISTS translations;
+DROP TABLE IF EXISTS login_throttles;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS user_last_read;
+DROP TABLE IF EXISTS user_highlighted_verses;
+DROP TABLE IF EXISTS user_favorite_verses;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,198 @@
+-- Baseline schema. Tables are created only if missing, so databases set up
+-- by earlier releases, which created them with GORM's AutoMigrate, are
+-- adopted as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+  id bigint NOT NULL AUTO_INCREMENT,
+  email varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  first_name varchar(255) NOT NULL,
+  last_name varchar(255) NOT NULL,
+  age bigint NOT NULL,
+  believer_category bigint NOT NULL,
+  email_verified_at datetime(3) NULL,
+  role varchar(20) NOT NULL DEFAULT 'user',
+  disabled_at datetime(3) NULL,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_users_email (email),
+  INDEX idx_users_role (role)
+);
+
+CREATE TABLE IF NOT EXISTS user_favorite_verses (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id bigint NOT NULL,
+  book_id bigint NOT NULL,
+  chapter bigint NOT NULL,
+  verse bigint NOT NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_user_favorite_verses_user_id (user_id),
+  INDEX idx_verse (book_id, chapter, verse),
+  UNIQUE INDEX unique_user_favorite (user_id, book_id, chapter, verse)
+);
+
+CREATE TABLE IF NOT EXISTS user_highlighted_verses (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id bigint NOT NULL,
+  book_id bigint NOT NULL,
+  chapter bigint NOT NULL,
+  verse bigint NOT NULL,
+  note text,
+  color varchar(20) DEFAULT 'yellow',
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_user_highlighted_verses_user_id (user_id),
+  INDEX idx_verse (book_id, chapter, verse),
+  UNIQUE INDEX unique_user_highlight (user_id, book_id, chapter, verse)
+);
+
+CREATE TABLE IF NOT EXISTS user_last_read (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id bigint NOT NULL,
+  book_id bigint NOT NULL,
+  book_name varchar(255) NOT NULL,
+  chapter bigint NOT NULL,
+  verse bigint NOT NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_user_last_read_user_id (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id bigint NOT NULL,
+  family_id varchar(64) NOT NULL,
+  token_hash varchar(64) NOT NULL,
+  user_agent varchar(255),
+  ip varchar(64),
+  expires_at datetime(3) NOT NULL,
+  revoked_at datetime(3) NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_sessions_user_id (user_id),
+  INDEX idx_sessions_family_id (family_id),
+  UNIQUE INDEX idx_sessions_token_hash (token_hash)
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id bigint NOT NULL,
+  token_hash varchar(64) NOT NULL,
+  expires_at datetime(3) NOT NULL,
+  used_at datetime(3) NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_password_resets_user_id (user_id),
+  UNIQUE INDEX idx_password_resets_token_hash (token_hash)
+);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id bigint NOT NULL,
+  secret varchar(64) NOT NULL,
+  enabled_at datetime(3) NULL,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id bigint NOT NULL AUTO_INCREMENT,
+  user_id bigint NOT NULL,
+  code_hash varchar(64) NOT NULL,
+  used_at datetime(3) NULL,
+  created_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_recovery_codes_user_id (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+  throttle_key varchar(320) NOT NULL,
+  failures bigint NOT NULL DEFAULT 0,
+  last_failure_at datetime(3) NOT NULL,
+  locked_until datetime(3) NULL,
+  PRIMARY KEY (throttle_key)
+);
+
+CREATE TABLE IF NOT EXISTS translations (
+  id varchar(32) NOT NULL,
+  name varchar(255) NOT NULL,
+  language varchar(16) NOT NULL,
+  copyright text,
+  versification varchar(32) NOT NULL DEFAULT 'KJV',
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS verses (
+  translation_id varchar(32) NOT NULL,
+  book_id bigint NOT NULL,
+  book varchar(255) NOT NULL,
+  chapter bigint NOT NULL,
+  verse bigint NOT NULL,
+  text varchar(1000) NOT NULL,
+  PRIMARY KEY (translation_id, book_id, chapter, verse)
+);
+
+CREATE TABLE IF NOT EXISTS books (
+  translation_id varchar(32) NOT NULL,
+  id bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  osis varchar(16) NOT NULL,
+  testament varchar(2) NOT NULL,
+  genre varchar(64),
+  canonical_order bigint NOT NULL,
+  aliases text,
+  chapters bigint NOT NULL,
+  verse_counts text,
+  PRIMARY KEY (translation_id, id)
+);
+
+-- Legacy single-translation table. Its rows are copied into verses under the
+-- niv translation on startup; new installs leave it empty.
+CREATE TABLE IF NOT EXISTS niv (
+  book_id bigint NOT NULL,
+  book varchar(255) NOT NULL,
+  chapter bigint NOT NULL,
+  verse bigint NOT NULL,
+  text varchar(1000) NOT NULL,
+  PRIMARY KEY (book_id, chapter, verse)
+);
+
+CREATE TABLE IF NOT EXISTS explanations (
+  id bigint NOT NULL AUTO_INCREMENT,
+  translation_id varchar(32) NOT NULL,
+  book_id bigint NOT NULL,
+  chapter bigint NOT NULL,
+  start_verse bigint NOT NULL,
+  end_verse bigint NOT NULL,
+  age_bucket bigint NOT NULL,
+  belief bigint NOT NULL,
+  prompt_version bigint NOT NULL,
+  model varchar(128) NOT NULL,
+  text text NOT NULL,
+  finish_reason varchar(32),
+  prompt_tokens bigint,
+  completion_tokens bigint,
+  created_at datetime(3) NULL,
+  expires_at datetime(3) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_explanation_key (translation_id, book_id, chapter, start_verse, end_verse, age_bucket, belief, prompt_version, model),
+  INDEX idx_explanations_expires_at (expires_at)
+);
+
+CREATE TABLE IF NOT EXISTS llm_usage (
+  id bigint NOT NULL AUTO_INCREMENT,
+  subject varchar(80) NOT NULL,
+  day varchar(10) NOT NULL,
+  requests bigint NOT NULL DEFAULT 0,
+  prompt_tokens bigint NOT NULL DEFAULT 0,
+  completion_tokens bigint NOT NULL DEFAULT 0,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX idx_llm_usage_subject_day (subject, day)
+);
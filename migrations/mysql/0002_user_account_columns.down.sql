@@ -0,0 +1,3 @@
+-- The columns are part of the 0001 schema, which new databases get from
+-- 0001 itself, so there is nothing to undo.
+DO 0;
@@ -0,0 +1,35 @@
+-- Databases adopted by 0001 keep the users table that GORM's AutoMigrate
+-- created, which predates email verification, roles and disabling. Each
+-- column and index is added only if it is missing, since 0001 creates them
+-- on new databases.
+
+-- Existing users signed up before verification existed; they count as
+-- verified so that EMAIL_VERIFICATION=required does not lock them out.
+SET @missing = (SELECT COUNT(*) = 0 FROM information_schema.columns
+  WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'email_verified_at');
+SET @stmt = IF(@missing, 'ALTER TABLE users ADD COLUMN email_verified_at datetime(3) NULL AFTER believer_category', 'DO 0');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+SET @stmt = IF(@missing, 'UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL', 'DO 0');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+
+SET @missing = (SELECT COUNT(*) = 0 FROM information_schema.columns
+  WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'role');
+SET @stmt = IF(@missing, 'ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT ''user'' AFTER email_verified_at', 'DO 0');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+
+SET @missing = (SELECT COUNT(*) = 0 FROM information_schema.columns
+  WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'disabled_at');
+SET @stmt = IF(@missing, 'ALTER TABLE users ADD COLUMN disabled_at datetime(3) NULL AFTER role', 'DO 0');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+
+SET @missing = (SELECT COUNT(*) = 0 FROM information_schema.statistics
+  WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_users_role');
+SET @stmt = IF(@missing, 'CREATE INDEX idx_users_role ON users (role)', 'DO 0');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+
+DEALLOCATE PREPARE stmt;
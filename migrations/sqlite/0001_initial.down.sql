@@ -0,0 +1,14 @@
+DROP TABLE llm_usage;
+DROP TABLE explanations;
+DROP TABLE books;
+DROP TABLE verses;
+DROP TABLE translations;
+DROP TABLE login_throttles;
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
+DROP TABLE password_resets;
+DROP TABLE sessions;
+DROP TABLE user_last_read;
+DROP TABLE user_highlighted_verses;
+DROP TABLE user_favorite_verses;
+DROP TABLE users;
@@ -0,0 +1,175 @@
+-- Baseline schema, matching mysql/0001_initial.up.sql. SQLite index names
+-- are global to the database, so indexes that share a name in MySQL are
+-- prefixed with their table here.
+
+CREATE TABLE users (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  email varchar(255) NOT NULL COLLATE NOCASE,
+  password varchar(255) NOT NULL,
+  first_name varchar(255) NOT NULL,
+  last_name varchar(255) NOT NULL,
+  age integer NOT NULL,
+  believer_category integer NOT NULL,
+  email_verified_at datetime NULL,
+  role varchar(20) NOT NULL DEFAULT 'user',
+  disabled_at datetime NULL,
+  created_at datetime NULL,
+  updated_at datetime NULL
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+CREATE INDEX idx_users_role ON users (role);
+
+CREATE TABLE user_favorite_verses (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  book_id integer NOT NULL,
+  chapter integer NOT NULL,
+  verse integer NOT NULL,
+  created_at datetime NULL
+);
+CREATE INDEX idx_user_favorite_verses_user_id ON user_favorite_verses (user_id);
+CREATE INDEX idx_user_favorite_verses_verse ON user_favorite_verses (book_id, chapter, verse);
+CREATE UNIQUE INDEX unique_user_favorite ON user_favorite_verses (user_id, book_id, chapter, verse);
+
+CREATE TABLE user_highlighted_verses (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  book_id integer NOT NULL,
+  chapter integer NOT NULL,
+  verse integer NOT NULL,
+  note text,
+  color varchar(20) DEFAULT 'yellow',
+  created_at datetime NULL,
+  updated_at datetime NULL
+);
+CREATE INDEX idx_user_highlighted_verses_user_id ON user_highlighted_verses (user_id);
+CREATE INDEX idx_user_highlighted_verses_verse ON user_highlighted_verses (book_id, chapter, verse);
+CREATE UNIQUE INDEX unique_user_highlight ON user_highlighted_verses (user_id, book_id, chapter, verse);
+
+CREATE TABLE user_last_read (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  book_id integer NOT NULL,
+  book_name varchar(255) NOT NULL,
+  chapter integer NOT NULL,
+  verse integer NOT NULL,
+  updated_at datetime NULL
+);
+CREATE UNIQUE INDEX idx_user_last_read_user_id ON user_last_read (user_id);
+
+CREATE TABLE sessions (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  family_id varchar(64) NOT NULL,
+  token_hash varchar(64) NOT NULL,
+  user_agent varchar(255),
+  ip varchar(64),
+  expires_at datetime NOT NULL,
+  revoked_at datetime NULL,
+  created_at datetime NULL
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX idx_sessions_family_id ON sessions (family_id);
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+
+CREATE TABLE password_resets (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  token_hash varchar(64) NOT NULL,
+  expires_at datetime NOT NULL,
+  used_at datetime NULL,
+  created_at datetime NULL
+);
+CREATE INDEX idx_password_resets_user_id ON password_resets (user_id);
+CREATE UNIQUE INDEX idx_password_resets_token_hash ON password_resets (token_hash);
+
+CREATE TABLE user_totp (
+  user_id integer NOT NULL PRIMARY KEY,
+  secret varchar(64) NOT NULL,
+  enabled_at datetime NULL,
+  last_used_step integer NOT NULL DEFAULT 0,
+  created_at datetime NULL,
+  updated_at datetime NULL
+);
+
+CREATE TABLE recovery_codes (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  code_hash varchar(64) NOT NULL,
+  used_at datetime NULL,
+  created_at datetime NULL
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE login_throttles (
+  throttle_key varchar(320) NOT NULL PRIMARY KEY,
+  failures integer NOT NULL DEFAULT 0,
+  last_failure_at datetime NOT NULL,
+  locked_until datetime NULL
+);
+
+CREATE TABLE translations (
+  id varchar(32) NOT NULL PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  language varchar(16) NOT NULL,
+  copyright text,
+  versification varchar(32) NOT NULL DEFAULT 'KJV',
+  created_at datetime NULL,
+  updated_at datetime NULL
+);
+
+CREATE TABLE verses (
+  translation_id varchar(32) NOT NULL,
+  book_id integer NOT NULL,
+  book varchar(255) NOT NULL,
+  chapter integer NOT NULL,
+  verse integer NOT NULL,
+  text varchar(1000) NOT NULL,
+  PRIMARY KEY (translation_id, book_id, chapter, verse)
+);
+
+CREATE TABLE books (
+  translation_id varchar(32) NOT NULL,
+  id integer NOT NULL,
+  name varchar(255) NOT NULL,
+  osis varchar(16) NOT NULL,
+  testament varchar(2) NOT NULL,
+  genre varchar(64),
+  canonical_order integer NOT NULL,
+  aliases text,
+  chapters integer NOT NULL,
+  verse_counts text,
+  PRIMARY KEY (translation_id, id)
+);
+
+CREATE TABLE explanations (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  translation_id varchar(32) NOT NULL,
+  book_id integer NOT NULL,
+  chapter integer NOT NULL,
+  start_verse integer NOT NULL,
+  end_verse integer NOT NULL,
+  age_bucket integer NOT NULL,
+  belief integer NOT NULL,
+  prompt_version integer NOT NULL,
+  model varchar(128) NOT NULL,
+  text text NOT NULL,
+  finish_reason varchar(32),
+  prompt_tokens integer,
+  completion_tokens integer,
+  created_at datetime NULL,
+  expires_at datetime NOT NULL
+);
+CREATE UNIQUE INDEX idx_explanation_key ON explanations (translation_id, book_id, chapter, start_verse, end_verse, age_bucket, belief, prompt_version, model);
+CREATE INDEX idx_explanations_expires_at ON explanations (expires_at);
+
+CREATE TABLE llm_usage (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  subject varchar(80) NOT NULL,
+  day varchar(10) NOT NULL,
+  requests integer NOT NULL DEFAULT 0,
+  prompt_tokens integer NOT NULL DEFAULT 0,
+  completion_tokens integer NOT NULL DEFAULT 0,
+  updated_at datetime NULL
+);
+CREATE UNIQUE INDEX idx_llm_usage_subject_day ON llm_usage (subject, day);
@@ -0,0 +1,2 @@
+-- Matches mysql/0002_user_account_columns.down.sql
+SELECT 1;
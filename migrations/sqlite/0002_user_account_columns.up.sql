@@ -0,0 +1,4 @@
+-- Matches mysql/0002_user_account_columns.up.sql, which brings users tables
+-- created by GORM's AutoMigrate up to the 0001 schema. SQLite databases have
+-- always been created by 0001, so there is nothing to do.
+SELECT 1;
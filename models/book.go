@@ -0,0 +1,21 @@
+package models
+
+// Book is the catalogue entry for one book of a translation. VerseCounts[i]
+// is the number of verses in chapter i+1.
+type Book struct {
+	TranslationID  string   `gorm:"column:translation_id;primaryKey;size:32" json:"-"`
+	ID             int      `gorm:"column:id;primaryKey;autoIncrement:false" json:"book_id"`
+	Name           string   `gorm:"column:name;not null;size:255" json:"book"`
+	OSIS           string   `gorm:"column:osis;not null;size:16" json:"osis"`
+	Testament      string   `gorm:"column:testament;not null;size:2" json:"testament"`
+	Genre          string   `gorm:"column:genre;size:64" json:"genre"`
+	CanonicalOrder int      `gorm:"column:canonical_order;not null" json:"canonical_order"`
+	Aliases        []string `gorm:"column:aliases;type:text;serializer:json" json:"aliases"`
+	Chapters       int      `gorm:"column:chapters;not null" json:"chapters"`
+	VerseCounts    []int    `gorm:"column:verse_counts;type:text;serializer:json" json:"verse_counts"`
+}
+
+// TableName overrides the default pluralized table name
+func (Book) TableName() string {
+	return "books"
+}
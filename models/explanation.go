@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Explanation is a cached LLM explanation of a verse range. The key columns
+// together identify the prompt that produced it, so a change to any of them
+// (including a new prompt version or model) misses the cache.
+type Explanation struct {
+	ID               int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TranslationID    string    `gorm:"column:translation_id;not null;size:32;uniqueIndex:idx_explanation_key" json:"translation_id"`
+	BookID           int       `gorm:"column:book_id;not null;uniqueIndex:idx_explanation_key" json:"book_id"`
+	Chapter          int       `gorm:"column:chapter;not null;uniqueIndex:idx_explanation_key" json:"chapter"`
+	StartVerse       int       `gorm:"column:start_verse;not null;uniqueIndex:idx_explanation_key" json:"start_verse"`
+	EndVerse         int       `gorm:"column:end_verse;not null;uniqueIndex:idx_explanation_key" json:"end_verse"`
+	AgeBucket        int       `gorm:"column:age_bucket;not null;uniqueIndex:idx_explanation_key" json:"age_bucket"`
+	Belief           int       `gorm:"column:belief;not null;uniqueIndex:idx_explanation_key" json:"belief"`
+	PromptVersion    int       `gorm:"column:prompt_version;not null;uniqueIndex:idx_explanation_key" json:"prompt_version"`
+	Model            string    `gorm:"column:model;not null;size:128;uniqueIndex:idx_explanation_key" json:"model"`
+	Text             string    `gorm:"column:text;type:text;not null" json:"text"`
+	FinishReason     string    `gorm:"column:finish_reason;size:32" json:"finish_reason"`
+	PromptTokens     int       `gorm:"column:prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int       `gorm:"column:completion_tokens" json:"completion_tokens"`
+	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	ExpiresAt        time.Time `gorm:"column:expires_at;not null;index" json:"expires_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (Explanation) TableName() string {
+	return "explanations"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// LLMUsage counts one caller's explanation requests and tokens for one UTC
+// day. Subject is "user:<id>" for signed-in callers and "ip:<address>"
+// otherwise; Day is formatted as 2006-01-02.
+type LLMUsage struct {
+	ID               int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Subject          string    `gorm:"column:subject;not null;size:80;uniqueIndex:idx_llm_usage_subject_day" json:"subject"`
+	Day              string    `gorm:"column:day;not null;size:10;uniqueIndex:idx_llm_usage_subject_day" json:"day"`
+	Requests         int       `gorm:"column:requests;not null;default:0" json:"requests"`
+	PromptTokens     int       `gorm:"column:prompt_tokens;not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int       `gorm:"column:completion_tokens;not null;default:0" json:"completion_tokens"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LoginThrottle counts recent failed logins for one key: an email address
+// ("email:...") or a client IP ("ip:..."). Emails are tracked whether or not
+// they belong to an account, so lockouts do not reveal who is registered.
+type LoginThrottle struct {
+	Key           string     `gorm:"column:throttle_key;primaryKey;size:320" json:"key"`
+	Failures      int        `gorm:"column:failures;not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at;not null" json:"last_failure_at"`
+	LockedUntil   *time.Time `gorm:"column:locked_until" json:"locked_until"`
+}
+
+// TableName overrides the default pluralized table name
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
@@ -1,5 +1,7 @@
 package models
 
+// NIV is the legacy single-translation verse table. Its rows are copied into
+// the verses table under DefaultTranslationID on startup.
 type NIV struct {
 	BookID  int    `gorm:"column:book_id;primaryKey"`
 	Book    string `gorm:"column:book;size:255;not null"`
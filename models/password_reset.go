@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use password reset token, stored as a SHA-256 hash
+type PasswordReset struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID    int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"column:token_hash;not null;size:64;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session is one refresh token. Tokens are stored as SHA-256 hashes and
+// rotated on every refresh; all tokens descended from one login share a
+// FamilyID, which access tokens carry as their session ID so that revoking
+// the family also invalidates them.
+type Session struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID    int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"column:family_id;not null;size:64;index" json:"family_id"`
+	TokenHash string     `gorm:"column:token_hash;not null;size:64;uniqueIndex" json:"-"`
+	UserAgent string     `gorm:"column:user_agent;size:255" json:"user_agent"`
+	IP        string     `gorm:"column:ip;size:64" json:"ip"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (Session) TableName() string {
+	return "sessions"
+}
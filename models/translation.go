@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DefaultTranslationID is the translation served by the legacy /api/niv routes
+const DefaultTranslationID = "niv"
+
+type Translation struct {
+	ID            string    `gorm:"column:id;primaryKey;size:32" json:"id"`
+	Name          string    `gorm:"column:name;not null;size:255" json:"name"`
+	Language      string    `gorm:"column:language;not null;size:16" json:"language"`
+	Copyright     string    `gorm:"column:copyright;type:text" json:"copyright"`
+	Versification string    `gorm:"column:versification;not null;size:32;default:KJV" json:"versification"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (Translation) TableName() string {
+	return "translations"
+}
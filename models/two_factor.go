@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// UserTOTP is a user's authenticator app secret. It is created when
+// enrolment starts and only protects logins once EnabledAt is set, after the
+// user has confirmed a code. LastUsedStep stops a code being replayed.
+type UserTOTP struct {
+	UserID       int        `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"user_id"`
+	Secret       string     `gorm:"column:secret;not null;size:64" json:"-"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at" json:"enabled_at"`
+	LastUsedStep int64      `gorm:"column:last_used_step;not null;default:0" json:"-"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode is a single-use code for logging in without the authenticator
+// app. Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID    int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"column:code_hash;not null;size:64" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default pluralized table name
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
@@ -2,16 +2,32 @@ package models
 
 import "time"
 
+// Roles, from least to most privileged. Moderators can look up users and
+// manage content; admins can also change accounts.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// ValidRole reports whether role is one of the defined roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
+
 type User struct {
-	ID              int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	Email           string    `gorm:"column:email;uniqueIndex;not null;size:255" json:"email"`
-	Password        string    `gorm:"column:password;not null;size:255" json:"-"`
-	FirstName       string    `gorm:"column:first_name;not null;size:255" json:"first_name"`
-	LastName        string    `gorm:"column:last_name;not null;size:255" json:"last_name"`
-	Age             int       `gorm:"column:age;not null" json:"age"`
-	BelieverCategory int      `gorm:"column:believer_category;not null" json:"believer_category"`
-	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	ID               int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Email            string     `gorm:"column:email;uniqueIndex;not null;size:255" json:"email"`
+	Password         string     `gorm:"column:password;not null;size:255" json:"-"`
+	FirstName        string     `gorm:"column:first_name;not null;size:255" json:"first_name"`
+	LastName         string     `gorm:"column:last_name;not null;size:255" json:"last_name"`
+	Age              int        `gorm:"column:age;not null" json:"age"`
+	BelieverCategory int        `gorm:"column:believer_category;not null" json:"believer_category"`
+	EmailVerifiedAt  *time.Time `gorm:"column:email_verified_at" json:"email_verified_at"`
+	Role             string     `gorm:"column:role;not null;size:20;default:user;index" json:"role"`
+	DisabledAt       *time.Time `gorm:"column:disabled_at" json:"disabled_at"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 }
 
 // TableName overrides the default pluralized table name
@@ -24,3 +40,15 @@ func (u *User) GetFullName() string {
 	return u.FirstName + " " + u.LastName
 }
 
+// UserStats summarises a user's activity for the admin API
+type UserStats struct {
+	Favorites      int64      `json:"favorites"`
+	Highlights     int64      `json:"highlights"`
+	ActiveSessions int64      `json:"active_sessions"`
+	LastActiveAt   *time.Time `json:"last_active_at"`
+	LastReadAt     *time.Time `json:"last_read_at"`
+	FailedLogins   int        `json:"failed_logins"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	// ExplanationsToday counts uncached explanations against today's quota
+	ExplanationsToday int `json:"explanations_today"`
+}
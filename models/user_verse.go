@@ -4,10 +4,10 @@ import "time"
 
 type UserFavoriteVerse struct {
 	ID        int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	UserID    int       `gorm:"column:user_id;not null;index" json:"user_id"`
-	BookID    int       `gorm:"column:book_id;not null;index:idx_verse" json:"book_id"`
-	Chapter   int       `gorm:"column:chapter;not null;index:idx_verse" json:"chapter"`
-	Verse     int       `gorm:"column:verse;not null;index:idx_verse" json:"verse"`
+	UserID    int       `gorm:"column:user_id;not null;index;uniqueIndex:unique_user_favorite" json:"user_id"`
+	BookID    int       `gorm:"column:book_id;not null;index:idx_verse;uniqueIndex:unique_user_favorite" json:"book_id"`
+	Chapter   int       `gorm:"column:chapter;not null;index:idx_verse;uniqueIndex:unique_user_favorite" json:"chapter"`
+	Verse     int       `gorm:"column:verse;not null;index:idx_verse;uniqueIndex:unique_user_favorite" json:"verse"`
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 }
 
@@ -18,10 +18,10 @@ func (UserFavoriteVerse) TableName() string {
 
 type UserHighlightedVerse struct {
 	ID        int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	UserID    int       `gorm:"column:user_id;not null;index" json:"user_id"`
-	BookID    int       `gorm:"column:book_id;not null;index:idx_verse" json:"book_id"`
-	Chapter   int       `gorm:"column:chapter;not null;index:idx_verse" json:"chapter"`
-	Verse     int       `gorm:"column:verse;not null;index:idx_verse" json:"verse"`
+	UserID    int       `gorm:"column:user_id;not null;index;uniqueIndex:unique_user_highlight" json:"user_id"`
+	BookID    int       `gorm:"column:book_id;not null;index:idx_verse;uniqueIndex:unique_user_highlight" json:"book_id"`
+	Chapter   int       `gorm:"column:chapter;not null;index:idx_verse;uniqueIndex:unique_user_highlight" json:"chapter"`
+	Verse     int       `gorm:"column:verse;not null;index:idx_verse;uniqueIndex:unique_user_highlight" json:"verse"`
 	Note      string    `gorm:"column:note;type:text" json:"note"`
 	Color     string    `gorm:"column:color;size:20;default:yellow" json:"color"`
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
@@ -0,0 +1,15 @@
+package models
+
+type Verse struct {
+	TranslationID string `gorm:"column:translation_id;primaryKey;size:32"`
+	BookID        int    `gorm:"column:book_id;primaryKey"`
+	Book          string `gorm:"column:book;size:255;not null"`
+	Chapter       int    `gorm:"column:chapter;primaryKey"`
+	Verse         int    `gorm:"column:verse;primaryKey"`
+	Text          string `gorm:"column:text;size:1000;not null"`
+}
+
+// TableName overrides the default pluralized table name
+func (Verse) TableName() string {
+	return "verses"
+}
@@ -0,0 +1,298 @@
+// Package reference parses human-readable scripture references such as
+// "John 3:16-18", "1 Cor 13" or "Gen 1:1-2:3; Ps 23".
+package reference
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Range is a contiguous span of verses within one book. An EndVerse of 0
+// means the range runs to the end of EndChapter.
+type Range struct {
+	Book         canon.Book
+	StartChapter int
+	StartVerse   int
+	EndChapter   int
+	EndVerse     int
+}
+
+// String formats the range in canonical form, e.g. "John 3:16-18"
+func (r Range) String() string {
+	switch {
+	case r.EndVerse == 0 && r.StartVerse == 1 && r.StartChapter == r.EndChapter:
+		return fmt.Sprintf("%s %d", r.Book.Name, r.StartChapter)
+	case r.EndVerse == 0 && r.StartVerse == 1:
+		return fmt.Sprintf("%s %d-%d", r.Book.Name, r.StartChapter, r.EndChapter)
+	case r.StartChapter == r.EndChapter && r.StartVerse == r.EndVerse:
+		return fmt.Sprintf("%s %d:%d", r.Book.Name, r.StartChapter, r.StartVerse)
+	case r.StartChapter == r.EndChapter:
+		return fmt.Sprintf("%s %d:%d-%d", r.Book.Name, r.StartChapter, r.StartVerse, r.EndVerse)
+	case r.EndVerse == 0:
+		return fmt.Sprintf("%s %d:%d-%d:end", r.Book.Name, r.StartChapter, r.StartVerse, r.EndChapter)
+	}
+	return fmt.Sprintf("%s %d:%d-%d:%d", r.Book.Name, r.StartChapter, r.StartVerse, r.EndChapter, r.EndVerse)
+}
+
+// Error reports the token that could not be parsed and its byte offset in
+// the original input.
+type Error struct {
+	Input   string
+	Token   string
+	Offset  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at offset %d (%q)", e.Message, e.Offset, e.Token)
+}
+
+// Parse resolves a reference string into verse ranges. Segments are
+// separated by ";" and may omit the book to reuse the previous one
+// ("Gen 1; 3"). Within a segment, "," separates further chapters or verses
+// ("John 3:16, 18-20").
+func Parse(input string) ([]Range, error) {
+	p := &parser{input: input}
+	var ranges []Range
+	var book *canon.Book
+
+	for start := 0; start <= len(input); {
+		end := strings.IndexByte(input[start:], ';')
+		if end < 0 {
+			end = len(input)
+		} else {
+			end += start
+		}
+
+		// Tolerate a trailing ";"
+		if end == len(input) && len(ranges) > 0 && strings.TrimSpace(input[start:end]) == "" {
+			break
+		}
+
+		segmentRanges, segmentBook, err := p.segment(start, end, book)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, segmentRanges...)
+		book = segmentBook
+		start = end + 1
+	}
+
+	if len(ranges) == 0 {
+		return nil, &Error{Input: input, Token: input, Message: "empty reference"}
+	}
+	return ranges, nil
+}
+
+type parser struct {
+	input string
+}
+
+func (p *parser) errorAt(offset int, message string) *Error {
+	end := offset
+	for end < len(p.input) && !strings.ContainsRune(";,", rune(p.input[end])) {
+		end++
+	}
+	token := strings.TrimSpace(p.input[offset:end])
+	if token == "" && offset < len(p.input) {
+		token = p.input[offset : offset+1]
+	}
+	return &Error{Input: p.input, Token: token, Offset: offset, Message: message}
+}
+
+// segment parses input[start:end], one book followed by chapter/verse specs
+func (p *parser) segment(start, end int, previous *canon.Book) ([]Range, *canon.Book, error) {
+	pos := skipSpace(p.input, start, end)
+	if pos == end {
+		return nil, previous, p.errorAt(pos, "empty reference segment")
+	}
+
+	book := previous
+	nameEnd := bookNameEnd(p.input, pos, end)
+	if nameEnd > pos {
+		name := strings.TrimSpace(p.input[pos:nameEnd])
+		found, ok := canon.Lookup(name)
+		if !ok {
+			return nil, nil, &Error{Input: p.input, Token: name, Offset: pos, Message: "unknown book"}
+		}
+		book = &found
+		pos = skipSpace(p.input, nameEnd, end)
+	}
+	if book == nil {
+		return nil, nil, p.errorAt(pos, "reference must start with a book name")
+	}
+
+	// A bare book name means the whole book
+	if pos == end {
+		return []Range{{Book: *book, StartChapter: 1, StartVerse: 1, EndChapter: book.Chapters}}, book, nil
+	}
+
+	var ranges []Range
+	// chapter is the context for verse-only items after a "C:V" item
+	chapter := 0
+	for pos < end {
+		itemEnd := strings.IndexByte(p.input[pos:end], ',')
+		if itemEnd < 0 {
+			itemEnd = end
+		} else {
+			itemEnd += pos
+		}
+
+		r, err := p.item(pos, itemEnd, *book, &chapter)
+		if err != nil {
+			return nil, nil, err
+		}
+		ranges = append(ranges, r)
+		pos = skipSpace(p.input, itemEnd+1, end)
+		if itemEnd < end && pos == end {
+			return nil, nil, p.errorAt(itemEnd, "trailing comma")
+		}
+	}
+	return ranges, book, nil
+}
+
+// item parses "C", "C:V", "C-C", "C:V-V", "C:V-C:V" or, after a "C:V"
+// item, a verse-only "V" or "V-V" within the same chapter.
+func (p *parser) item(start, end int, book canon.Book, chapter *int) (Range, error) {
+	text := p.input[start:end]
+	dash := strings.IndexAny(text, "-–—")
+	left, right, rightOffset := text, "", 0
+	if dash >= 0 {
+		left = text[:dash]
+		_, size := decodeRune(text[dash:])
+		right = text[dash+size:]
+		rightOffset = start + dash + size
+	}
+
+	sc, sv, err := p.point(left, start, book)
+	if err != nil {
+		return Range{}, err
+	}
+
+	// Single-chapter books are referenced by verse ("Jude 3")
+	singleChapter := book.Chapters == 1 && sv == 0
+	switch {
+	case singleChapter:
+		sc, sv = 1, sc
+		*chapter = 1
+	case sv == 0 && *chapter > 0:
+		sc, sv = *chapter, sc
+	case sv > 0:
+		*chapter = sc
+	}
+
+	r := Range{Book: book, StartChapter: sc, StartVerse: sv, EndChapter: sc, EndVerse: sv}
+	if sv == 0 {
+		r.StartVerse = 1
+	}
+
+	if dash >= 0 {
+		ec, ev, err := p.point(right, rightOffset, book)
+		if err != nil {
+			return Range{}, err
+		}
+		switch {
+		case ev > 0:
+			// "C:V-C:V"
+			r.EndChapter, r.EndVerse = ec, ev
+			*chapter = ec
+		case sv > 0:
+			// "C:V-V"
+			r.EndVerse = ec
+		default:
+			// "C-C"
+			r.EndChapter, r.EndVerse = ec, 0
+		}
+	}
+
+	if r.StartChapter < 1 || r.StartChapter > book.Chapters {
+		return Range{}, p.errorAt(start, fmt.Sprintf("%s has %d chapters", book.Name, book.Chapters))
+	}
+	if r.EndChapter < 1 || r.EndChapter > book.Chapters {
+		return Range{}, p.errorAt(rightOffset, fmt.Sprintf("%s has %d chapters", book.Name, book.Chapters))
+	}
+	if r.EndChapter < r.StartChapter || (r.EndChapter == r.StartChapter && r.EndVerse != 0 && r.EndVerse < r.StartVerse) {
+		return Range{}, p.errorAt(rightOffset, "range ends before it starts")
+	}
+	return r, nil
+}
+
+// point parses "C" or "C:V" (also "C.V"); a missing verse is returned as 0
+func (p *parser) point(text string, offset int, book canon.Book) (int, int, error) {
+	trimmed := strings.TrimSpace(text)
+	offset += strings.Index(text, trimmed)
+	if trimmed == "" {
+		return 0, 0, p.errorAt(offset, "missing chapter or verse number")
+	}
+
+	chapterText, verseText, hasVerse := strings.Cut(trimmed, ":")
+	if !hasVerse {
+		chapterText, verseText, hasVerse = strings.Cut(trimmed, ".")
+	}
+
+	chapter, err := strconv.Atoi(strings.TrimSpace(chapterText))
+	if err != nil || chapter < 1 {
+		return 0, 0, p.errorAt(offset, "invalid chapter number")
+	}
+	if !hasVerse {
+		return chapter, 0, nil
+	}
+
+	verse, err := strconv.Atoi(strings.TrimSpace(verseText))
+	if err != nil || verse < 1 {
+		return 0, 0, p.errorAt(offset+len(chapterText)+1, "invalid verse number")
+	}
+	return chapter, verse, nil
+}
+
+// bookNameEnd returns where a leading book name ends: an optional number
+// prefix ("1 Cor", "2John") followed by words, stopping at the first digit
+// after a letter. It returns start if the segment has no book name.
+func bookNameEnd(s string, start, end int) int {
+	i := start
+	for i < end && unicode.IsDigit(rune(s[i])) {
+		i++
+	}
+	if i > start {
+		j := skipSpace(s, i, end)
+		if j == end || !unicode.IsLetter(rune(s[j])) {
+			return start
+		}
+		i = j
+	}
+
+	seenLetter := false
+	for i < end {
+		r, size := decodeRune(s[i:end])
+		if unicode.IsDigit(r) && seenLetter {
+			break
+		}
+		if unicode.IsLetter(r) {
+			seenLetter = true
+		} else if !unicode.IsSpace(r) && r != '.' {
+			break
+		}
+		i += size
+	}
+	if !seenLetter {
+		return start
+	}
+	return i
+}
+
+func skipSpace(s string, pos, end int) int {
+	for pos < end && unicode.IsSpace(rune(s[pos])) {
+		pos++
+	}
+	return pos
+}
+
+func decodeRune(s string) (rune, int) {
+	for _, r := range s {
+		return r, len(string(r))
+	}
+	return 0, 0
+}
@@ -0,0 +1,89 @@
+package reference
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"John 3:16", []string{"John 3:16"}},
+		{"John 3:16-18", []string{"John 3:16-18"}},
+		{"jn 3.16–18", []string{"John 3:16-18"}},
+		{"1 Cor 13", []string{"1 Corinthians 13"}},
+		{"1Cor 13:4-7", []string{"1 Corinthians 13:4-7"}},
+		{"I Corinthians 13", []string{"1 Corinthians 13"}},
+		{"Gen 1:1-2:3; Ps 23", []string{"Genesis 1:1-2:3", "Psalms 23"}},
+		{"Gen 1; 3", []string{"Genesis 1", "Genesis 3"}},
+		{"John 3:16, 18-20", []string{"John 3:16", "John 3:18-20"}},
+		{"Matt 5-7", []string{"Matthew 5-7"}},
+		{"Song of Solomon 2:1", []string{"Song of Songs 2:1"}},
+		{"Jude 3", []string{"Jude 1:3"}},
+		{"Phlm 4-6", []string{"Philemon 1:4-6"}},
+		{"Rom", []string{"Romans 1-16"}},
+		{"Rev 22:21;", []string{"Revelation 22:21"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ranges, err := Parse(tt.input)
+			require.NoError(t, err)
+
+			got := []string{}
+			for _, r := range ranges {
+				got = append(got, r.String())
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParse_RangeFields(t *testing.T) {
+	ranges, err := Parse("Gen 1:1-2:3")
+	require.NoError(t, err)
+	require.Len(t, ranges, 1)
+
+	r := ranges[0]
+	assert.Equal(t, 1, r.Book.ID)
+	assert.Equal(t, []int{1, 1, 2, 3}, []int{r.StartChapter, r.StartVerse, r.EndChapter, r.EndVerse})
+
+	ranges, err = Parse("Ps 23")
+	require.NoError(t, err)
+	assert.Equal(t, []int{23, 1, 23, 0}, []int{ranges[0].StartChapter, ranges[0].StartVerse, ranges[0].EndChapter, ranges[0].EndVerse})
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input   string
+		token   string
+		offset  int
+		message string
+	}{
+		{"", "", 0, "empty reference segment"},
+		{"Jhon 3:16", "Jhon", 0, "unknown book"},
+		{"John 3:16; Hezekiah 4", "Hezekiah", 11, "unknown book"},
+		{"John 3:x", "x", 7, "invalid verse number"},
+		{"John 30", "30", 5, "John has 21 chapters"},
+		{"John 3:18-16", "16", 10, "range ends before it starts"},
+		{"3:16", "3:16", 0, "reference must start with a book name"},
+		{"John 3:16,", ",", 9, "trailing comma"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			require.Error(t, err)
+
+			var refErr *Error
+			require.ErrorAs(t, err, &refErr)
+			assert.Equal(t, tt.message, refErr.Message)
+			assert.Equal(t, tt.token, refErr.Token)
+			assert.Equal(t, tt.offset, refErr.Offset)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package search provides an in-memory inverted index over verse text with
+// phrase, prefix and boolean queries.
+package search
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/models"
+	"sort"
+	"strings"
+)
+
+// posting lists the word positions of a term in one verse
+type posting struct {
+	doc       int
+	positions []int
+}
+
+// Index is an immutable inverted index; it is safe for concurrent use
+type Index struct {
+	verses   []models.Verse
+	postings map[string][]posting
+	terms    []string
+	all      []int
+}
+
+// NewIndex indexes the verses in canonical (book, chapter, verse) order
+func NewIndex(verses []models.Verse) *Index {
+	sorted := make([]models.Verse, len(verses))
+	copy(sorted, verses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.BookID != b.BookID {
+			return a.BookID < b.BookID
+		}
+		if a.Chapter != b.Chapter {
+			return a.Chapter < b.Chapter
+		}
+		return a.Verse < b.Verse
+	})
+
+	ix := &Index{
+		verses:   sorted,
+		postings: map[string][]posting{},
+		all:      make([]int, len(sorted)),
+	}
+	for doc, v := range sorted {
+		ix.all[doc] = doc
+		for pos, t := range tokenize(v.Text) {
+			list := ix.postings[t.term]
+			if n := len(list); n > 0 && list[n-1].doc == doc {
+				list[n-1].positions = append(list[n-1].positions, pos)
+			} else {
+				list = append(list, posting{doc: doc, positions: []int{pos}})
+			}
+			ix.postings[t.term] = list
+		}
+	}
+
+	ix.terms = make([]string, 0, len(ix.postings))
+	for term := range ix.postings {
+		ix.terms = append(ix.terms, term)
+	}
+	sort.Strings(ix.terms)
+
+	return ix
+}
+
+// Len returns the number of indexed verses
+func (ix *Index) Len() int {
+	return len(ix.verses)
+}
+
+// Filter restricts a search to a testament and/or a range of book IDs.
+// Zero values mean no restriction.
+type Filter struct {
+	Testament string
+	FromBook  int
+	ToBook    int
+}
+
+func (f Filter) match(v models.Verse) bool {
+	if f.FromBook > 0 && v.BookID < f.FromBook {
+		return false
+	}
+	if f.ToBook > 0 && v.BookID > f.ToBook {
+		return false
+	}
+	if f.Testament != "" {
+		book, ok := canon.ByID(v.BookID)
+		if !ok || !strings.EqualFold(book.Testament(), f.Testament) {
+			return false
+		}
+	}
+	return true
+}
+
+// Highlight marks a match in a verse's text as rune offsets [Start, End)
+type Highlight struct {
+	Start int
+	End   int
+}
+
+type Hit struct {
+	Verse      models.Verse
+	Highlights []Highlight
+}
+
+// Search returns one page of matching verses in canonical order and the
+// total number of matches. A negative offset or limit returns no verses.
+func (ix *Index) Search(q *Query, f Filter, offset, limit int) ([]Hit, int) {
+	var matches []int
+	for _, doc := range q.root.eval(ix) {
+		if f.match(ix.verses[doc]) {
+			matches = append(matches, doc)
+		}
+	}
+
+	total := len(matches)
+	if offset < 0 || limit < 0 || offset >= total {
+		return []Hit{}, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	matchers := q.root.positive(nil, false)
+	hits := make([]Hit, 0, end-offset)
+	for _, doc := range matches[offset:end] {
+		v := ix.verses[doc]
+		hits = append(hits, Hit{Verse: v, Highlights: highlight(v.Text, matchers)})
+	}
+	return hits, total
+}
+
+// docs returns the sorted documents containing term
+func (ix *Index) docs(term string) []int {
+	list := ix.postings[term]
+	docs := make([]int, len(list))
+	for i, p := range list {
+		docs[i] = p.doc
+	}
+	return docs
+}
+
+// highlight finds the spans of text matched by the query's positive terms
+func highlight(text string, matchers []node) []Highlight {
+	tokens := tokenize(text)
+	var spans []Highlight
+
+	for i := range tokens {
+		for _, m := range matchers {
+			switch m := m.(type) {
+			case termNode:
+				if tokens[i].term == string(m) {
+					spans = append(spans, Highlight{Start: tokens[i].start, End: tokens[i].end})
+				}
+			case prefixNode:
+				if strings.HasPrefix(tokens[i].term, string(m)) {
+					spans = append(spans, Highlight{Start: tokens[i].start, End: tokens[i].end})
+				}
+			case phraseNode:
+				if i+len(m) <= len(tokens) && phraseAt(tokens, i, m) {
+					spans = append(spans, Highlight{Start: tokens[i].start, End: tokens[i+len(m)-1].end})
+				}
+			}
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	merged := []Highlight{}
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.Start <= merged[n-1].End {
+			if s.End > merged[n-1].End {
+				merged[n-1].End = s.End
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+func phraseAt(tokens []token, i int, terms []string) bool {
+	for j, term := range terms {
+		if tokens[i+j].term != term {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+package search
+
+import (
+	"sort"
+	"strings"
+)
+
+// node is a query expression evaluated to a sorted list of documents
+type node interface {
+	eval(ix *Index) []int
+	// positive appends the leaves that are not negated, for highlighting
+	positive(leaves []node, negated bool) []node
+}
+
+type termNode string
+
+func (n termNode) eval(ix *Index) []int {
+	return ix.docs(string(n))
+}
+
+func (n termNode) positive(leaves []node, negated bool) []node {
+	if negated {
+		return leaves
+	}
+	return append(leaves, n)
+}
+
+type prefixNode string
+
+func (n prefixNode) eval(ix *Index) []int {
+	var result []int
+	i := sort.SearchStrings(ix.terms, string(n))
+	for ; i < len(ix.terms) && strings.HasPrefix(ix.terms[i], string(n)); i++ {
+		result = union(result, ix.docs(ix.terms[i]))
+	}
+	return result
+}
+
+func (n prefixNode) positive(leaves []node, negated bool) []node {
+	if negated {
+		return leaves
+	}
+	return append(leaves, n)
+}
+
+type phraseNode []string
+
+func (n phraseNode) eval(ix *Index) []int {
+	lists := make([][]posting, len(n))
+	for i, term := range n {
+		lists[i] = ix.postings[term]
+		if len(lists[i]) == 0 {
+			return nil
+		}
+	}
+
+	var result []int
+	cursors := make([]int, len(n))
+	for _, first := range lists[0] {
+		// Advance every other term to the same document
+		found := true
+		postings := make([]posting, len(n))
+		postings[0] = first
+		for i := 1; i < len(n); i++ {
+			list := lists[i]
+			for cursors[i] < len(list) && list[cursors[i]].doc < first.doc {
+				cursors[i]++
+			}
+			if cursors[i] == len(list) || list[cursors[i]].doc != first.doc {
+				found = false
+				break
+			}
+			postings[i] = list[cursors[i]]
+		}
+		if found && consecutive(postings) {
+			result = append(result, first.doc)
+		}
+	}
+	return result
+}
+
+func (n phraseNode) positive(leaves []node, negated bool) []node {
+	if negated {
+		return leaves
+	}
+	return append(leaves, n)
+}
+
+// consecutive reports whether the terms occur at adjacent positions
+func consecutive(postings []posting) bool {
+	for _, start := range postings[0].positions {
+		match := true
+		for i := 1; i < len(postings); i++ {
+			if !containsInt(postings[i].positions, start+i) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+type andNode []node
+
+func (n andNode) eval(ix *Index) []int {
+	var result []int
+	first := true
+	var excluded [][]int
+	for _, child := range n {
+		// NOT children are subtracted instead of complemented
+		if not, ok := child.(notNode); ok {
+			excluded = append(excluded, not.child.eval(ix))
+			continue
+		}
+		docs := child.eval(ix)
+		if first {
+			result, first = docs, false
+		} else {
+			result = intersect(result, docs)
+		}
+	}
+	if first {
+		result = ix.all
+	}
+	for _, docs := range excluded {
+		result = difference(result, docs)
+	}
+	return result
+}
+
+func (n andNode) positive(leaves []node, negated bool) []node {
+	for _, child := range n {
+		leaves = child.positive(leaves, negated)
+	}
+	return leaves
+}
+
+type orNode []node
+
+func (n orNode) eval(ix *Index) []int {
+	var result []int
+	for _, child := range n {
+		result = union(result, child.eval(ix))
+	}
+	return result
+}
+
+func (n orNode) positive(leaves []node, negated bool) []node {
+	for _, child := range n {
+		leaves = child.positive(leaves, negated)
+	}
+	return leaves
+}
+
+type notNode struct {
+	child node
+}
+
+func (n notNode) eval(ix *Index) []int {
+	return difference(ix.all, n.child.eval(ix))
+}
+
+func (n notNode) positive(leaves []node, negated bool) []node {
+	return n.child.positive(leaves, !negated)
+}
+
+func intersect(a, b []int) []int {
+	var out []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func union(a, b []int) []int {
+	out := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			out = append(out, a[i])
+			i++
+		case a[i] > b[j]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
+
+func difference(a, b []int) []int {
+	var out []int
+	j := 0
+	for _, doc := range a {
+		for j < len(b) && b[j] < doc {
+			j++
+		}
+		if j < len(b) && b[j] == doc {
+			continue
+		}
+		out = append(out, doc)
+	}
+	return out
+}
+
+func containsInt(sorted []int, v int) bool {
+	i := sort.SearchInts(sorted, v)
+	return i < len(sorted) && sorted[i] == v
+}
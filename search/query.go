@@ -0,0 +1,260 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError reports the query token that could not be parsed
+type SyntaxError struct {
+	Token   string
+	Offset  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d (%q)", e.Message, e.Offset, e.Token)
+}
+
+// Query is a parsed search expression
+type Query struct {
+	root node
+}
+
+// ParseQuery parses a search expression. Words are ANDed by default;
+// "quoted text" matches a phrase, a trailing * matches a prefix, and the
+// upper-case operators AND, OR and NOT can be grouped with parentheses:
+//
+//	love AND (faith OR hope) NOT "love of money"
+func ParseQuery(input string) (*Query, error) {
+	p := &queryParser{input: input}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, &SyntaxError{Message: "empty query"}
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		return nil, &SyntaxError{Token: t.text, Offset: t.offset, Message: "unexpected token"}
+	}
+	if !hasPositive(root, false) {
+		return nil, &SyntaxError{Token: input, Message: "query must contain at least one term that is not negated"}
+	}
+	return &Query{root: root}, nil
+}
+
+type lexKind int
+
+const (
+	lexWord lexKind = iota
+	lexPhrase
+	lexAnd
+	lexOr
+	lexNot
+	lexOpen
+	lexClose
+)
+
+type lexToken struct {
+	kind   lexKind
+	text   string
+	offset int
+}
+
+type queryParser struct {
+	input  string
+	tokens []lexToken
+	pos    int
+}
+
+func (p *queryParser) lex() error {
+	s := p.input
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, lexToken{kind: lexOpen, text: "(", offset: i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, lexToken{kind: lexClose, text: ")", offset: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return &SyntaxError{Token: s[i:], Offset: i, Message: "unterminated phrase"}
+			}
+			p.tokens = append(p.tokens, lexToken{kind: lexPhrase, text: s[i+1 : i+1+end], offset: i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n()\"", rune(s[i])) {
+				i++
+			}
+			word := s[start:i]
+			kind := lexWord
+			switch word {
+			case "AND":
+				kind = lexAnd
+			case "OR":
+				kind = lexOr
+			case "NOT":
+				kind = lexNot
+			}
+			if kind == lexWord && strings.HasPrefix(word, "-") && len(word) > 1 {
+				// -word is shorthand for NOT word
+				p.tokens = append(p.tokens, lexToken{kind: lexNot, text: "-", offset: start})
+				word, start = word[1:], start+1
+			}
+			p.tokens = append(p.tokens, lexToken{kind: kind, text: word, offset: start})
+		}
+	}
+	return nil
+}
+
+func (p *queryParser) peek() *lexToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *queryParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{left}
+	for t := p.peek(); t != nil && t.kind == lexOr; t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return orNode(children), nil
+}
+
+func (p *queryParser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{left}
+	for t := p.peek(); t != nil && t.kind != lexOr && t.kind != lexClose; t = p.peek() {
+		if t.kind == lexAnd {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return andNode(children), nil
+}
+
+func (p *queryParser) parseUnary() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, &SyntaxError{Offset: len(p.input), Message: "unexpected end of query"}
+	}
+	if t.kind == lexNot {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, &SyntaxError{Offset: len(p.input), Message: "unexpected end of query"}
+	}
+	p.pos++
+
+	switch t.kind {
+	case lexOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.peek(); c == nil || c.kind != lexClose {
+			return nil, &SyntaxError{Token: "(", Offset: t.offset, Message: "unclosed parenthesis"}
+		}
+		p.pos++
+		return inner, nil
+	case lexWord:
+		prefix := strings.HasSuffix(t.text, "*")
+		terms := tokenize(strings.TrimSuffix(t.text, "*"))
+		switch {
+		case len(terms) == 0:
+			return nil, &SyntaxError{Token: t.text, Offset: t.offset, Message: "term has no letters or digits"}
+		case prefix && len(terms) == 1:
+			return prefixNode(terms[0].term), nil
+		case len(terms) == 1:
+			return termNode(terms[0].term), nil
+		}
+		// Words such as "God's" or "well-being" are matched as phrases
+		return newPhrase(terms), nil
+	case lexPhrase:
+		terms := tokenize(t.text)
+		if len(terms) == 0 {
+			return nil, &SyntaxError{Token: `"` + t.text + `"`, Offset: t.offset, Message: "empty phrase"}
+		}
+		if len(terms) == 1 {
+			return termNode(terms[0].term), nil
+		}
+		return newPhrase(terms), nil
+	}
+	return nil, &SyntaxError{Token: t.text, Offset: t.offset, Message: "expected a term"}
+}
+
+func newPhrase(tokens []token) phraseNode {
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.term
+	}
+	return phraseNode(terms)
+}
+
+// hasPositive reports whether a node can match without relying only on NOT
+func hasPositive(n node, negated bool) bool {
+	switch n := n.(type) {
+	case notNode:
+		return hasPositive(n.child, !negated)
+	case andNode:
+		for _, c := range n {
+			if hasPositive(c, negated) {
+				return true
+			}
+		}
+		return false
+	case orNode:
+		for _, c := range n {
+			if !hasPositive(c, negated) {
+				return false
+			}
+		}
+		return true
+	}
+	return !negated
+}
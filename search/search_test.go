@@ -0,0 +1,125 @@
+package search
+
+import (
+	"testing"
+
+	"bible_reading_backend_nkv/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testVerses = []models.Verse{
+	{BookID: 43, Chapter: 3, Verse: 16, Text: "For God so loved the world that he gave his one and only Son"},
+	{BookID: 1, Chapter: 1, Verse: 1, Text: "In the beginning God created the heavens and the earth."},
+	{BookID: 46, Chapter: 13, Verse: 13, Text: "And now these three remain: faith, hope and love. But the greatest of these is love."},
+	{BookID: 54, Chapter: 6, Verse: 10, Text: "For the love of money is a root of all kinds of evil."},
+	{BookID: 62, Chapter: 4, Verse: 8, Text: "Whoever does not love does not know God, because God is love."},
+	{BookID: 19, Chapter: 23, Verse: 1, Text: "The Lord is my shepherd, I lack nothing."},
+}
+
+func search(t *testing.T, query string, f Filter) []Hit {
+	t.Helper()
+	q, err := ParseQuery(query)
+	require.NoError(t, err)
+	hits, total := NewIndex(testVerses).Search(q, f, 0, 10)
+	assert.Equal(t, total, len(hits))
+	return hits
+}
+
+func refs(hits []Hit) []int {
+	ids := []int{}
+	for _, h := range hits {
+		ids = append(ids, h.Verse.BookID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []int
+	}{
+		{"god", []int{1, 43, 62}},
+		{"God love", []int{62}},
+		{"god AND love", []int{62}},
+		{"faith OR shepherd", []int{19, 46}},
+		{"love NOT money", []int{46, 62}},
+		{"love -money", []int{46, 62}},
+		{`"love of money"`, []int{54}},
+		{`"money of love"`, []int{}},
+		{"lov*", []int{43, 46, 54, 62}},
+		{"(faith OR money) AND love", []int{46, 54}},
+		{"NOT love AND god", []int{1, 43}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.expected, refs(search(t, tt.query, Filter{})))
+		})
+	}
+}
+
+func TestSearch_Filters(t *testing.T) {
+	assert.Equal(t, []int{1}, refs(search(t, "god", Filter{Testament: "ot"})))
+	assert.Equal(t, []int{43, 62}, refs(search(t, "god", Filter{Testament: "NT"})))
+	assert.Equal(t, []int{43}, refs(search(t, "god", Filter{FromBook: 40, ToBook: 43})))
+}
+
+func TestSearch_Pagination(t *testing.T) {
+	q, err := ParseQuery("love")
+	require.NoError(t, err)
+	ix := NewIndex(testVerses)
+
+	hits, total := ix.Search(q, Filter{}, 1, 1)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []int{54}, refs(hits))
+
+	hits, total = ix.Search(q, Filter{}, 5, 1)
+	assert.Equal(t, 3, total)
+	assert.Empty(t, hits)
+
+	hits, total = ix.Search(q, Filter{}, -20, 20)
+	assert.Equal(t, 3, total)
+	assert.Empty(t, hits)
+}
+
+func TestSearch_Highlights(t *testing.T) {
+	hits := search(t, `"love of money" OR evil NOT shepherd`, Filter{})
+	require.Len(t, hits, 1)
+	assert.Equal(t, []Highlight{{Start: 8, End: 21}, {Start: 48, End: 52}}, hits[0].Highlights)
+
+	// Offsets count runes, not bytes
+	ix := NewIndex([]models.Verse{{BookID: 1, Chapter: 1, Verse: 1, Text: "“Let there be light,” and there was light."}})
+	q, err := ParseQuery("light")
+	require.NoError(t, err)
+	hits, _ = ix.Search(q, Filter{}, 0, 10)
+	require.Len(t, hits, 1)
+	assert.Equal(t, []Highlight{{Start: 14, End: 19}, {Start: 36, End: 41}}, hits[0].Highlights)
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		query   string
+		offset  int
+		message string
+	}{
+		{"", 0, "empty query"},
+		{`"unterminated`, 0, "unterminated phrase"},
+		{"(love OR hope", 0, "unclosed parenthesis"},
+		{"love AND", 8, "unexpected end of query"},
+		{"love )", 5, "unexpected token"},
+		{"NOT love", 0, "query must contain at least one term that is not negated"},
+		{"love ---", 6, "term has no letters or digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			assert.Equal(t, tt.message, syntaxErr.Message)
+			assert.Equal(t, tt.offset, syntaxErr.Offset)
+		})
+	}
+}
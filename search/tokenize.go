@@ -0,0 +1,51 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// token is a lowercased word and its position in the source text, in runes
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// tokenize splits text into words of letters and digits. Offsets are rune
+// indexes so clients can slice the text without caring about UTF-8.
+func tokenize(text string) []token {
+	var (
+		tokens []token
+		word   strings.Builder
+		start  = -1
+		i      = 0
+	)
+	flush := func() {
+		if start >= 0 {
+			tokens = append(tokens, token{term: word.String(), start: start, end: i})
+			word.Reset()
+			start = -1
+		}
+	}
+
+	for _, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			word.WriteRune(unicode.ToLower(r))
+		} else {
+			flush()
+		}
+		i++
+	}
+	flush()
+
+	return tokens
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
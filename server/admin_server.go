@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/server/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ListUsers pages through users, optionally filtered by ?q= (email or name)
+// and ?role=
+func (s *EchoServer) ListUsers(ctx echo.Context) error {
+	search := ctx.QueryParam("q")
+	role := ctx.QueryParam("role")
+	if role != "" && !models.ValidRole(role) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid role"})
+	}
+	page, limit := pagination(ctx)
+
+	users, err := s.DB.SearchUsers(ctx.Request().Context(), search, role, limit, (page-1)*limit)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to list users", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch users"})
+	}
+	total, err := s.DB.CountUsers(ctx.Request().Context(), search, role)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to count users", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch users"})
+	}
+
+	data := make([]dto.AdminUserResponse, len(users))
+	for i := range users {
+		data[i] = adminUserResponse(&users[i])
+	}
+	return ctx.JSON(http.StatusOK, paginatedResponse(data, total, page, limit))
+}
+
+func (s *EchoServer) GetUser(ctx echo.Context) error {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	return ctx.JSON(http.StatusOK, adminUserResponse(user))
+}
+
+func (s *EchoServer) GetUserStats(ctx echo.Context) error {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+
+	stats, err := s.DB.GetUserStats(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get user stats", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch user statistics"})
+	}
+	throttles, err := s.DB.GetLoginThrottles(ctx.Request().Context(), accountThrottleKey(user.Email))
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get failed logins", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch user statistics"})
+	}
+	for _, t := range throttles {
+		stats.FailedLogins = t.Failures
+		if t.LockedUntil != nil && time.Now().Before(*t.LockedUntil) {
+			stats.LockedUntil = t.LockedUntil
+		}
+	}
+	day, _ := quotaDay(time.Now())
+	usage, err := s.DB.GetLLMUsage(ctx.Request().Context(), "user:"+strconv.Itoa(userID), day)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get explanation usage", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch user statistics"})
+	}
+	stats.ExplanationsToday = usage.Requests
+	return ctx.JSON(http.StatusOK, stats)
+}
+
+// DisableUser blocks an account from logging in and ends its sessions
+func (s *EchoServer) DisableUser(ctx echo.Context) error {
+	return s.setUserDisabled(ctx, true)
+}
+
+func (s *EchoServer) EnableUser(ctx echo.Context) error {
+	return s.setUserDisabled(ctx, false)
+}
+
+func (s *EchoServer) setUserDisabled(ctx echo.Context, disabled bool) error {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	if isCurrentUser(ctx, userID) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "You cannot change your own account here"})
+	}
+
+	if err := s.DB.SetUserDisabled(ctx.Request().Context(), userID, disabled); err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+
+	adminID, _ := middleware.GetUserID(ctx)
+	s.Logger.InfoContext(ctx.Request().Context(), "admin set user disabled", "admin_id", adminID, "user_id", userID, "disabled", disabled)
+	return ctx.JSON(http.StatusOK, adminUserResponse(user))
+}
+
+func (s *EchoServer) UpdateUserRole(ctx echo.Context) error {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	if isCurrentUser(ctx, userID) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "You cannot change your own account here"})
+	}
+
+	var req dto.UpdateRoleRequest
+	if err := ctx.Bind(&req); err != nil || !models.ValidRole(req.Role) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Role must be user, moderator or admin"})
+	}
+
+	if _, err := s.DB.GetUserByID(ctx.Request().Context(), userID); err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	if err := s.DB.UpdateUser(ctx.Request().Context(), userID, map[string]interface{}{"role": req.Role}); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to update role", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update user"})
+	}
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+
+	adminID, _ := middleware.GetUserID(ctx)
+	s.Logger.InfoContext(ctx.Request().Context(), "admin set user role", "admin_id", adminID, "user_id", userID, "role", req.Role)
+	return ctx.JSON(http.StatusOK, adminUserResponse(user))
+}
+
+// ForcePasswordReset invalidates a user's password and sessions and emails
+// them a reset link, e.g. when their account may be compromised
+func (s *EchoServer) ForcePasswordReset(ctx echo.Context) error {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	if isCurrentUser(ctx, userID) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "You cannot change your own account here"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	reset, token, err := s.newPasswordReset(user.ID)
+	if err == nil {
+		err = s.DB.ForcePasswordReset(ctx.Request().Context(), reset)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to force password reset", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset password"})
+	}
+
+	msg := s.passwordResetMessage(user, token, "An administrator has reset the password for your account.",
+		"Until you choose a new password you will not be able to log in.")
+	if err := s.Mailer.Send(ctx.Request().Context(), msg); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to send password reset email", "user_id", user.ID, "error", err)
+		return ctx.JSON(http.StatusBadGateway, map[string]string{"error": "Password was reset but the email could not be sent"})
+	}
+
+	adminID, _ := middleware.GetUserID(ctx)
+	s.Logger.InfoContext(ctx.Request().Context(), "admin forced password reset", "admin_id", adminID, "user_id", userID)
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Password reset email sent"})
+}
+
+// UnlockUser lifts a login lockout and forgets the user's failed logins
+func (s *EchoServer) UnlockUser(ctx echo.Context) error {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	if err := s.DB.ClearLoginFailures(ctx.Request().Context(), accountThrottleKey(user.Email)); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to unlock user", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to unlock user"})
+	}
+
+	adminID, _ := middleware.GetUserID(ctx)
+	s.Logger.InfoContext(ctx.Request().Context(), "admin unlocked user", "admin_id", adminID, "user_id", userID)
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "User unlocked"})
+}
+
+// DeleteExplanations invalidates cached explanations for ?translation= and
+// ?book=, or every entry with ?all=true
+func (s *EchoServer) DeleteExplanations(ctx echo.Context) error {
+	translationID := strings.ToLower(ctx.QueryParam("translation"))
+	bookName := ctx.QueryParam("book")
+	if translationID == "" && bookName == "" && ctx.QueryParam("all") != "true" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Specify translation, book or all=true"})
+	}
+
+	bookID := 0
+	if bookName != "" {
+		book, ok := canon.Lookup(bookName)
+		if id, err := strconv.Atoi(bookName); err == nil {
+			book, ok = canon.ByID(id)
+		}
+		if !ok {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown book"})
+		}
+		bookID = book.ID
+	}
+
+	deleted, err := s.DB.DeleteExplanations(ctx.Request().Context(), translationID, bookID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to delete explanations", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete explanations"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]int64{"deleted": deleted})
+}
+
+// isCurrentUser reports whether userID is the caller. Admins cannot disable,
+// demote or reset themselves, so they cannot lock themselves out.
+func isCurrentUser(ctx echo.Context, userID int) bool {
+	currentID, _ := middleware.GetUserID(ctx)
+	return currentID == userID
+}
+
+func userIDParam(ctx echo.Context) (int, bool) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	return userID, err == nil && userID > 0
+}
+
+func adminUserResponse(user *models.User) dto.AdminUserResponse {
+	return dto.AdminUserResponse{
+		UserResponse: userResponse(user),
+		Disabled:     user.DisabledAt != nil,
+		DisabledAt:   user.DisabledAt,
+	}
+}
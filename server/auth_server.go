@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func (s *EchoServer) Register(ctx echo.Context) error {
+	var req dto.RegisterRequest
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	req.Email = normalizeEmail(req.Email)
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.LastName = strings.TrimSpace(req.LastName)
+	if msg := validateRegisterRequest(req); msg != "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	_, err := s.DB.GetUserByEmail(ctx.Request().Context(), req.Email)
+	if err == nil {
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": "Email already registered"})
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to check email", "email", req.Email, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to register user"})
+	}
+
+	user := &models.User{
+		Email:            req.Email,
+		Password:         req.Password,
+		FirstName:        req.FirstName,
+		LastName:         req.LastName,
+		Age:              req.Age,
+		BelieverCategory: req.BelifRating,
+	}
+	if err := s.DB.CreateUser(ctx.Request().Context(), user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ctx.JSON(http.StatusConflict, map[string]string{"error": "Email already registered"})
+		}
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to create user", "email", req.Email, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to register user"})
+	}
+
+	if err := s.sendVerificationEmail(ctx.Request().Context(), user); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	// Without a verified email the user cannot log in yet, so no session is started
+	if s.requireVerifiedEmail {
+		return ctx.JSON(http.StatusCreated, dto.RegisterResponse{
+			VerificationRequired: true,
+			User:                 userResponse(user),
+		})
+	}
+
+	tokens, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to start session", "user_id", user.ID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+
+	return ctx.JSON(http.StatusCreated, dto.RegisterResponse{
+		Access:    tokens.Access,
+		Refresh:   tokens.Refresh,
+		ExpiresIn: tokens.ExpiresIn,
+		User:      userResponse(user),
+	})
+}
+
+func (s *EchoServer) Login(ctx echo.Context) error {
+	var req dto.LoginRequest
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	req.Email = normalizeEmail(req.Email)
+	if req.Email == "" || req.Password == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Email and password are required"})
+	}
+
+	block, err := s.reserveLoginAttempt(ctx, req.Email)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to check failed logins", "email", req.Email, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log in"})
+	}
+	if block != nil {
+		return block.respond(ctx)
+	}
+
+	user, err := s.DB.VerifyPassword(ctx.Request().Context(), req.Email, req.Password)
+	if errors.Is(err, database.ErrInvalidCredentials) {
+		s.recordLoginFailure(ctx)
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid email or password"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to verify password", "email", req.Email, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log in"})
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx.Request().Context(), user.ID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get 2FA status", "user_id", user.ID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log in"})
+	}
+	// With 2FA the counter is only reset once the code is right too
+	if !enabled {
+		s.clearLoginFailures(ctx.Request().Context(), user.Email)
+	}
+	if user.DisabledAt != nil {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"error": "Account disabled"})
+	}
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"error": "Email address not verified"})
+	}
+
+	// With 2FA the password alone only earns a short-lived challenge
+	if enabled {
+		challenge, err := s.tokens.GenerateMFAChallengeToken(user.ID)
+		if err != nil {
+			s.Logger.ErrorContext(ctx.Request().Context(), "failed to generate 2FA challenge", "user_id", user.ID, "error", err)
+			return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		}
+		return ctx.JSON(http.StatusOK, dto.LoginResponse{MFARequired: true, Challenge: challenge})
+	}
+	tokens, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to start session", "user_id", user.ID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+
+	return ctx.JSON(http.StatusOK, dto.LoginResponse{
+		Access:    tokens.Access,
+		Refresh:   tokens.Refresh,
+		ExpiresIn: tokens.ExpiresIn,
+	})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateRegisterRequest returns a client-facing message for the first
+// invalid field, or "" if the request is valid
+func validateRegisterRequest(req dto.RegisterRequest) string {
+	switch {
+	case req.FirstName == "" || len(req.FirstName) > 255:
+		return "First name is required and must be at most 255 characters"
+	case req.LastName == "" || len(req.LastName) > 255:
+		return "Last name is required and must be at most 255 characters"
+	case !validEmail(req.Email):
+		return "Invalid email address"
+	case len(req.Password) < 6:
+		return "Password must be at least 6 characters"
+	case req.Age < 1 || req.Age > 150:
+		return "Age must be between 1 and 150"
+	case req.BelifRating < 1 || req.BelifRating > 5:
+		return "Belief rating must be between 1 and 5"
+	}
+	return ""
+}
+
+func validEmail(email string) bool {
+	if email == "" || len(email) > 255 {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// explainQuota is how many uncached explanations a caller may request per UTC
+// day. Zero means unlimited; usage is still recorded.
+type explainQuota struct {
+	// User applies to callers with a valid token, counted per user
+	User int
+	// Anonymous applies to everyone else, counted per client IP
+	Anonymous int
+}
+
+// quotaDay returns the UTC day usage is counted against and the time left
+// until the next one starts
+func quotaDay(now time.Time) (string, time.Duration) {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return now.Format("2006-01-02"), next.Sub(now)
+}
+
+// quotaSubject returns the key usage is counted under and the caller's daily
+// limit. It uses the same caller identity as rate limiting.
+func (s *EchoServer) quotaSubject(ctx echo.Context) (string, int) {
+	subject := s.rateLimitKey(ctx)
+	if strings.HasPrefix(subject, "user:") {
+		return subject, s.explainQuota.User
+	}
+	return subject, s.explainQuota.Anonymous
+}
+
+// explainReservation is one request counted against a caller's quota. A nil
+// reservation, used when the quota could not be checked, does nothing.
+type explainReservation struct {
+	subject string
+	day     string
+	db      database.DatabaseClient
+	logger  *slog.Logger
+}
+
+// reserveExplainQuota counts an uncached explanation against the caller's
+// quota and sets the X-Quota-* headers. It returns database.ErrQuotaExceeded
+// once the day's quota is used up. If usage cannot be read the request is
+// let through, so a database hiccup does not take explanations down.
+func (s *EchoServer) reserveExplainQuota(ctx echo.Context) (*explainReservation, error) {
+	subject, limit := s.quotaSubject(ctx)
+	day, reset := quotaDay(time.Now())
+
+	dbLimit := limit
+	if dbLimit == 0 {
+		dbLimit = math.MaxInt32
+	}
+	used, err := s.DB.ReserveLLMRequest(ctx.Request().Context(), subject, day, dbLimit)
+	if err != nil && !errors.Is(err, database.ErrQuotaExceeded) {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to reserve explanation quota", "subject", subject, "error", err)
+		return nil, nil
+	}
+
+	if limit > 0 {
+		header := ctx.Response().Header()
+		header.Set("X-Quota-Limit", strconv.Itoa(limit))
+		header.Set("X-Quota-Remaining", strconv.Itoa(max(limit-used, 0)))
+		header.Set("X-Quota-Reset", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &explainReservation{subject: subject, day: day, db: s.DB, logger: s.Logger}, nil
+}
+
+// release gives the request back after a failure the caller did not get
+// anything for. It also runs after the client has disconnected, so it does
+// not use the request's cancellation.
+func (r *explainReservation) release(ctx echo.Context) {
+	if r == nil {
+		return
+	}
+	if err := r.db.ReleaseLLMRequest(context.WithoutCancel(ctx.Request().Context()), r.subject, r.day); err != nil {
+		r.logger.ErrorContext(ctx.Request().Context(), "failed to release explanation quota", "subject", r.subject, "error", err)
+	}
+}
+
+// record adds the tokens a request used to the caller's usage
+func (r *explainReservation) record(ctx echo.Context, usage *dto.TokenUsage) {
+	if r == nil || usage == nil {
+		return
+	}
+	err := r.db.RecordLLMTokens(ctx.Request().Context(), r.subject, r.day, usage.PromptTokens, usage.CompletionTokens)
+	if err != nil {
+		r.logger.ErrorContext(ctx.Request().Context(), "failed to record explanation tokens", "subject", r.subject, "error", err)
+	}
+}
+
+// quotaExceeded writes the 429 response for a caller who has used up their
+// daily quota
+func quotaExceeded(ctx echo.Context) error {
+	_, reset := quotaDay(time.Now())
+	ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+	return ctx.JSON(http.StatusTooManyRequests, map[string]string{"error": "Daily explanation quota exceeded"})
+}
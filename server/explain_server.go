@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/explain"
+	"bible_reading_backend_nkv/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// bindExplainRequest binds the request body and, when a valid token is sent,
+// replaces age and belief with the caller's profile values
+func (s *EchoServer) bindExplainRequest(ctx echo.Context) (dto.ExplainRequest, error) {
+	var req dto.ExplainRequest
+
+	if err := ctx.Bind(&req); err != nil {
+		return req, err
+	}
+
+	// Signed-in callers get explanations for their own age and belief
+	if user, ok := s.optionalUser(ctx); ok {
+		req.Age = user.Age
+		req.Belief = user.BelieverCategory
+	}
+
+	// Set default values if not provided (fallback if no token or user not found)
+	if req.Age == 0 {
+		req.Age = 25
+	}
+	if req.Belief == 0 {
+		req.Belief = 3
+	}
+
+	return req, nil
+}
+
+// contextVerses is how many verses either side of the requested range are
+// sent to the model as background
+const contextVerses = 2
+
+var (
+	errInvalidRange    = errors.New("invalid verse range")
+	errBookNotFound    = errors.New("book not found")
+	errPassageNotFound = errors.New("passage not found")
+)
+
+// loadExplainRequest validates the requested range against the translation
+// and loads its text plus the neighbouring verses, so that missing references
+// are rejected before any LLM call is made
+func (s *EchoServer) loadExplainRequest(ctx echo.Context, translationID string, req dto.ExplainRequest) (explain.Request, error) {
+	if req.EndVerse == 0 {
+		req.EndVerse = req.StartVerse
+	}
+	if req.Chapter < 1 || req.StartVerse < 1 || req.EndVerse < req.StartVerse {
+		return explain.Request{}, errInvalidRange
+	}
+
+	book, ok := canon.Lookup(req.Book)
+	if !ok {
+		return explain.Request{}, errBookNotFound
+	}
+
+	verses, err := s.DB.GetAllVerseByChapter(ctx.Request().Context(), translationID, book.ID, req.Chapter)
+	if err != nil {
+		return explain.Request{}, err
+	}
+
+	out := explain.Request{
+		Translation: translationID,
+		BookID:      book.ID,
+		Book:        book.Name,
+		Chapter:     req.Chapter,
+		StartVerse:  req.StartVerse,
+		EndVerse:    req.EndVerse,
+		Age:         explain.AgeBucket(req.Age),
+		Belief:      req.Belief,
+	}
+	for _, v := range verses {
+		switch {
+		case v.Verse < req.StartVerse-contextVerses:
+		case v.Verse < req.StartVerse:
+			out.Before = append(out.Before, v)
+		case v.Verse <= req.EndVerse:
+			out.Passage = append(out.Passage, v)
+		case v.Verse <= req.EndVerse+contextVerses:
+			out.After = append(out.After, v)
+		}
+	}
+
+	// Some translations omit single verses, so only the ends of the range
+	// have to exist
+	if len(out.Passage) == 0 || out.Passage[0].Verse != req.StartVerse ||
+		out.Passage[len(out.Passage)-1].Verse != req.EndVerse {
+		return explain.Request{}, errPassageNotFound
+	}
+	return out, nil
+}
+
+// explanationKey returns the cache key columns for a request
+func (s *EchoServer) explanationKey(req explain.Request) models.Explanation {
+	return models.Explanation{
+		TranslationID: req.Translation,
+		BookID:        req.BookID,
+		Chapter:       req.Chapter,
+		StartVerse:    req.StartVerse,
+		EndVerse:      req.EndVerse,
+		AgeBucket:     req.Age,
+		Belief:        req.Belief,
+		PromptVersion: explain.PromptVersion,
+		Model:         s.Explainer.Model(),
+	}
+}
+
+// cachedExplanation returns the cached explanation for req, or nil on a miss.
+// Cache failures are logged and treated as misses.
+func (s *EchoServer) cachedExplanation(ctx echo.Context, req explain.Request) *models.Explanation {
+	if s.explainCacheTTL <= 0 {
+		return nil
+	}
+	explanation, err := s.DB.GetExplanation(ctx.Request().Context(), s.explanationKey(req))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.Logger.ErrorContext(ctx.Request().Context(), "failed to read explanation cache", "error", err)
+		}
+		return nil
+	}
+	return explanation
+}
+
+// saveExplanation caches a freshly generated explanation. Results without a
+// finish reason may have been cut short and are not kept.
+func (s *EchoServer) saveExplanation(ctx echo.Context, req explain.Request, result *explain.Result) {
+	if s.explainCacheTTL <= 0 || result.Text == "" || result.FinishReason == "" {
+		return
+	}
+	explanation := s.explanationKey(req)
+	explanation.Model = result.Model
+	explanation.Text = result.Text
+	explanation.FinishReason = result.FinishReason
+	if result.Usage != nil {
+		explanation.PromptTokens = result.Usage.PromptTokens
+		explanation.CompletionTokens = result.Usage.CompletionTokens
+	}
+	explanation.CreatedAt = time.Now().UTC()
+	explanation.ExpiresAt = explanation.CreatedAt.Add(s.explainCacheTTL)
+	if err := s.DB.SaveExplanation(ctx.Request().Context(), &explanation); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to write explanation cache", "error", err)
+	}
+}
+
+// explainError writes the response for a failed explanation
+func (s *EchoServer) explainError(ctx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errInvalidRange):
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid verse range"})
+	case errors.Is(err, errBookNotFound):
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Book not found"})
+	case errors.Is(err, errPassageNotFound):
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Passage not found"})
+	}
+
+	var configErr *explain.ConfigError
+	if errors.As(err, &configErr) {
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{
+			"error": configErr.Message,
+		})
+	}
+
+	s.Logger.ErrorContext(ctx.Request().Context(), "failed to get explanation", "error", err)
+	return ctx.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to get explanation",
+	})
+}
+
+// ExplainVerseStream streams an explanation to the client as Server-Sent
+// Events: "delta" events carry text as it is generated and a final "done"
+// event carries the finish reason and token usage. The upstream request is
+// cancelled when the client disconnects.
+func (s *EchoServer) ExplainVerseStream(ctx echo.Context) error {
+	req, err := s.bindExplainRequest(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request parameters",
+		})
+	}
+
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+	explainReq, err := s.loadExplainRequest(ctx, translationID, req)
+	if err != nil {
+		return s.explainError(ctx, err)
+	}
+
+	w := ctx.Response()
+	startStream := func(cache string) {
+		w.Header().Set(echo.HeaderContentType, "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.Header().Set("X-Cache", cache)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if cached := s.cachedExplanation(ctx, explainReq); cached != nil {
+		startStream("HIT")
+		writeSSE(w, "delta", dto.ExplainDeltaEvent{Content: cached.Text})
+		writeSSE(w, "done", dto.ExplainDoneEvent{FinishReason: cached.FinishReason, Cached: true})
+		return nil
+	}
+
+	reservation, err := s.reserveExplainQuota(ctx)
+	if err != nil {
+		return quotaExceeded(ctx)
+	}
+
+	// Headers are only sent with the first delta, so errors that happen
+	// before any text is generated can still be returned as JSON
+	onDelta := func(content string) error {
+		if !w.Committed {
+			startStream("MISS")
+		}
+		return writeSSE(w, "delta", dto.ExplainDeltaEvent{Content: content})
+	}
+
+	// The request context is cancelled when the client goes away, which
+	// aborts the upstream call as well
+	result, err := s.Explainer.Stream(ctx.Request().Context(), explainReq, onDelta)
+	if ctx.Request().Context().Err() != nil {
+		if !w.Committed {
+			reservation.release(ctx)
+		}
+		return nil
+	}
+	if err != nil {
+		if !w.Committed {
+			reservation.release(ctx)
+			return s.explainError(ctx, err)
+		}
+		s.Logger.ErrorContext(ctx.Request().Context(), "explanation stream interrupted", "error", err)
+		writeSSE(w, "error", map[string]string{"error": "Explanation stream interrupted"})
+		return nil
+	}
+
+	reservation.record(ctx, result.Usage)
+	if !w.Committed {
+		// The model produced no text at all
+		reservation.release(ctx)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "No explanation available"})
+	}
+	s.saveExplanation(ctx, explainReq, result)
+	writeSSE(w, "done", dto.ExplainDoneEvent{FinishReason: result.FinishReason, Usage: result.Usage})
+	return nil
+}
+
+// writeSSE writes one Server-Sent Event with a JSON payload and flushes it
+func writeSSE(w *echo.Response, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
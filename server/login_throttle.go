@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/config"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// loginPolicy limits failed logins per account and per client IP
+type loginPolicy struct {
+	// MaxAttempts failures for one email lock it for Lockout
+	MaxAttempts int
+	// IPMaxAttempts failures from one IP, across any emails, block it for Lockout
+	IPMaxAttempts int
+	// Lockout is how long a lock lasts; failures older than this are forgotten
+	Lockout time.Duration
+}
+
+const (
+	// loginDelayAfter failures for an email, each further attempt has to wait
+	// twice as long as the one before, up to loginMaxDelay
+	loginDelayAfter = 3
+	loginMaxDelay   = 30 * time.Second
+)
+
+func newLoginPolicy(cfg config.Auth) loginPolicy {
+	return loginPolicy{
+		MaxAttempts:   cfg.LoginMaxAttempts,
+		IPMaxAttempts: cfg.LoginIPMaxAttempts,
+		Lockout:       cfg.LoginLockout(),
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "email:" + email
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginDelay is how long to wait after the last of failures before the next
+// attempt for an email is allowed
+func loginDelay(failures int) time.Duration {
+	if failures < loginDelayAfter {
+		return 0
+	}
+	shift := failures - loginDelayAfter
+	if shift >= 5 {
+		return loginMaxDelay
+	}
+	return time.Second << shift
+}
+
+// loginBlock is the response to a login attempt that is not allowed yet
+type loginBlock struct {
+	status  int
+	wait    time.Duration
+	message string
+}
+
+// respond writes the block as JSON with a Retry-After header
+func (b *loginBlock) respond(ctx echo.Context) error {
+	ctx.Response().Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(b.wait.Seconds()))))
+	return ctx.JSON(b.status, map[string]string{"error": b.message})
+}
+
+// reserveLoginAttempt returns a 423 block if email is locked, a 429 block if
+// the client IP is locked or email must wait after recent failures, and nil if
+// the login may be attempted. An attempt that may go ahead is counted against
+// email straight away, so that parallel attempts cannot all get past the same
+// check while the first is being verified; clearLoginFailures takes it back
+// once the attempt succeeds.
+func (s *EchoServer) reserveLoginAttempt(ctx echo.Context, email string) (*loginBlock, error) {
+	policy := s.loginPolicy
+	throttles, err := s.DB.GetLoginThrottles(ctx.Request().Context(), ipThrottleKey(ctx.RealIP()))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, t := range throttles {
+		if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+			return &loginBlock{http.StatusTooManyRequests, t.LockedUntil.Sub(now),
+				"Too many failed login attempts from this address"}, nil
+		}
+	}
+
+	key := accountThrottleKey(email)
+	t, reserved, err := s.DB.ReserveLoginAttempt(ctx.Request().Context(), key, policy.MaxAttempts, policy.Lockout, policy.Lockout, loginDelay)
+	if err != nil {
+		return nil, err
+	}
+	now = time.Now()
+	if !reserved {
+		if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+			return &loginBlock{http.StatusLocked, t.LockedUntil.Sub(now),
+				"Account temporarily locked after too many failed login attempts"}, nil
+		}
+		return &loginBlock{http.StatusTooManyRequests, t.LastFailureAt.Add(loginDelay(t.Failures)).Sub(now),
+			"Too many failed login attempts, please wait before trying again"}, nil
+	}
+	if t.Failures == policy.MaxAttempts {
+		s.Logger.WarnContext(ctx.Request().Context(), "locking out after failed logins", "key", key, "lockout", policy.Lockout, "failures", t.Failures)
+	}
+	return nil, nil
+}
+
+// recordLoginFailure counts a failed password or 2FA code against the client
+// IP; reserveLoginAttempt has already counted it against the email. Errors are
+// logged rather than returned so that the client still gets its 401.
+func (s *EchoServer) recordLoginFailure(ctx echo.Context) {
+	policy := s.loginPolicy
+	key := ipThrottleKey(ctx.RealIP())
+	t, err := s.DB.RecordLoginFailure(ctx.Request().Context(), key, policy.IPMaxAttempts, policy.Lockout, policy.Lockout)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to record failed login", "key", key, "error", err)
+		return
+	}
+	if t.Failures == policy.IPMaxAttempts {
+		s.Logger.WarnContext(ctx.Request().Context(), "locking out after failed logins", "key", key, "lockout", policy.Lockout, "failures", t.Failures)
+	}
+}
+
+// clearLoginFailures resets the counter for email after a successful login.
+// The IP counter is left alone so that one valid account cannot be used to
+// keep guessing others.
+func (s *EchoServer) clearLoginFailures(ctx context.Context, email string) {
+	if err := s.DB.ClearLoginFailures(ctx, accountThrottleKey(email)); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to clear failed logins", "email", email, "error", err)
+	}
+}
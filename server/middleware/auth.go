@@ -0,0 +1,88 @@
+// Package middleware holds the echo middleware used by the server.
+package middleware
+
+import (
+	"bible_reading_backend_nkv/server/utils"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// UserIDKey is the echo context key holding the authenticated user's ID
+	UserIDKey = "user_id"
+	// SessionIDKey is the echo context key holding the token's session ID
+	SessionIDKey = "session_id"
+)
+
+// JWTConfig configures JWTAuthWithConfig
+type JWTConfig struct {
+	// Tokens checks the signature and expiry of tokens. It is required.
+	Tokens *utils.Tokens
+	// Validator is called with the claims of every correctly signed,
+	// unexpired token. Returning utils.ErrInvalidToken rejects the token
+	// with 401; any other error is answered with 500.
+	Validator func(ctx echo.Context, claims *utils.Claims) error
+	// Logger receives validation errors; it defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// JWTAuth rejects requests without a bearer token issued by tokens with 401
+// and stores the token's user ID in the context under UserIDKey
+func JWTAuth(tokens *utils.Tokens) echo.MiddlewareFunc {
+	return JWTAuthWithConfig(JWTConfig{Tokens: tokens})
+}
+
+// JWTAuthWithConfig is JWTAuth with additional checks, such as whether the
+// token's session has been revoked
+func JWTAuthWithConfig(config JWTConfig) echo.MiddlewareFunc {
+	if config.Tokens == nil {
+		panic("middleware: JWTAuth requires Tokens")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			authHeader := ctx.Request().Header.Get(echo.HeaderAuthorization)
+			if authHeader == "" {
+				return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Authorization header required"})
+			}
+
+			token, ok := utils.BearerToken(authHeader)
+			if !ok {
+				return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid authorization header format"})
+			}
+
+			claims, err := config.Tokens.ParseToken(token)
+			if err == nil && config.Validator != nil {
+				err = config.Validator(ctx, claims)
+			}
+			if errors.Is(err, utils.ErrInvalidToken) {
+				return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+			}
+			if err != nil {
+				config.Logger.ErrorContext(ctx.Request().Context(), "failed to validate token", "error", err)
+				return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to validate token"})
+			}
+
+			ctx.Set(UserIDKey, claims.UserID)
+			ctx.Set(SessionIDKey, claims.SessionID)
+			return next(ctx)
+		}
+	}
+}
+
+// GetUserID returns the user ID stored by JWTAuth
+func GetUserID(ctx echo.Context) (int, bool) {
+	userID, ok := ctx.Get(UserIDKey).(int)
+	return userID, ok
+}
+
+// GetSessionID returns the session ID stored by JWTAuth
+func GetSessionID(ctx echo.Context) string {
+	sessionID, _ := ctx.Get(SessionIDKey).(string)
+	return sessionID
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/server/utils"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTokens issues the tokens used by the middleware tests
+var testTokens = utils.NewTokens(utils.TokenConfig{Secret: "test-secret", AccessExpiry: 15 * time.Minute})
+
+func TestJWTAuth(t *testing.T) {
+	token, err := testTokens.GenerateToken(7, "session-1")
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.GET("/me", func(ctx echo.Context) error {
+		userID, ok := GetUserID(ctx)
+		require.True(t, ok)
+		return ctx.JSON(http.StatusOK, map[string]int{"user_id": userID})
+	}, JWTAuth(testTokens))
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{"Bearer " + token, http.StatusOK},
+		{"", http.StatusUnauthorized},
+		{token, http.StatusUnauthorized},
+		{"Bearer " + token + "x", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if tt.header != "" {
+			req.Header.Set(echo.HeaderAuthorization, tt.header)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, tt.status, rec.Code, tt.header)
+		if tt.status == http.StatusOK {
+			assert.JSONEq(t, `{"user_id":7}`, rec.Body.String())
+		}
+	}
+}
+
+func TestJWTAuthWithConfig(t *testing.T) {
+	revoked, err := testTokens.GenerateToken(7, "revoked")
+	require.NoError(t, err)
+	active, err := testTokens.GenerateToken(7, "active")
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.GET("/me", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, GetSessionID(ctx))
+	}, JWTAuthWithConfig(JWTConfig{
+		Tokens: testTokens,
+		Validator: func(ctx echo.Context, claims *utils.Claims) error {
+			switch claims.SessionID {
+			case "revoked":
+				return utils.ErrInvalidToken
+			case "active":
+				return nil
+			}
+			return errors.New("database down")
+		},
+	}))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(active)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "active", rec.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, serve(revoked).Code)
+
+	other, err := testTokens.GenerateToken(7, "other")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, serve(other).Code)
+}
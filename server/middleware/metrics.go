@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so that scanners
+// probing random paths do not create a series per path
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests with a method outside standardMethods, which
+// clients can make up freely
+const otherMethod = "OTHER"
+
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Metrics counts requests and records their latency by method, route
+// pattern and status, see metrics.HTTPRequests
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			started := time.Now()
+			if err := next(ctx); err != nil {
+				// Write the error response now, so that its status is recorded
+				ctx.Error(err)
+			}
+
+			route := ctx.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			method := ctx.Request().Method
+			if !standardMethods[method] {
+				method = otherMethod
+			}
+			labels := []string{method, route, strconv.Itoa(ctx.Response().Status)}
+			metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(started).Seconds())
+			return nil
+		}
+	}
+}
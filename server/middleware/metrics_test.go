@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/metrics"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(Metrics())
+	e.GET("/metrics-test/:id", func(ctx echo.Context) error {
+		if ctx.Param("id") == "bad" {
+			return errors.New("boom")
+		}
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test/bad"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/metrics-test/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/metrics-test/:id", "500")))
+
+	// Unknown paths share one series
+	before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", unmatchedRoute, "404"))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wp-login.php", nil))
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+
+	// So do made-up methods
+	before = testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(otherMethod, unmatchedRoute, "404"))
+	series := testutil.CollectAndCount(metrics.HTTPRequests)
+	for _, method := range []string{"FOO", "BAR"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/wp-login.php", nil))
+	}
+	assert.Equal(t, before+2, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(otherMethod, unmatchedRoute, "404")))
+	assert.Equal(t, series, testutil.CollectAndCount(metrics.HTTPRequests))
+}
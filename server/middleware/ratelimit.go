@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/config"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitConfig configures RateLimit
+type RateLimitConfig struct {
+	Rate config.Rate
+	// KeyFunc identifies the caller; it defaults to RateLimitKey
+	KeyFunc func(ctx echo.Context) string
+	// Skipper exempts requests from the limit, e.g. health checks
+	Skipper func(ctx echo.Context) bool
+}
+
+// RateLimit is a token bucket limiter. Each caller's bucket holds up to
+// Rate.Limit requests and refills continuously over Rate.Period. Every
+// response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (seconds until the bucket is full again); requests over
+// the limit get 429 with Retry-After.
+//
+// Buckets live in memory, so each server instance counts separately.
+func RateLimit(config RateLimitConfig) echo.MiddlewareFunc {
+	if config.KeyFunc == nil {
+		config.KeyFunc = RateLimitKey
+	}
+	limiter := newLimiter(config.Rate, time.Now)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if config.Rate.Limit == 0 {
+			return next
+		}
+		return func(ctx echo.Context) error {
+			if config.Skipper != nil && config.Skipper(ctx) {
+				return next(ctx)
+			}
+
+			result := limiter.allow(config.KeyFunc(ctx))
+			header := ctx.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(config.Rate.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(seconds(result.reset)))
+			if !result.allowed {
+				header.Set("Retry-After", strconv.Itoa(seconds(result.retryAfter)))
+				return ctx.JSON(http.StatusTooManyRequests, map[string]string{"error": "Rate limit exceeded"})
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// RateLimitKey identifies callers by the user ID stored by JWTAuth and by
+// client IP otherwise. Limits that run before JWTAuth need a KeyFunc that
+// checks the caller's token and session itself.
+func RateLimitKey(ctx echo.Context) string {
+	if userID, ok := GetUserID(ctx); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "ip:" + ctx.RealIP()
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type limitResult struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// limiter holds the token buckets of one RateLimit middleware
+type limiter struct {
+	rate config.Rate
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newLimiter(rate config.Rate, now func() time.Time) *limiter {
+	return &limiter{rate: rate, now: now, buckets: map[string]*bucket{}, lastSweep: now()}
+}
+
+// allow takes a token from key's bucket if it has one
+func (l *limiter) allow(key string) limitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.rate.Limit)
+	perToken := l.rate.Period / time.Duration(l.rate.Limit)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	var result limitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		result.allowed = true
+	} else {
+		result.retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.remaining = int(b.tokens)
+	result.reset = time.Duration((capacity - b.tokens) * float64(perToken))
+
+	l.sweep(now)
+	return result
+}
+
+// sweep forgets buckets that have refilled completely, at most once per
+// period, so that one-off callers do not accumulate
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Period {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.rate.Period {
+			delete(l.buckets, key)
+		}
+	}
+}
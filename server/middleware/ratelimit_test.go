@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newLimiter(config.Rate{Limit: 3, Period: 3 * time.Second}, func() time.Time { return now })
+
+	for i := 2; i >= 0; i-- {
+		r := l.allow("a")
+		assert.True(t, r.allowed)
+		assert.Equal(t, i, r.remaining)
+	}
+	r := l.allow("a")
+	assert.False(t, r.allowed)
+	assert.Equal(t, time.Second, r.retryAfter)
+	assert.Equal(t, 3*time.Second, r.reset)
+
+	// Buckets are per key
+	assert.True(t, l.allow("b").allowed)
+
+	// One token comes back per second
+	now = now.Add(time.Second)
+	assert.True(t, l.allow("a").allowed)
+	assert.False(t, l.allow("a").allowed)
+
+	// Idle buckets are dropped once full
+	now = now.Add(time.Minute)
+	l.allow("c")
+	assert.NotContains(t, l.buckets, "a")
+	assert.Contains(t, l.buckets, "c")
+}
+
+func TestRateLimit(t *testing.T) {
+	e := echo.New()
+	// Stands in for JWTAuth
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if ctx.Request().Header.Get(echo.HeaderAuthorization) != "" {
+				ctx.Set(UserIDKey, 7)
+			}
+			return next(ctx)
+		}
+	})
+	e.GET("/", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}, RateLimit(RateLimitConfig{Rate: config.Rate{Limit: 2, Period: time.Minute}}))
+
+	do := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if auth != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+auth)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(t, http.StatusOK, do("").Code)
+
+	rec = do("")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// A signed-in caller has their own bucket even from the same address
+	assert.Equal(t, http.StatusOK, do("token").Code)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxRequestIDLength bounds the IDs accepted from clients and proxies
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID: the X-Request-ID header if the
+// client or a proxy sent a usable one, otherwise a random one. The ID is
+// returned in the X-Request-ID response header and carried by the request's
+// context, see logging.RequestID.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			ctx.Response().Header().Set(echo.HeaderXRequestID, id)
+			ctx.SetRequest(req.WithContext(logging.WithRequestID(req.Context(), id)))
+			return next(ctx)
+		}
+	}
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces, so
+// that a client cannot forge log lines through the header
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestLogger logs one line per request with its method, route, status,
+// latency, response size and, for authenticated requests, the user ID.
+// Server errors are logged at error level. It must run after RequestID for
+// the line to carry the request ID.
+func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			started := time.Now()
+			if err := next(ctx); err != nil {
+				// Write the error response now, so that its status is logged
+				ctx.Error(err)
+			}
+
+			req, res := ctx.Request(), ctx.Response()
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("route", ctx.Path()),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", res.Status),
+				slog.Float64("latency_ms", float64(time.Since(started).Microseconds())/1000),
+				slog.Int64("bytes", res.Size),
+				slog.String("ip", ctx.RealIP()),
+			}
+			if userID, ok := GetUserID(ctx); ok {
+				attrs = append(attrs, slog.Int("user_id", userID))
+			}
+
+			level := slog.LevelInfo
+			if res.Status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(req.Context(), level, "request", attrs...)
+			return nil
+		}
+	}
+}
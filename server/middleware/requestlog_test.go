@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/logging"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	e := echo.New()
+	var seen string
+	e.GET("/", func(ctx echo.Context) error {
+		seen = logging.RequestID(ctx.Request().Context())
+		return ctx.NoContent(http.StatusOK)
+	}, RequestID())
+
+	// A usable ID from the client is kept
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "edge-42")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "edge-42", seen)
+	assert.Equal(t, "edge-42", rec.Header().Get(echo.HeaderXRequestID))
+
+	// Anything else is replaced
+	for _, id := range []string{"", "has space", "line\nbreak", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderXRequestID, id)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Len(t, seen, 32, "%q", id)
+		assert.Equal(t, seen, rec.Header().Get(echo.HeaderXRequestID))
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Use(RequestID(), RequestLogger(logging.New(&buf, logging.Config{Format: logging.FormatJSON})))
+	e.GET("/users/:id", func(ctx echo.Context) error {
+		ctx.Set(UserIDKey, 7)
+		return ctx.String(http.StatusOK, "hello")
+	})
+	e.GET("/fail", func(ctx echo.Context) error {
+		return errors.New("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var ok, failed map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &ok))
+	require.NoError(t, json.Unmarshal(lines[1], &failed))
+
+	assert.Equal(t, "INFO", ok["level"])
+	assert.Equal(t, "req-1", ok["request_id"])
+	assert.Equal(t, "GET", ok["method"])
+	assert.Equal(t, "/users/:id", ok["route"])
+	assert.EqualValues(t, 200, ok["status"])
+	assert.EqualValues(t, 5, ok["bytes"])
+	assert.EqualValues(t, 7, ok["user_id"])
+	assert.Contains(t, ok, "latency_ms")
+
+	assert.Equal(t, "ERROR", failed["level"])
+	assert.EqualValues(t, 500, failed["status"])
+	assert.NotContains(t, failed, "user_id")
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RoleKey is the echo context key holding the authenticated user's role. It
+// is set by the JWTConfig validator, which loads the user.
+const RoleKey = "role"
+
+// RequireRole rejects with 403 requests whose user has none of roles. It must
+// run after JWTAuthWithConfig with a validator that stores the role.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			role := GetRole(ctx)
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(ctx)
+				}
+			}
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": "Insufficient permissions"})
+		}
+	}
+}
+
+// GetRole returns the role stored under RoleKey, or "" if there is none
+func GetRole(ctx echo.Context) string {
+	role, _ := ctx.Get(RoleKey).(string)
+	return role
+}
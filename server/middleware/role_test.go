@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bible_reading_backend_nkv/server/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRole(t *testing.T) {
+	roles := map[int]string{1: "user", 2: "moderator", 3: "admin"}
+
+	e := echo.New()
+	auth := JWTAuthWithConfig(JWTConfig{
+		Tokens: testTokens,
+		Validator: func(ctx echo.Context, claims *utils.Claims) error {
+			ctx.Set(RoleKey, roles[claims.UserID])
+			return nil
+		},
+	})
+	ok := func(ctx echo.Context) error { return ctx.NoContent(http.StatusOK) }
+	e.GET("/moderate", ok, auth, RequireRole("moderator", "admin"))
+	e.GET("/admin", ok, auth, RequireRole("admin"))
+	// Without a validator setting the role, everyone is turned away
+	e.GET("/unconfigured", ok, JWTAuth(testTokens), RequireRole("admin"))
+
+	tests := []struct {
+		path   string
+		userID int
+		status int
+	}{
+		{"/moderate", 1, http.StatusForbidden},
+		{"/moderate", 2, http.StatusOK},
+		{"/moderate", 3, http.StatusOK},
+		{"/admin", 2, http.StatusForbidden},
+		{"/admin", 3, http.StatusOK},
+		{"/unconfigured", 3, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		token, err := testTokens.GenerateToken(tt.userID, "session-1")
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, tt.status, rec.Code, "%s as %s", tt.path, roles[tt.userID])
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/reference"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetPassage resolves a human-readable reference such as "John 3:16-18; Ps 23"
+func (s *EchoServer) GetPassage(ctx echo.Context) error {
+	ref := ctx.QueryParam("ref")
+	if ref == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "ref query parameter is required"})
+	}
+
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+
+	ranges, err := reference.Parse(ref)
+	if err != nil {
+		var refErr *reference.Error
+		if errors.As(err, &refErr) {
+			return ctx.JSON(http.StatusBadRequest, dto.ParseErrorResponse{
+				Error:  refErr.Message,
+				Token:  refErr.Token,
+				Offset: refErr.Offset,
+			})
+		}
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	response := dto.PassageResponse{
+		Reference:   ref,
+		Translation: translationID,
+		Passages:    make([]dto.PassageRange, 0, len(ranges)),
+	}
+	found := 0
+	for _, r := range ranges {
+		verses, err := s.DB.GetVersesInRange(ctx.Request().Context(), translationID,
+			r.Book.ID, r.StartChapter, r.StartVerse, r.EndChapter, r.EndVerse)
+		if err != nil {
+			s.Logger.ErrorContext(ctx.Request().Context(), "failed to get passage", "translation", translationID, "reference", r.String(), "error", err)
+			return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch verses"})
+		}
+
+		passage := dto.PassageRange{
+			Reference:    r.String(),
+			BookID:       r.Book.ID,
+			BookName:     r.Book.Name,
+			StartChapter: r.StartChapter,
+			StartVerse:   r.StartVerse,
+			EndChapter:   r.EndChapter,
+			EndVerse:     r.EndVerse,
+			Verses:       make([]dto.VerseReferenceResponse, 0, len(verses)),
+		}
+		for _, v := range verses {
+			passage.Verses = append(passage.Verses, dto.VerseReferenceResponse{
+				BookID:   v.BookID,
+				BookName: v.Book,
+				Chapter:  v.Chapter,
+				Verse:    v.Verse,
+				Text:     v.Text,
+			})
+		}
+		// Report the actual last verse for open-ended chapter ranges
+		if passage.EndVerse == 0 && len(verses) > 0 {
+			passage.EndVerse = verses[len(verses)-1].Verse
+		}
+		found += len(verses)
+		response.Passages = append(response.Passages, passage)
+	}
+
+	if found == 0 {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Passage not found"})
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
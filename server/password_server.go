@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/mailer"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the address belongs to an account, so it cannot be used to
+// find out who is registered.
+func (s *EchoServer) ForgotPassword(ctx echo.Context) error {
+	var req dto.ForgotPasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+	req.Email = normalizeEmail(req.Email)
+	if !validEmail(req.Email) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
+	}
+
+	response := map[string]string{
+		"message": "If an account exists for that email, a password reset link has been sent",
+	}
+
+	user, err := s.DB.GetUserByEmail(ctx.Request().Context(), req.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusOK, response)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get user", "email", req.Email, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process request"})
+	}
+
+	// The reset is created and mailed after responding, so that the response
+	// takes as long whether or not the address has an account
+	s.runInBackground(ctx.Request().Context(), func(ctx context.Context) {
+		reset, token, err := s.newPasswordReset(user.ID)
+		if err == nil {
+			err = s.DB.CreatePasswordReset(ctx, reset)
+		}
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to create password reset", "user_id", user.ID, "error", err)
+			return
+		}
+
+		msg := s.passwordResetMessage(user, token, "Someone asked to reset the password for your account.",
+			"If you did not ask for this, you can ignore this email.")
+		if err := s.Mailer.Send(ctx, msg); err != nil {
+			s.Logger.ErrorContext(ctx, "failed to send password reset email", "user_id", user.ID, "error", err)
+		}
+	})
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. All of
+// the user's sessions are ended.
+func (s *EchoServer) ResetPassword(ctx echo.Context) error {
+	var req dto.ResetPasswordRequest
+	if err := ctx.Bind(&req); err != nil || req.Token == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+	if len(req.Password) < 6 {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Password must be at least 6 characters"})
+	}
+
+	userID, err := s.DB.ResetPassword(ctx.Request().Context(), utils.HashToken(req.Token), req.Password)
+	if errors.Is(err, database.ErrInvalidResetToken) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired reset token"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to reset password", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset password"})
+	}
+
+	s.Logger.InfoContext(ctx.Request().Context(), "password reset", "user_id", userID)
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Password reset successfully"})
+}
+
+// newPasswordReset builds a reset row for userID and returns the token to email
+func (s *EchoServer) newPasswordReset(userID int) (*models.PasswordReset, string, error) {
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	return &models.PasswordReset{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().UTC().Add(s.cfg.Auth.PasswordResetExpiry()),
+	}, token, nil
+}
+
+// passwordResetMessage is the email carrying a reset link, explained by
+// reason and closed by footer
+func (s *EchoServer) passwordResetMessage(user *models.User, token, reason, footer string) mailer.Message {
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n%s "+
+			"Open this link within %d minutes to choose a new one:\n\n%s\n\n%s\n",
+			user.FirstName, reason, s.cfg.Auth.PasswordResetExpiryMinutes,
+			s.frontendURL("/reset-password", token), footer),
+	}
+}
+
+// frontendURL builds a link into the web app carrying token as a query
+// parameter
+func (s *EchoServer) frontendURL(path, token string) string {
+	return s.cfg.Server.FrontendURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/canon"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/search"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// searchIndexes caches one inverted index per translation. Indexes are built
+// from the verses table on first use and rebuilt when the translation's
+// updated_at changes, which cmd/import does when it replaces the verses.
+type searchIndexes struct {
+	mu            sync.Mutex
+	byTranslation map[string]*indexEntry
+}
+
+// indexEntry is the index of one version of a translation. Ready is closed
+// once the index is built or has failed.
+type indexEntry struct {
+	version time.Time
+	ready   chan struct{}
+	index   *search.Index
+	err     error
+}
+
+func (s *EchoServer) searchIndex(ctx context.Context, translationID string) (*search.Index, error) {
+	translation, err := s.DB.GetTranslation(ctx, translationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches wait for the first request's build rather than starting
+	// their own; the lock is only held to look the entry up
+	s.indexes.mu.Lock()
+	entry := s.indexes.byTranslation[translationID]
+	build := entry == nil || entry.version.Before(translation.UpdatedAt)
+	if build {
+		entry = &indexEntry{version: translation.UpdatedAt, ready: make(chan struct{})}
+		if s.indexes.byTranslation == nil {
+			s.indexes.byTranslation = map[string]*indexEntry{}
+		}
+		s.indexes.byTranslation[translationID] = entry
+	}
+	s.indexes.mu.Unlock()
+
+	if build {
+		// Other searches wait for this build, so it must not be cut short
+		// by this request's client going away
+		entry.index, entry.err = s.buildSearchIndex(context.WithoutCancel(ctx), translationID)
+		if entry.err != nil {
+			s.indexes.mu.Lock()
+			if s.indexes.byTranslation[translationID] == entry {
+				delete(s.indexes.byTranslation, translationID)
+			}
+			s.indexes.mu.Unlock()
+		}
+		close(entry.ready)
+	}
+
+	select {
+	case <-entry.ready:
+		return entry.index, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *EchoServer) buildSearchIndex(ctx context.Context, translationID string) (*search.Index, error) {
+	started := time.Now()
+	verses, err := s.DB.GetAllVerse(ctx, translationID)
+	if err != nil {
+		return nil, err
+	}
+	ix := search.NewIndex(verses)
+	s.Logger.InfoContext(ctx, "built search index", "translation", translationID, "verses", ix.Len(), "duration", time.Since(started))
+	return ix, nil
+}
+
+// SearchVerses runs a full-text query, e.g. /api/search?q="love+of+money"+OR+greed&testament=nt
+func (s *EchoServer) SearchVerses(ctx echo.Context) error {
+	q := strings.TrimSpace(ctx.QueryParam("q"))
+	if q == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "q query parameter is required"})
+	}
+
+	query, err := search.ParseQuery(q)
+	if err != nil {
+		var syntaxErr *search.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return ctx.JSON(http.StatusBadRequest, dto.ParseErrorResponse{
+				Error:  syntaxErr.Message,
+				Token:  syntaxErr.Token,
+				Offset: syntaxErr.Offset,
+			})
+		}
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	filter, err := searchFilter(ctx.QueryParam("testament"), ctx.QueryParam("book"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	page, limit := pagination(ctx)
+
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+
+	ix, err := s.searchIndex(ctx.Request().Context(), translationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.translationError(ctx, err)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to build search index", "translation", translationID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to search verses"})
+	}
+
+	hits, total := ix.Search(query, filter, (page-1)*limit, limit)
+
+	results := make([]dto.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		highlights := make([]dto.Highlight, 0, len(hit.Highlights))
+		for _, h := range hit.Highlights {
+			highlights = append(highlights, dto.Highlight{Start: h.Start, End: h.End})
+		}
+		results = append(results, dto.SearchResult{
+			BookID:     hit.Verse.BookID,
+			BookName:   hit.Verse.Book,
+			Chapter:    hit.Verse.Chapter,
+			Verse:      hit.Verse.Verse,
+			Text:       hit.Verse.Text,
+			Highlights: highlights,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, paginatedResponse(results, int64(total), page, limit))
+}
+
+// searchFilter parses ?testament=ot|nt and ?book=John or ?book=Gen-Deut
+func searchFilter(testament, books string) (search.Filter, error) {
+	var filter search.Filter
+
+	switch strings.ToUpper(testament) {
+	case "":
+	case canon.OldTestament, canon.NewTestament:
+		filter.Testament = strings.ToUpper(testament)
+	default:
+		return filter, fmt.Errorf("testament must be %q or %q", "ot", "nt")
+	}
+
+	if books == "" {
+		return filter, nil
+	}
+	from, to, isRange := strings.Cut(books, "-")
+	first, err := lookupBook(from)
+	if err != nil {
+		return filter, err
+	}
+	last := first
+	if isRange {
+		if last, err = lookupBook(to); err != nil {
+			return filter, err
+		}
+	}
+	if last.ID < first.ID {
+		return filter, fmt.Errorf("book range %q ends before it starts", books)
+	}
+	filter.FromBook, filter.ToBook = first.ID, last.ID
+	return filter, nil
+}
+
+// lookupBook resolves a book_id or a book name/abbreviation
+func lookupBook(s string) (canon.Book, error) {
+	s = strings.TrimSpace(s)
+	if id, err := strconv.Atoi(s); err == nil {
+		if book, ok := canon.ByID(id); ok {
+			return book, nil
+		}
+	} else if book, ok := canon.Lookup(s); ok {
+		return book, nil
+	}
+	return canon.Book{}, fmt.Errorf("unknown book %q", s)
+}
+
+// warmSearchIndex builds the default translation's index before serving
+func (s *EchoServer) warmSearchIndex() {
+	if _, err := s.searchIndex(context.Background(), models.DefaultTranslationID); err != nil {
+		s.Logger.Warn("failed to build search index, it will be built on first search", "error", err)
+	}
+}
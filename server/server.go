@@ -1,34 +1,79 @@
 package server
 
 import (
+	"bible_reading_backend_nkv/config"
 	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/explain"
+	"bible_reading_backend_nkv/mailer"
+	"bible_reading_backend_nkv/metrics"
 	"bible_reading_backend_nkv/models"
 	"bible_reading_backend_nkv/server/middleware"
-	"log"
+	"bible_reading_backend_nkv/server/utils"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 
 type Server interface{
 	Start() error
+	Shutdown(ctx context.Context) error
 	Readiness(ctx echo.Context) error
 	Liveness(ctx echo.Context) error
+	Metrics(ctx echo.Context) error
 	GetAllVerse(ctx echo.Context) error
 	GetAllVerseByChapter(ctx echo.Context) error
 	GetAllChapter(ctx echo.Context) error
 	ExpainVerse(ctx echo.Context) error
+	ExplainVerseStream(ctx echo.Context) error
+	GetTranslations(ctx echo.Context) error
+	GetTranslation(ctx echo.Context) error
+	GetPassage(ctx echo.Context) error
+	SearchVerses(ctx echo.Context) error
 	
 	// Authentication methods
 	Register(ctx echo.Context) error
 	Login(ctx echo.Context) error
+	RefreshToken(ctx echo.Context) error
+	Logout(ctx echo.Context) error
+	LogoutAll(ctx echo.Context) error
+	ForgotPassword(ctx echo.Context) error
+	ResetPassword(ctx echo.Context) error
+	VerifyEmail(ctx echo.Context) error
+	ResendVerification(ctx echo.Context) error
+	LoginTwoFactor(ctx echo.Context) error
 	
 	// User management methods
 	GetCurrentUser(ctx echo.Context) error
 	UpdateCurrentUser(ctx echo.Context) error
 	DeleteCurrentUser(ctx echo.Context) error
+
+	// Two-factor authentication methods
+	GetTwoFactorStatus(ctx echo.Context) error
+	SetupTwoFactor(ctx echo.Context) error
+	EnableTwoFactor(ctx echo.Context) error
+	DisableTwoFactor(ctx echo.Context) error
+	RegenerateRecoveryCodes(ctx echo.Context) error
+
+	// Admin methods
+	ListUsers(ctx echo.Context) error
+	GetUser(ctx echo.Context) error
+	GetUserStats(ctx echo.Context) error
+	DisableUser(ctx echo.Context) error
+	EnableUser(ctx echo.Context) error
+	UpdateUserRole(ctx echo.Context) error
+	ForcePasswordReset(ctx echo.Context) error
+	UnlockUser(ctx echo.Context) error
+	DeleteExplanations(ctx echo.Context) error
 	
 	// Verse tracking methods
 	AddFavoriteVerse(ctx echo.Context) error
@@ -46,7 +91,20 @@ type Server interface{
 
 type EchoServer struct{
 	echo *echo.Echo
+	cfg *config.Config
 	DB database.DatabaseClient
+	Explainer explain.Explainer
+	Mailer mailer.Mailer
+	Logger *slog.Logger
+	tokens *utils.Tokens
+	indexes searchIndexes
+	explainCacheTTL time.Duration
+	requireVerifiedEmail bool
+	loginPolicy loginPolicy
+	explainQuota explainQuota
+	rateLimits rateLimits
+	// background tracks work started by runInBackground
+	background sync.WaitGroup
 }
 
 // GetEcho returns the echo instance for testing purposes
@@ -54,32 +112,95 @@ func (s *EchoServer) GetEcho() *echo.Echo {
 	return s.echo
 }
 
-func NewEchoServer(db database.DatabaseClient) Server{
+// NewEchoServer builds the server from a validated configuration, see
+// config.Load. Requests and errors are logged to logger.
+func NewEchoServer(cfg *config.Config, db database.DatabaseClient, logger *slog.Logger) Server{
 	e := echo.New()
+	// Every request gets a span, continuing the caller's trace if it sent a
+	// traceparent header, except health checks and metrics scrapes, which
+	// would crowd out everything else
+	e.Use(otelecho.Middleware(cfg.Tracing.ServiceName, otelecho.WithSkipper(func(ctx echo.Context) bool {
+		switch ctx.Path() {
+		case "/readiness", "/liveness", "/metrics":
+			return true
+		}
+		return false
+	})))
+	// Every request gets an ID, which is logged with everything done for it,
+	// and is counted in the metrics
+	e.Use(middleware.RequestID(), middleware.Metrics(), middleware.RequestLogger(logger))
+
 	// ✅ CORS configuration
 	e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
-		AllowOrigins: []string{
-			"https://ashley-samuel.in",
-			"http://13.203.234.131:3000",
-			"http://localhost:3000",  // React dev server
-		},
+		AllowOrigins: cfg.Server.CORSOrigins,
 		AllowMethods: []string{
 			echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS,
 		},
 		AllowHeaders: []string{
 			echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization,
+			echo.HeaderXRequestID, "traceparent", "tracestate",
 		},
 		ExposeHeaders: []string{
 			"Content-Length",
+			"Retry-After",
+			echo.HeaderXRequestID,
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+			"X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
 		},
 		AllowCredentials: true,
 	}))
 
+	// Client IPs feed login throttling, so X-Forwarded-For is only believed
+	// when TRUST_PROXY says a reverse proxy on a private network sets it
+	if cfg.Server.TrustProxy {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
+
+	explainer, err := explain.New(cfg.LLM.ExplainerConfig())
+	if err != nil {
+		logger.Warn("verse explanations are unavailable", "error", err)
+		explainer = explain.Unavailable(err)
+	} else {
+		explainer = explain.WithMetrics(explainer)
+	}
+
+	mail, err := mailer.New(cfg.Mail.MailerConfig())
+	if err != nil {
+		logger.Warn("mail is not configured, logging messages instead", "error", err)
+		mail = mailer.Log{}
+	}
+
 	server:= &EchoServer{
 		echo: e, 
+		cfg: cfg,
 		DB: db,
+		Explainer: explainer,
+		Mailer: mail,
+		Logger: logger,
+		tokens: utils.NewTokens(utils.TokenConfig{
+			Secret:        cfg.JWT.Secret,
+			AccessExpiry:  cfg.JWT.AccessExpiry(),
+			RefreshExpiry: cfg.JWT.RefreshExpiry(),
+		}),
+		explainCacheTTL: cfg.Explain.CacheTTL(),
+		requireVerifiedEmail: cfg.Auth.EmailVerification == "required",
+		loginPolicy: newLoginPolicy(cfg.Auth),
+		explainQuota: explainQuota{User: cfg.Explain.DailyQuota, Anonymous: cfg.Explain.DailyQuotaAnonymous},
+		rateLimits: newRateLimits(cfg.RateLimit),
 	}
 
+	// Every /api request counts against the general limit; health checks are
+	// left alone so that probes are never throttled
+	e.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rate: server.rateLimits.API,
+		KeyFunc: server.rateLimitKey,
+		Skipper: func(ctx echo.Context) bool {
+			return !strings.HasPrefix(ctx.Path(), "/api/")
+		},
+	}))
+
 	server.registerRoutes()
 	return server
 }
@@ -88,13 +209,29 @@ func NewEchoServer(db database.DatabaseClient) Server{
 func (s *EchoServer) registerRoutes(){
 	s.echo.GET("/readiness", s.Readiness)
 	s.echo.GET("/liveness", s.Liveness)
+	if s.cfg.Metrics.Enabled {
+		s.echo.GET("/metrics", s.Metrics)
+	}
 
-	// Authentication endpoints (public)
-	s.echo.POST("/api/register/", s.Register)
-	s.echo.POST("/api/login/", s.Login)
+	// Authentication endpoints (public), with a stricter shared rate limit
+	authLimit := middleware.RateLimit(middleware.RateLimitConfig{Rate: s.rateLimits.Auth, KeyFunc: s.rateLimitKey})
+	s.echo.POST("/api/register/", s.Register, authLimit)
+	s.echo.POST("/api/login/", s.Login, authLimit)
+	s.echo.POST("/api/login/2fa", s.LoginTwoFactor, authLimit)
+	s.echo.POST("/api/token/refresh", s.RefreshToken, authLimit)
+	s.echo.POST("/api/password/forgot", s.ForgotPassword, authLimit)
+	s.echo.POST("/api/password/reset", s.ResetPassword, authLimit)
+	s.echo.GET("/api/verify-email", s.VerifyEmail, authLimit)
+	s.echo.POST("/api/verify-email/resend", s.ResendVerification, authLimit)
 
-	// User-related protected routes with JWT middleware
-	protected := s.echo.Group("/api", middleware.JWTAuth())
+	// User-related protected routes with JWT middleware; tokens of revoked sessions are rejected
+	protected := s.echo.Group("/api", middleware.JWTAuthWithConfig(middleware.JWTConfig{
+		Tokens:    s.tokens,
+		Validator: s.validateSession,
+		Logger:    s.Logger,
+	}))
+	protected.POST("/logout", s.Logout)
+	protected.POST("/logout-all", s.LogoutAll)
 
 	// User profile endpoints
 	userGroup := protected.Group("/users")
@@ -102,6 +239,13 @@ func (s *EchoServer) registerRoutes(){
 	userGroup.PUT("/me", s.UpdateCurrentUser)
 	userGroup.DELETE("/me", s.DeleteCurrentUser)
 
+	// Two-factor authentication endpoints
+	userGroup.GET("/me/2fa", s.GetTwoFactorStatus)
+	userGroup.POST("/me/2fa/setup", s.SetupTwoFactor)
+	userGroup.POST("/me/2fa/enable", s.EnableTwoFactor)
+	userGroup.POST("/me/2fa/disable", s.DisableTwoFactor)
+	userGroup.POST("/me/2fa/recovery-codes", s.RegenerateRecoveryCodes)
+
 	// Verse tracking endpoints
 	userGroup.POST("/me/favorites", s.AddFavoriteVerse)
 	userGroup.GET("/me/favorites", s.GetFavoriteVerses)
@@ -116,25 +260,121 @@ func (s *EchoServer) registerRoutes(){
 	userGroup.GET("/me/last-read", s.GetLastRead)
 	protected.GET("/last-read-verses/", s.GetLastReadVerses)
 
-	// NIV endpoints (public, but explain can use token if provided)
-	nivServerGroup := s.echo.Group("/api/niv")
-	nivServerGroup.GET("/verses", s.GetAllVerse)
-	nivServerGroup.GET("/:bookId/:chapterId/verses", s.GetAllVerseByChapter)
-	nivServerGroup.GET("/books", s.GetAllBook)
-	nivServerGroup.GET("/chapters/:bookId", s.GetAllChapter)
-	nivServerGroup.POST("/explain", s.ExpainVerse)
+	// Admin endpoints; moderators can look things up and manage content,
+	// only admins can change accounts
+	adminGroup := protected.Group("/admin", middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	adminGroup.GET("/users", s.ListUsers)
+	adminGroup.GET("/users/:id", s.GetUser)
+	adminGroup.GET("/users/:id/stats", s.GetUserStats)
+	adminGroup.POST("/users/:id/disable", s.DisableUser, adminOnly)
+	adminGroup.POST("/users/:id/enable", s.EnableUser, adminOnly)
+	adminGroup.PUT("/users/:id/role", s.UpdateUserRole, adminOnly)
+	adminGroup.POST("/users/:id/password-reset", s.ForcePasswordReset, adminOnly)
+	adminGroup.POST("/users/:id/unlock", s.UnlockUser, adminOnly)
+	adminGroup.DELETE("/explanations", s.DeleteExplanations)
+
+	// Reference lookup, e.g. /api/passage?ref=John+3:16-18&translation=niv (public)
+	s.echo.GET("/api/passage", s.GetPassage)
+
+	// Full-text search, e.g. /api/search?q=love+NOT+money&testament=nt (public)
+	s.echo.GET("/api/search", s.SearchVerses)
+
+	// Bible endpoints for any loaded translation (public)
+	// The explain limit is shared by both route families so that the alias
+	// does not double it
+	explainLimit := middleware.RateLimit(middleware.RateLimitConfig{Rate: s.rateLimits.Explain, KeyFunc: s.rateLimitKey})
+	bibleGroup := s.echo.Group("/api/bibles")
+	bibleGroup.GET("", s.GetTranslations)
+	bibleGroup.GET("/:translation", s.GetTranslation)
+	s.registerVerseRoutes(bibleGroup.Group("/:translation"), explainLimit)
+
+	// NIV endpoints, kept as an alias for /api/bibles/niv
+	nivServerGroup := s.echo.Group("/api/niv", fixedTranslation(models.DefaultTranslationID))
+	s.registerVerseRoutes(nivServerGroup, explainLimit)
+
+}
 
+// registerVerseRoutes adds the read-only verse endpoints to a translation
+// group. explainLimit rate limits the LLM-backed explain endpoints.
+func (s *EchoServer) registerVerseRoutes(g *echo.Group, explainLimit echo.MiddlewareFunc) {
+	g.GET("/verses", s.GetAllVerse)
+	g.GET("/:bookId/:chapterId/verses", s.GetAllVerseByChapter)
+	g.GET("/books", s.GetAllBook)
+	g.GET("/chapters/:bookId", s.GetAllChapter)
+	g.GET("/passage", s.GetPassage)
+	g.GET("/search", s.SearchVerses)
+	// Public, but explain uses the caller's profile if a token is provided
+	g.POST("/explain", s.ExpainVerse, explainLimit)
+	g.POST("/explain/stream", s.ExplainVerseStream, explainLimit)
 }
 
 
+// Start serves HTTP, or HTTPS when a certificate is configured, until
+// Shutdown is called. It returns nil after a shutdown.
 func (s *EchoServer) Start() error{
-	if err:= s.echo.Start(":8000"); err != nil && err!= http.ErrServerClosed{
-		log.Fatalf("server shutdown occured %s", err)
+	s.warmSearchIndex()
+
+	// Echo keeps separate servers for HTTP and HTTPS and shuts both down
+	serverCfg := s.cfg.Server
+	for _, httpServer := range []*http.Server{s.echo.Server, s.echo.TLSServer} {
+		httpServer.ReadTimeout = time.Duration(serverCfg.ReadTimeoutSeconds) * time.Second
+		httpServer.ReadHeaderTimeout = time.Duration(serverCfg.ReadHeaderTimeoutSeconds) * time.Second
+		httpServer.WriteTimeout = time.Duration(serverCfg.WriteTimeoutSeconds) * time.Second
+		httpServer.IdleTimeout = time.Duration(serverCfg.IdleTimeoutSeconds) * time.Second
+	}
+
+	var err error
+	if serverCfg.TLS() {
+		err = s.echo.StartTLS(serverCfg.Address(), serverCfg.TLSCertFile, serverCfg.TLSKeyFile)
+	} else {
+		err = s.echo.Start(serverCfg.Address())
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 	return nil
 }
 
+// Shutdown stops accepting connections and waits for in-flight requests,
+// including streamed explanations, to finish. If ctx ends first the
+// remaining connections are closed, which cancels their requests. It then
+// waits, until ctx ends, for work the requests left running in the
+// background, such as sending emails.
+func (s *EchoServer) Shutdown(ctx context.Context) error {
+	err := s.echo.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		s.Logger.Warn("requests still running at shutdown deadline, closing their connections")
+		if closeErr := s.echo.Close(); closeErr != nil {
+			return closeErr
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Logger.Warn("background work still running at shutdown deadline")
+	}
+	return err
+}
+
+// runInBackground runs fn without holding up the response. fn gets the
+// request's context values, such as its request ID and trace, but is not
+// canceled when the request ends. Shutdown waits for it.
+func (s *EchoServer) runInBackground(ctx context.Context, fn func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn(ctx)
+	}()
+}
+
 
 
 
@@ -151,4 +391,34 @@ func (s *EchoServer) Liveness(ctx echo.Context) error{
 
 		return ctx.JSON(http.StatusOK, models.Health{Status: "OK"})
 	
-}
\ No newline at end of file
+}
+
+// Metrics serves the Prometheus metrics. When a metrics token is configured
+// it must be sent as a bearer token.
+func (s *EchoServer) Metrics(ctx echo.Context) error {
+	if token := s.cfg.Metrics.Token; token != "" {
+		auth := ctx.Request().Header.Get(echo.HeaderAuthorization)
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid metrics token"})
+		}
+	}
+	metrics.Handler().ServeHTTP(ctx.Response(), ctx.Request())
+	return nil
+}
+
+// rateLimits are the request rates allowed per user or client IP
+type rateLimits struct {
+	API     config.Rate
+	Auth    config.Rate
+	Explain config.Rate
+}
+
+// newRateLimits parses the configured rates, which config.Validate has
+// already checked
+func newRateLimits(cfg config.RateLimit) rateLimits {
+	var limits rateLimits
+	limits.API, _ = config.ParseRate(cfg.API)
+	limits.Auth, _ = config.ParseRate(cfg.Auth)
+	limits.Explain, _ = config.ParseRate(cfg.Explain)
+	return limits
+}
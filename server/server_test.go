@@ -2,84 +2,508 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"bible_reading_backend_nkv/config"
 	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/explain"
+	"bible_reading_backend_nkv/logging"
+	"bible_reading_backend_nkv/mailer"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/test/fixtures"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
-// TestHelper loads test cases from JSON and runs them
+// errDatabaseDown stands in for a lost database connection
+var errDatabaseDown = errors.New("database connection lost")
+
+// setups arrange the preconditions of test cases that the fixture data does
+// not meet, keyed by test case ID. Every case gets its own server and a
+// database seeded with test/fixtures.
+var setups = map[string]func(s *EchoServer, db *database.MemoryClient){
+	"HEALTH.READINESS.002": failDatabase,
+	"HEALTH.METRICS.002":   requireMetricsToken,
+	"NIV.VERSES.002":       emptyDatabase,
+	"NIV.VERSES.003":       failDatabase,
+	"NIV.BOOKS.002":        emptyDatabase,
+	"NIV.BOOKS.003":        failDatabase,
+	"NIV.CHAPTERS.003":     failDatabase,
+	"NIV.EXPLAIN.002":      explainerFails(&explain.ConfigError{Message: "OpenAI API key not configured"}),
+	"NIV.EXPLAIN.006":      explainerFails(&explain.UpstreamError{StatusCode: http.StatusUnauthorized}),
+	"NIV.EXPLAIN.007":      explainerFails(context.DeadlineExceeded),
+	"NIV.EXPLAIN.008":      explainerFails(&explain.ConfigError{Message: "Invalid API key format"}),
+}
+
+func failDatabase(s *EchoServer, db *database.MemoryClient) {
+	db.Fail(errDatabaseDown)
+}
+
+func requireMetricsToken(s *EchoServer, db *database.MemoryClient) {
+	s.cfg.Metrics.Token = "scrape-token"
+}
+
+func emptyDatabase(s *EchoServer, db *database.MemoryClient) {
+	s.DB = database.NewMemoryClient()
+}
+
+func explainerFails(err error) func(s *EchoServer, db *database.MemoryClient) {
+	return func(s *EchoServer, db *database.MemoryClient) {
+		s.Explainer = explain.Unavailable(err)
+	}
+}
+
+// TestFromJSON runs the test cases in docs/tests/test_cases.json against the
+// handlers, backed by an in-memory database
 func TestFromJSON(t *testing.T) {
 	// Try multiple possible paths for test_cases.json
 	testPaths := []string{
 		"docs/tests/test_cases.json",
-		"prompts/tests/test_cases.json",
 		"../docs/tests/test_cases.json",
 	}
-	
-	var jsonFile *os.File
+
+	var byteValue []byte
 	var err error
-	
 	for _, path := range testPaths {
-		jsonFile, err = os.Open(path)
+		byteValue, err = os.ReadFile(path)
 		if err == nil {
 			break
 		}
 	}
-	
-	if jsonFile == nil {
-		t.Skip("test_cases.json not found - skipping JSON-based tests")
-		return
-	}
-	defer jsonFile.Close()
+	require.NoError(t, err, "test_cases.json not found")
 
-	byteValue, _ := io.ReadAll(jsonFile)
 	var testSuite TestSuite
-	json.Unmarshal(byteValue, &testSuite)
-
-	// Setup test server (mock database)
-	// For integration tests, use real database connection
-	db, err := database.NewDatabaseClient()
-	if err != nil {
-		t.Skip("Database not available for integration tests")
-	}
-
-	server := NewEchoServer(db)
+	require.NoError(t, json.Unmarshal(byteValue, &testSuite))
+	require.NotEmpty(t, testSuite.Tests)
 
-	// Run tests
 	for _, endpointTest := range testSuite.Tests {
 		for _, testCase := range endpointTest.TestCases {
 			t.Run(testCase.ID, func(t *testing.T) {
+				server, db := newTestServer(t)
+				if setup, ok := setups[testCase.ID]; ok {
+					setup(server, db)
+				}
 				runTestCase(t, server, endpointTest.Endpoint, testCase)
 			})
 		}
 	}
 }
 
-func runTestCase(t *testing.T, server Server, endpoint Endpoint, testCase TestCase) {
-	// Create request
-	req := httptest.NewRequest(testCase.Request.Method, testCase.Request.URL, nil)
-	if testCase.Request.Body != nil {
-		bodyBytes, _ := json.Marshal(testCase.Request.Body)
-		req = httptest.NewRequest(testCase.Request.Method, testCase.Request.URL, bytes.NewBuffer(bodyBytes))
+// TestTracing checks that requests are traced as part of the caller's trace
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	server, _ := newTestServer(t)
+
+	for _, path := range []string{"/api/niv/books", "/liveness"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		rec := httptest.NewRecorder()
+		server.GetEcho().ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Health checks are not traced
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/niv/books", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+// TestSearchIndexFollowsImports checks that replacing a translation's verses,
+// as cmd/import does, is picked up by the next search
+func TestSearchIndexFollowsImports(t *testing.T) {
+	server, db := newTestServer(t)
+	search := func() int64 {
+		rec := httptest.NewRecorder()
+		server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/search?q=zebra", nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var response struct{ Total int64 }
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response.Total
+	}
+	require.Zero(t, search())
+
+	translation, err := db.GetTranslation(context.Background(), "niv")
+	require.NoError(t, err)
+	verses, err := db.GetAllVerse(context.Background(), "niv")
+	require.NoError(t, err)
+	verses[0].Text += " zebra"
+	require.NoError(t, db.ReplaceTranslation(context.Background(), translation, verses))
+
+	assert.EqualValues(t, 1, search())
+}
+
+// TestIncompleteExplanationsAreNotCached checks that an explanation the
+// model did not finish is neither reported as done nor cached
+func TestIncompleteExplanationsAreNotCached(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req dto.OpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if !req.Stream {
+			// Answered, but without saying the answer is complete
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"God loves"}}]}`)
+			return
+		}
+		// The connection closes before [DONE]
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"delta":{"content":"God "}}]}`)
+	}))
+	defer upstream.Close()
+
+	server, _ := newTestServer(t)
+	explainer, err := explain.New(explain.Config{Provider: explain.ProviderOpenAICompatible, BaseURL: upstream.URL, Model: "llama3"})
+	require.NoError(t, err)
+	server.Explainer = explainer
+
+	explainVerse := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"book":"John","chapter":3,"start_verse":16}`))
 		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.GetEcho().ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := explainVerse("/api/niv/explain/stream")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+		assert.Contains(t, rec.Body.String(), "event: error")
+		assert.NotContains(t, rec.Body.String(), "event: done")
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := explainVerse("/api/niv/explain")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+	}
+}
+
+// blockingMailer holds every message until release is closed
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mailer.Message
+}
+
+func (m blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+// TestAccountEmailsDoNotWaitForMail checks that emails which only go to
+// existing accounts are sent after responding, so that response times do not
+// reveal who has an account, and that Shutdown waits for them
+func TestAccountEmailsDoNotWaitForMail(t *testing.T) {
+	for _, path := range []string{"/api/password/forgot", "/api/verify-email/resend"} {
+		t.Run(path, func(t *testing.T) {
+			server, db := newTestServer(t)
+			outbox := blockingMailer{release: make(chan struct{}), sent: make(chan mailer.Message, 1)}
+			server.Mailer = outbox
+			require.NoError(t, db.CreateUser(context.Background(), &models.User{FirstName: "Ann", Email: "ann@example.com", Password: "x"}))
+
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email":"ann@example.com"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			responded := make(chan struct{})
+			go func() {
+				server.GetEcho().ServeHTTP(rec, req)
+				close(responded)
+			}()
+			select {
+			case <-responded:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the response waited for the email to be sent")
+			}
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			close(outbox.release)
+			require.NoError(t, server.Shutdown(context.Background()))
+			require.Len(t, outbox.sent, 1)
+			assert.Equal(t, "ann@example.com", (<-outbox.sent).To)
+		})
+	}
+}
+
+// TestNIVAliasIgnoresTranslationParameter tests that the legacy /api/niv
+// routes always serve the default translation
+func TestNIVAliasIgnoresTranslationParameter(t *testing.T) {
+	server, db := newTestServer(t)
+	verses, err := fixtures.Verses("kjv")
+	require.NoError(t, err)
+	require.NoError(t, db.ReplaceTranslation(context.Background(), &models.Translation{ID: "kjv", Name: "KJV"}, verses[:1]))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	want := get("/api/niv/books")
+	require.Equal(t, http.StatusOK, want.Code)
+	require.NotEqual(t, want.Body.String(), get("/api/bibles/kjv/books").Body.String())
+	for _, query := range []string{"kjv", "unknown"} {
+		rec := get("/api/niv/books?translation=" + query)
+		assert.Equal(t, http.StatusOK, rec.Code, query)
+		assert.Equal(t, want.Body.String(), rec.Body.String(), query)
+	}
+}
+
+// slowPasswords takes as long to check a password as bcrypt would on a real
+// server, so that parallel logins overlap
+type slowPasswords struct {
+	*database.MemoryClient
+}
+
+func (db slowPasswords) VerifyPassword(ctx context.Context, email, password string) (*models.User, error) {
+	time.Sleep(50 * time.Millisecond)
+	return db.MemoryClient.VerifyPassword(ctx, email, password)
+}
+
+// TestParallelLoginsAreThrottled tests that wrong passwords sent at the same
+// time cannot all get past the delay that starts after the third failure
+func TestParallelLoginsAreThrottled(t *testing.T) {
+	server, db := newTestServer(t)
+	server.DB = slowPasswords{db}
+	require.NoError(t, db.CreateUser(context.Background(), &models.User{FirstName: "Ann", Email: "ann@example.com", Password: "password123"}))
+
+	codes := make([]int, 10)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/login/", strings.NewReader(`{"email":"ann@example.com","password":"wrong"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			server.GetEcho().ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}()
 	}
+	wg.Wait()
+
+	verified := 0
+	for _, code := range codes {
+		if code == http.StatusUnauthorized {
+			verified++
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, code)
+		}
+	}
+	assert.Equal(t, 3, verified, "only the attempts before the delay are checked")
+}
+
+// countingSessions counts the lookups that go into checking an access token
+type countingSessions struct {
+	*database.MemoryClient
+	lookups *atomic.Int32
+}
+
+func (db countingSessions) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	db.lookups.Add(1)
+	return db.MemoryClient.IsSessionActive(ctx, familyID)
+}
 
-	// Create response recorder
+// TestSessionIsCheckedOncePerRequest tests that the rate limiter and the JWT
+// middleware share one session lookup
+func TestSessionIsCheckedOncePerRequest(t *testing.T) {
+	server, db := newTestServer(t)
+	require.NoError(t, db.CreateUser(context.Background(), &models.User{FirstName: "Ann", Email: "ann@example.com", Password: "password123"}))
+	req := httptest.NewRequest(http.MethodPost, "/api/login/", strings.NewReader(`{"email":"ann@example.com","password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var login dto.LoginResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &login))
+
+	lookups := &atomic.Int32{}
+	server.DB = countingSessions{db, lookups}
+	req = httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Access)
+	rec = httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, int32(1), lookups.Load())
+}
+
+// newTestServer builds a server with the default configuration, the fake
+// LLM provider and a database holding the fixture verses
+func newTestServer(t *testing.T) (*EchoServer, *database.MemoryClient) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.DSN = "memory"
+	cfg.JWT.Secret = "test-secret"
+	cfg.LLM.Provider = explain.ProviderFake
+	require.NoError(t, cfg.Validate())
+
+	db := database.NewMemoryClient()
+	require.NoError(t, fixtures.Seed(context.Background(), db))
+	return NewEchoServer(&cfg, db, logging.Discard()).(*EchoServer), db
+}
 
-	// Execute request (this requires Echo server setup)
-	// For now, this is a template - adjust based on your Echo setup
+func runTestCase(t *testing.T, server *EchoServer, endpoint Endpoint, testCase TestCase) {
+	req := httptest.NewRequest(testCase.Request.Method, requestTarget(t, testCase.Request), requestBody(t, testCase.Request.Body))
+	for k, v := range testCase.Request.Headers {
+		req.Header.Set(k, v)
+	}
+	if testCase.Request.Body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, req)
 
-	// Assert status code
 	assert.Contains(t, testCase.ExpectedStatusCodes, rec.Code,
-		fmt.Sprintf("Test %s: Expected status %v, got %d", testCase.ID, testCase.ExpectedStatusCodes, rec.Code))
+		fmt.Sprintf("Test %s: Expected status %v, got %d: %s", testCase.ID, testCase.ExpectedStatusCodes, rec.Code, rec.Body.String()))
+
+	schema, _ := testCase.ExpectedResponseSchema.(map[string]interface{})
+	if len(schema) == 0 {
+		return
+	}
+	var body interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		// Handlers that answer with ctx.String send plain text
+		body = rec.Body.String()
+	}
+	for _, problem := range validateSchema(schema, body, "response") {
+		t.Errorf("Test %s (%s %s): %s", testCase.ID, endpoint.Method, endpoint.Path, problem)
+	}
+}
+
+// requestTarget returns the path and query of the test case's URL, which
+// names a running server, merged with its query_params
+func requestTarget(t *testing.T, request Request) string {
+	u, err := url.Parse(request.URL)
+	require.NoError(t, err)
+	query := u.Query()
+	for k, v := range request.QueryParams {
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// requestBody encodes a test case's body as JSON. A string body is sent as
+// it is, so that cases can send malformed JSON.
+func requestBody(t *testing.T, body interface{}) io.Reader {
+	switch body := body.(type) {
+	case nil:
+		return nil
+	case string:
+		return strings.NewReader(body)
+	}
+	bodyBytes, err := json.Marshal(body)
+	require.NoError(t, err)
+	return bytes.NewReader(bodyBytes)
+}
+
+// validateSchema checks value against the subset of JSON Schema used by
+// test_cases.json: type, enum, properties, required and items. It returns a
+// description of each mismatch.
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		return []string{fmt.Sprintf("%s: expected type %v, got %s", path, types, jsonType(value))}
+	}
+
+	var problems []string
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+	}
+
+	if object, ok := value.(map[string]interface{}); ok {
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, propertySchema := range properties {
+			property, ok := object[name]
+			if !ok {
+				continue
+			}
+			problems = append(problems, validateSchema(propertySchema.(map[string]interface{}), property, path+"."+name)...)
+		}
+	}
+
+	if array, ok := value.([]interface{}); ok {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range array {
+				problems = append(problems, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+// matchesType reports whether value has the schema type, or one of the
+// types if the schema lists several
+func matchesType(types interface{}, value interface{}) bool {
+	if list, ok := types.([]interface{}); ok {
+		for _, t := range list {
+			if matchesType(t, value) {
+				return true
+			}
+		}
+		return false
+	}
+	actual := jsonType(value)
+	return actual == types || (types == "number" && actual == "integer")
+}
+
+// jsonType names the JSON Schema type of a value decoded by encoding/json
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // Test data structures
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/server/middleware"
+	"bible_reading_backend_nkv/server/utils"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// tokenPair is a freshly issued access and refresh token
+type tokenPair struct {
+	Access    string
+	Refresh   string
+	ExpiresIn int
+}
+
+// newSession builds the session row for a new refresh token in familyID
+func (s *EchoServer) newSession(ctx echo.Context, userID int, familyID string) (*models.Session, string, error) {
+	refresh, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	userAgent := ctx.Request().UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return &models.Session{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		UserAgent: userAgent,
+		IP:        ctx.RealIP(),
+		ExpiresAt: time.Now().UTC().Add(s.tokens.RefreshExpiry()),
+	}, refresh, nil
+}
+
+func (s *EchoServer) accessToken(session *models.Session, refresh string) (tokenPair, error) {
+	access, err := s.tokens.GenerateToken(session.UserID, session.FamilyID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return tokenPair{
+		Access:    access,
+		Refresh:   refresh,
+		ExpiresIn: int(s.tokens.AccessExpiry().Seconds()),
+	}, nil
+}
+
+// startSession opens a new session for userID, as on login or registration
+func (s *EchoServer) startSession(ctx echo.Context, userID int) (tokenPair, error) {
+	familyID, err := utils.NewSessionID()
+	if err != nil {
+		return tokenPair{}, err
+	}
+	session, refresh, err := s.newSession(ctx, userID, familyID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	if err := s.DB.CreateSession(ctx.Request().Context(), session); err != nil {
+		return tokenPair{}, err
+	}
+	return s.accessToken(session, refresh)
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+// Each refresh token can be used once; presenting one that was already used
+// means it leaked, so the whole session is revoked.
+func (s *EchoServer) RefreshToken(ctx echo.Context) error {
+	var req dto.RefreshRequest
+	if err := ctx.Bind(&req); err != nil || req.Refresh == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Refresh token is required"})
+	}
+
+	session, err := s.DB.GetSessionByTokenHash(ctx.Request().Context(), utils.HashToken(req.Refresh))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid refresh token"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get session", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to refresh token"})
+	}
+
+	if session.RevokedAt != nil {
+		return s.refreshTokenReused(ctx, session)
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid refresh token"})
+	}
+
+	next, refresh, err := s.newSession(ctx, session.UserID, session.FamilyID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to create session", "user_id", session.UserID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to refresh token"})
+	}
+	err = s.DB.RotateSession(ctx.Request().Context(), session, next)
+	if errors.Is(err, database.ErrSessionRevoked) {
+		// Another request rotated the same token first
+		return s.refreshTokenReused(ctx, session)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to rotate session", "user_id", session.UserID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to refresh token"})
+	}
+
+	tokens, err := s.accessToken(next, refresh)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to generate token", "user_id", next.UserID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+	return ctx.JSON(http.StatusOK, dto.LoginResponse{
+		Access:    tokens.Access,
+		Refresh:   tokens.Refresh,
+		ExpiresIn: tokens.ExpiresIn,
+	})
+}
+
+func (s *EchoServer) refreshTokenReused(ctx echo.Context, session *models.Session) error {
+	s.Logger.WarnContext(ctx.Request().Context(), "refresh token reused, revoking session", "user_id", session.UserID, "session_family", session.FamilyID)
+	if err := s.DB.RevokeSessionFamily(ctx.Request().Context(), session.FamilyID); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to revoke session", "session_family", session.FamilyID, "error", err)
+	}
+	return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Refresh token already used; session revoked"})
+}
+
+// Logout revokes the session of the access token used to call it
+func (s *EchoServer) Logout(ctx echo.Context) error {
+	if err := s.DB.RevokeSessionFamily(ctx.Request().Context(), middleware.GetSessionID(ctx)); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to revoke session", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log out"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the current user
+func (s *EchoServer) LogoutAll(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+	if err := s.DB.RevokeUserSessions(ctx.Request().Context(), userID); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to revoke sessions", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log out"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Logged out of all sessions successfully"})
+}
+
+// validateSession rejects access tokens whose session was revoked by a
+// logout or by refresh token reuse, or whose user was deleted or disabled. It
+// stores the user's role for middleware.RequireRole. The rate limiter has
+// usually looked the same token up through optionalUser already, so its
+// result is reused, and stored for optionalUser otherwise.
+func (s *EchoServer) validateSession(ctx echo.Context, claims *utils.Claims) error {
+	user, ok := ctx.Get(optionalUserKey).(*models.User)
+	if !ok || user == nil || user.ID != claims.UserID {
+		var err error
+		user, err = s.sessionUser(ctx.Request().Context(), claims)
+		if err != nil {
+			return err
+		}
+		ctx.Set(optionalUserKey, user)
+	}
+	ctx.Set(middleware.RoleKey, user.Role)
+	return nil
+}
+
+// sessionUser returns the user of an access token if its session is active
+// and the user exists and is enabled, and utils.ErrInvalidToken otherwise
+func (s *EchoServer) sessionUser(ctx context.Context, claims *utils.Claims) (*models.User, error) {
+	if claims.SessionID == "" {
+		return nil, utils.ErrInvalidToken
+	}
+	active, err := s.DB.IsSessionActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, utils.ErrInvalidToken
+	}
+
+	user, err := s.DB.GetUserByID(ctx, claims.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, utils.ErrInvalidToken
+	}
+	return user, nil
+}
+
+// optionalUserKey caches the result of optionalUser for the request
+const optionalUserKey = "optional_user"
+
+// optionalUser returns the caller's user on public endpoints that treat
+// signed-in callers differently. Callers without a token, or whose token
+// fails the checks of validateSession, are anonymous.
+func (s *EchoServer) optionalUser(ctx echo.Context) (*models.User, bool) {
+	if user, ok := ctx.Get(optionalUserKey).(*models.User); ok {
+		return user, user != nil
+	}
+
+	var user *models.User
+	if token, ok := utils.BearerToken(ctx.Request().Header.Get(echo.HeaderAuthorization)); ok {
+		if claims, err := s.tokens.ParseToken(token); err == nil {
+			user, err = s.sessionUser(ctx.Request().Context(), claims)
+			if err != nil && !errors.Is(err, utils.ErrInvalidToken) {
+				s.Logger.ErrorContext(ctx.Request().Context(), "failed to validate token", "error", err)
+			}
+		}
+	}
+	ctx.Set(optionalUserKey, user)
+	return user, user != nil
+}
+
+// rateLimitKey identifies callers for rate limits and quotas: by user ID when
+// optionalUser accepts their token and by client IP otherwise, so that a
+// revoked token cannot buy a fresh bucket
+func (s *EchoServer) rateLimitKey(ctx echo.Context) string {
+	if user, ok := s.optionalUser(ctx); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+	return "ip:" + ctx.RealIP()
+}
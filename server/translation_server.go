@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/models"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func (s *EchoServer) GetTranslations(ctx echo.Context) error {
+	translations, err := s.DB.GetTranslations(ctx.Request().Context())
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get translations", "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch translations"})
+	}
+	return ctx.JSON(http.StatusOK, translations)
+}
+
+func (s *EchoServer) GetTranslation(ctx echo.Context) error {
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+
+	translation, err := s.DB.GetTranslation(ctx.Request().Context(), translationID)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, translation)
+}
+
+// translationKey holds the translation set by fixedTranslation
+const translationKey = "translation"
+
+// fixedTranslation makes every route of a group use translationID, whatever
+// the request names. The legacy /api/niv group is bound to the default entry
+// this way, so that ?translation= cannot turn it into another translation.
+func fixedTranslation(translationID string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(translationKey, translationID)
+			return next(ctx)
+		}
+	}
+}
+
+// translationID resolves the translation a request targets, from
+// fixedTranslation, the :translation path parameter or the ?translation=
+// query parameter. Requests that name none use the default entry.
+func (s *EchoServer) translationID(ctx echo.Context) (string, error) {
+	if id, ok := ctx.Get(translationKey).(string); ok {
+		return id, nil
+	}
+	id := ctx.Param("translation")
+	if id == "" {
+		id = ctx.QueryParam("translation")
+	}
+	if id == "" {
+		return models.DefaultTranslationID, nil
+	}
+
+	translation, err := s.DB.GetTranslation(ctx.Request().Context(), id)
+	if err != nil {
+		return "", err
+	}
+	return translation.ID, nil
+}
+
+// translationError writes the response for a failed translation lookup
+func (s *EchoServer) translationError(ctx echo.Context, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Translation not found"})
+	}
+	s.Logger.ErrorContext(ctx.Request().Context(), "failed to resolve translation", "error", err)
+	return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch translation"})
+}
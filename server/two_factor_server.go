@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/server/middleware"
+	"bible_reading_backend_nkv/server/utils"
+	"bible_reading_backend_nkv/totp"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// totpIssuer is the account name shown in authenticator apps
+const totpIssuer = "Bible Reading"
+
+// recoveryCodeCount is how many recovery codes are issued at a time
+const recoveryCodeCount = 10
+
+// errInvalidCode is returned by verifySecondFactor for wrong, expired or
+// already used codes
+var errInvalidCode = errors.New("invalid authentication code")
+
+func (s *EchoServer) GetTwoFactorStatus(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get 2FA status", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch two-factor status"})
+	}
+	return ctx.JSON(http.StatusOK, dto.TwoFactorStatusResponse{Enabled: enabled})
+}
+
+// SetupTwoFactor starts enrolment by generating a secret for the user's
+// authenticator app. 2FA is not enforced until EnableTwoFactor confirms it.
+func (s *EchoServer) SetupTwoFactor(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	enabled, err := s.twoFactorEnabled(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get 2FA status", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set up two-factor authentication"})
+	}
+	if enabled {
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err == nil {
+		err = s.DB.SaveUserTOTP(ctx.Request().Context(), &models.UserTOTP{UserID: userID, Secret: secret})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to set up 2FA", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set up two-factor authentication"})
+	}
+
+	return ctx.JSON(http.StatusOK, dto.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, user.Email, secret),
+	})
+}
+
+// EnableTwoFactor confirms enrolment with a code from the authenticator app
+// and returns the user's recovery codes. They are shown only this once.
+func (s *EchoServer) EnableTwoFactor(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.Bind(&req); err != nil || req.Code == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Authentication code is required"})
+	}
+
+	t, err := s.DB.GetUserTOTP(ctx.Request().Context(), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Two-factor setup has not been started"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get 2FA secret", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to enable two-factor authentication"})
+	}
+	if t.EnabledAt != nil {
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+	}
+
+	step, ok := totp.Validate(t.Secret, normalizeCode(req.Code), time.Now())
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid authentication code"})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = s.DB.EnableTOTP(ctx.Request().Context(), userID, step, hashes)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to enable 2FA", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to enable two-factor authentication"})
+	}
+
+	s.Logger.InfoContext(ctx.Request().Context(), "two-factor authentication enabled", "user_id", userID)
+	return ctx.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor turns 2FA off. It needs both the password and a current
+// code or recovery code, so a stolen access token alone cannot remove it.
+func (s *EchoServer) DisableTwoFactor(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.TwoFactorDisableRequest
+	if err := ctx.Bind(&req); err != nil || req.Password == "" || req.Code == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Password and authentication code are required"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+
+	// Wrong passwords and codes count towards the login lockout, so that they
+	// cannot be guessed here instead
+	block, err := s.reserveLoginAttempt(ctx, user.Email)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to check failed logins", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to disable two-factor authentication"})
+	}
+	if block != nil {
+		return block.respond(ctx)
+	}
+	if _, err := s.DB.VerifyPassword(ctx.Request().Context(), user.Email, req.Password); err != nil {
+		if errors.Is(err, database.ErrInvalidCredentials) {
+			s.recordLoginFailure(ctx)
+			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid password"})
+		}
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to verify password", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to disable two-factor authentication"})
+	}
+
+	if err := s.verifySecondFactor(ctx.Request().Context(), userID, req.Code, true); err != nil {
+		if errors.Is(err, errInvalidCode) {
+			s.recordLoginFailure(ctx)
+		}
+		return s.twoFactorError(ctx, userID, err, "Failed to disable two-factor authentication")
+	}
+	s.clearLoginFailures(ctx.Request().Context(), user.Email)
+	if err := s.DB.DisableTOTP(ctx.Request().Context(), userID); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to disable 2FA", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to disable two-factor authentication"})
+	}
+
+	s.Logger.InfoContext(ctx.Request().Context(), "two-factor authentication disabled", "user_id", userID)
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a
+// code from the authenticator app
+func (s *EchoServer) RegenerateRecoveryCodes(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.Bind(&req); err != nil || req.Code == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Authentication code is required"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+
+	// Wrong codes count towards the login lockout, so that a stolen access
+	// token cannot be used to guess one
+	block, err := s.reserveLoginAttempt(ctx, user.Email)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to check failed logins", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to regenerate recovery codes"})
+	}
+	if block != nil {
+		return block.respond(ctx)
+	}
+	if err := s.verifySecondFactor(ctx.Request().Context(), userID, req.Code, false); err != nil {
+		if errors.Is(err, errInvalidCode) {
+			s.recordLoginFailure(ctx)
+		}
+		return s.twoFactorError(ctx, userID, err, "Failed to regenerate recovery codes")
+	}
+	s.clearLoginFailures(ctx.Request().Context(), user.Email)
+
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = s.DB.ReplaceRecoveryCodes(ctx.Request().Context(), userID, hashes)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to regenerate recovery codes", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to regenerate recovery codes"})
+	}
+	return ctx.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// LoginTwoFactor completes a login that returned mfa_required by exchanging
+// the challenge and a code for tokens
+func (s *EchoServer) LoginTwoFactor(ctx echo.Context) error {
+	var req dto.LoginTwoFactorRequest
+	if err := ctx.Bind(&req); err != nil || req.Challenge == "" || req.Code == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Challenge and authentication code are required"})
+	}
+
+	userID, err := s.tokens.ParseMFAChallengeToken(req.Challenge)
+	if err != nil {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired challenge"})
+	}
+	// The account may have been disabled since the challenge was issued
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	if user.DisabledAt != nil {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"error": "Account disabled"})
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	block, err := s.reserveLoginAttempt(ctx, user.Email)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to check failed logins", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log in"})
+	}
+	if block != nil {
+		return block.respond(ctx)
+	}
+	if err := s.verifySecondFactor(ctx.Request().Context(), userID, req.Code, true); err != nil {
+		if errors.Is(err, errInvalidCode) {
+			s.recordLoginFailure(ctx)
+		}
+		return s.twoFactorError(ctx, userID, err, "Failed to log in")
+	}
+	s.clearLoginFailures(ctx.Request().Context(), user.Email)
+
+	tokens, err := s.startSession(ctx, userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to start session", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+
+	return ctx.JSON(http.StatusOK, dto.LoginResponse{
+		Access:    tokens.Access,
+		Refresh:   tokens.Refresh,
+		ExpiresIn: tokens.ExpiresIn,
+	})
+}
+
+// twoFactorEnabled reports whether the user has confirmed 2FA enrolment
+func (s *EchoServer) twoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	t, err := s.DB.GetUserTOTP(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.EnabledAt != nil, nil
+}
+
+// verifySecondFactor checks code against the user's authenticator secret or,
+// if allowRecovery is set, their unused recovery codes. Accepted codes cannot
+// be used again. It returns errInvalidCode if 2FA is not enabled.
+func (s *EchoServer) verifySecondFactor(ctx context.Context, userID int, code string, allowRecovery bool) error {
+	t, err := s.DB.GetUserTOTP(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && t.EnabledAt == nil) {
+		return errInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+
+	if step, ok := totp.Validate(t.Secret, normalizeCode(code), time.Now()); ok {
+		err := s.DB.UseTOTPStep(ctx, userID, step)
+		if errors.Is(err, database.ErrCodeReused) {
+			return errInvalidCode
+		}
+		return err
+	}
+	if !allowRecovery {
+		return errInvalidCode
+	}
+
+	err = s.DB.UseRecoveryCode(ctx, userID, utils.HashRecoveryCode(code))
+	if errors.Is(err, database.ErrInvalidRecoveryCode) {
+		return errInvalidCode
+	}
+	if err == nil {
+		s.Logger.InfoContext(ctx, "recovery code used", "user_id", userID)
+	}
+	return err
+}
+
+func (s *EchoServer) twoFactorError(ctx echo.Context, userID int, err error, message string) error {
+	if errors.Is(err, errInvalidCode) {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid authentication code"})
+	}
+	s.Logger.ErrorContext(ctx.Request().Context(), "failed to check 2FA code", "user_id", userID, "error", err)
+	return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": message})
+}
+
+// normalizeCode strips the spaces some apps show in the middle of a code
+func normalizeCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, hash, err := utils.NewRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/server/middleware"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func (s *EchoServer) GetCurrentUser(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	return ctx.JSON(http.StatusOK, userResponse(user))
+}
+
+func (s *EchoServer) UpdateCurrentUser(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.UpdateUserRequest
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	updates := map[string]interface{}{}
+	if req.FirstName != nil {
+		name := strings.TrimSpace(*req.FirstName)
+		if name == "" || len(name) > 255 {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "First name must be between 1 and 255 characters"})
+		}
+		updates["first_name"] = name
+	}
+	if req.LastName != nil {
+		name := strings.TrimSpace(*req.LastName)
+		if name == "" || len(name) > 255 {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Last name must be between 1 and 255 characters"})
+		}
+		updates["last_name"] = name
+	}
+	if req.Age != nil {
+		if *req.Age < 1 || *req.Age > 150 {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Age must be between 1 and 150"})
+		}
+		updates["age"] = *req.Age
+	}
+	if req.BelieverCategory != nil {
+		if *req.BelieverCategory < 1 || *req.BelieverCategory > 5 {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Believer category must be between 1 and 5"})
+		}
+		updates["believer_category"] = *req.BelieverCategory
+	}
+
+	if len(updates) > 0 {
+		if err := s.DB.UpdateUser(ctx.Request().Context(), userID, updates); err != nil {
+			s.Logger.ErrorContext(ctx.Request().Context(), "failed to update user", "user_id", userID, "error", err)
+			return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update user"})
+		}
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	return ctx.JSON(http.StatusOK, userResponse(user))
+}
+
+func (s *EchoServer) DeleteCurrentUser(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	if _, err := s.DB.GetUserByID(ctx.Request().Context(), userID); err != nil {
+		return s.userError(ctx, userID, err)
+	}
+	if err := s.DB.DeleteUser(ctx.Request().Context(), userID); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to delete user", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete user"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "User deleted successfully"})
+}
+
+// userError writes the response for a failed user lookup. A valid token for a
+// deleted account is reported as not found.
+func (s *EchoServer) userError(ctx echo.Context, userID int, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+	s.Logger.ErrorContext(ctx.Request().Context(), "failed to get user", "user_id", userID, "error", err)
+	return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch user"})
+}
+
+func userResponse(user *models.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:               user.ID,
+		Email:            user.Email,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		Name:             user.GetFullName(),
+		Age:              user.Age,
+		BelieverCategory: user.BelieverCategory,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+	}
+}
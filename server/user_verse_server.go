@@ -0,0 +1,389 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/models"
+	"bible_reading_backend_nkv/server/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Favorite Verses Handlers
+
+func (s *EchoServer) AddFavoriteVerse(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.AddFavoriteVerseRequest
+	if err := ctx.Bind(&req); err != nil || !validVerseRef(req.BookID, req.Chapter, req.Verse) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	if _, err := s.lookupVerse(ctx, models.DefaultTranslationID, req.BookID, req.Chapter, req.Verse); err != nil {
+		return s.verseError(ctx, err)
+	}
+
+	err := s.DB.AddFavoriteVerse(ctx.Request().Context(), userID, req.BookID, req.Chapter, req.Verse)
+	if errors.Is(err, database.ErrAlreadyFavorite) || errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": "Verse already in favorites"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to add favorite verse", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add favorite verse"})
+	}
+
+	return ctx.JSON(http.StatusCreated, map[string]string{"message": "Favorite verse added successfully"})
+}
+
+func (s *EchoServer) GetFavoriteVerses(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+	page, limit := pagination(ctx)
+
+	favorites, err := s.DB.GetFavoriteVerses(ctx.Request().Context(), userID, limit, (page-1)*limit)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get favorite verses", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch favorite verses"})
+	}
+	total, err := s.DB.GetFavoriteVersesCount(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to count favorite verses", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch favorite verses"})
+	}
+
+	data := make([]dto.FavoriteVerseResponse, 0, len(favorites))
+	for _, f := range favorites {
+		ref := s.verseReference(ctx, translationID, f.BookID, f.Chapter, f.Verse)
+		data = append(data, dto.FavoriteVerseResponse{
+			ID:        f.ID,
+			UserID:    f.UserID,
+			BookID:    f.BookID,
+			BookName:  ref.BookName,
+			Chapter:   f.Chapter,
+			Verse:     f.Verse,
+			Text:      ref.Text,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, paginatedResponse(data, total, page, limit))
+}
+
+func (s *EchoServer) RemoveFavoriteVerse(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	bookID, chapter, verse, ok := verseParams(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	if err := s.DB.RemoveFavoriteVerse(ctx.Request().Context(), userID, bookID, chapter, verse); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to remove favorite verse", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to remove favorite verse"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Favorite verse removed successfully"})
+}
+
+// Highlighted Verses Handlers
+
+func (s *EchoServer) AddHighlightedVerse(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.AddHighlightRequest
+	if err := ctx.Bind(&req); err != nil || !validVerseRef(req.BookID, req.Chapter, req.Verse) || len(req.Color) > 20 {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	if _, err := s.lookupVerse(ctx, models.DefaultTranslationID, req.BookID, req.Chapter, req.Verse); err != nil {
+		return s.verseError(ctx, err)
+	}
+
+	err := s.DB.AddHighlightedVerse(ctx.Request().Context(), userID, req.BookID, req.Chapter, req.Verse, req.Note, req.Color)
+	if errors.Is(err, database.ErrAlreadyHighlighted) || errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": "Verse already highlighted"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to add highlighted verse", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add highlighted verse"})
+	}
+
+	return ctx.JSON(http.StatusCreated, map[string]string{"message": "Highlighted verse added successfully"})
+}
+
+func (s *EchoServer) GetHighlightedVerses(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+	page, limit := pagination(ctx)
+
+	highlights, err := s.DB.GetHighlightedVerses(ctx.Request().Context(), userID, limit, (page-1)*limit)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get highlighted verses", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch highlighted verses"})
+	}
+	total, err := s.DB.GetHighlightedVersesCount(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to count highlighted verses", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch highlighted verses"})
+	}
+
+	data := make([]dto.HighlightedVerseResponse, 0, len(highlights))
+	for _, h := range highlights {
+		ref := s.verseReference(ctx, translationID, h.BookID, h.Chapter, h.Verse)
+		data = append(data, dto.HighlightedVerseResponse{
+			ID:        h.ID,
+			UserID:    h.UserID,
+			BookID:    h.BookID,
+			BookName:  ref.BookName,
+			Chapter:   h.Chapter,
+			Verse:     h.Verse,
+			Text:      ref.Text,
+			Note:      h.Note,
+			Color:     h.Color,
+			CreatedAt: h.CreatedAt,
+			UpdatedAt: h.UpdatedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, paginatedResponse(data, total, page, limit))
+}
+
+func (s *EchoServer) UpdateHighlightedVerse(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	bookID, chapter, verse, ok := verseParams(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	var req dto.UpdateHighlightRequest
+	if err := ctx.Bind(&req); err != nil || len(req.Color) > 20 {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	err := s.DB.UpdateHighlightedVerse(ctx.Request().Context(), userID, bookID, chapter, verse, req.Note, req.Color)
+	if errors.Is(err, database.ErrHighlightNotFound) {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Highlight not found"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to update highlighted verse", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update highlighted verse"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Highlighted verse updated successfully"})
+}
+
+func (s *EchoServer) RemoveHighlightedVerse(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	bookID, chapter, verse, ok := verseParams(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	if err := s.DB.RemoveHighlightedVerse(ctx.Request().Context(), userID, bookID, chapter, verse); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to remove highlighted verse", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to remove highlighted verse"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Highlighted verse removed successfully"})
+}
+
+// Last Read Handlers
+
+func (s *EchoServer) UpdateLastRead(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	var req dto.UpdateLastReadRequest
+	if err := ctx.Bind(&req); err != nil || !validVerseRef(req.BookID, req.Chapter, req.Verse) || len(req.BookName) > 255 {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+
+	v, err := s.lookupVerse(ctx, models.DefaultTranslationID, req.BookID, req.Chapter, req.Verse)
+	if err != nil {
+		return s.verseError(ctx, err)
+	}
+	if req.BookName == "" {
+		req.BookName = v.Book
+	}
+
+	if err := s.DB.UpdateLastRead(ctx.Request().Context(), userID, req.BookID, req.BookName, req.Chapter, req.Verse); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to update last read", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update last read"})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Last read updated successfully"})
+}
+
+func (s *EchoServer) GetLastRead(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	translationID, err := s.translationID(ctx)
+	if err != nil {
+		return s.translationError(ctx, err)
+	}
+
+	lastRead, err := s.DB.GetLastRead(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get last read", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch last read"})
+	}
+	if lastRead == nil {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "No last read position"})
+	}
+
+	ref := s.verseReference(ctx, translationID, lastRead.BookID, lastRead.Chapter, lastRead.Verse)
+	return ctx.JSON(http.StatusOK, dto.LastReadResponse{
+		UserID:    lastRead.UserID,
+		BookID:    lastRead.BookID,
+		BookName:  lastRead.BookName,
+		Chapter:   lastRead.Chapter,
+		Verse:     lastRead.Verse,
+		Text:      ref.Text,
+		UpdatedAt: lastRead.UpdatedAt,
+	})
+}
+
+// GetLastReadVerses is the legacy endpoint used by the frontend
+func (s *EchoServer) GetLastReadVerses(ctx echo.Context) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+	}
+
+	lastReads, err := s.DB.GetLastReadVerses(ctx.Request().Context(), userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get last read verses", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch last read verses"})
+	}
+
+	verses := make([]dto.VerseReferenceResponse, 0, len(lastReads))
+	for _, l := range lastReads {
+		ref := s.verseReference(ctx, models.DefaultTranslationID, l.BookID, l.Chapter, l.Verse)
+		ref.BookName = l.BookName
+		verses = append(verses, ref)
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{"last_read_verses": verses})
+}
+
+// lookupVerse returns a single verse, or gorm.ErrRecordNotFound
+func (s *EchoServer) lookupVerse(ctx echo.Context, translationID string, bookID, chapter, verse int) (*models.Verse, error) {
+	verses, err := s.DB.GetVersesInRange(ctx.Request().Context(), translationID, bookID, chapter, verse, chapter, verse)
+	if err != nil {
+		return nil, err
+	}
+	if len(verses) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &verses[0], nil
+}
+
+// verseReference returns the book name and text of a stored reference. A
+// verse missing from the translation is returned without text rather than
+// failing the whole list.
+func (s *EchoServer) verseReference(ctx echo.Context, translationID string, bookID, chapter, verse int) dto.VerseReferenceResponse {
+	ref := dto.VerseReferenceResponse{BookID: bookID, Chapter: chapter, Verse: verse}
+	v, err := s.lookupVerse(ctx, translationID, bookID, chapter, verse)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.Logger.ErrorContext(ctx.Request().Context(), "failed to get verse", "translation", translationID, "book_id", bookID, "chapter", chapter, "verse", verse, "error", err)
+		}
+		return ref
+	}
+	ref.BookName = v.Book
+	ref.Text = v.Text
+	return ref
+}
+
+// verseError writes the response for a failed verse lookup
+func (s *EchoServer) verseError(ctx echo.Context, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Verse not found"})
+	}
+	s.Logger.ErrorContext(ctx.Request().Context(), "failed to get verse", "error", err)
+	return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch verse"})
+}
+
+func validVerseRef(bookID, chapter, verse int) bool {
+	return bookID >= 1 && chapter >= 1 && verse >= 1
+}
+
+// verseParams parses the :book_id/:chapter/:verse path parameters
+func verseParams(ctx echo.Context) (bookID, chapter, verse int, ok bool) {
+	bookID, err1 := strconv.Atoi(ctx.Param("book_id"))
+	chapter, err2 := strconv.Atoi(ctx.Param("chapter"))
+	verse, err3 := strconv.Atoi(ctx.Param("verse"))
+	if err1 != nil || err2 != nil || err3 != nil || !validVerseRef(bookID, chapter, verse) {
+		return 0, 0, 0, false
+	}
+	return bookID, chapter, verse, true
+}
+
+// maxPage bounds the page parameter, so that the offset (page-1)*limit
+// cannot overflow
+const maxPage = 100000
+
+// pagination reads the page and limit query parameters (default 1 and 20,
+// page capped at maxPage and limit at 100)
+func pagination(ctx echo.Context) (page, limit int) {
+	page, _ = strconv.Atoi(ctx.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	if page > maxPage {
+		page = maxPage
+	}
+	limit, _ = strconv.Atoi(ctx.QueryParam("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
+
+func paginatedResponse(data interface{}, total int64, page, limit int) dto.PaginatedResponse {
+	return dto.PaginatedResponse{
+		Data:       data,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+}
@@ -0,0 +1,176 @@
+// Package utils holds helpers shared by the HTTP handlers and middleware.
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenConfig holds the signing key and lifetimes of the tokens issued by
+// Tokens
+type TokenConfig struct {
+	Secret        string
+	AccessExpiry  time.Duration
+	RefreshExpiry time.Duration
+}
+
+// Tokens issues and checks the JWTs handed out by the API, all signed with
+// the same key
+type Tokens struct {
+	secret        []byte
+	accessExpiry  time.Duration
+	refreshExpiry time.Duration
+}
+
+// NewTokens returns Tokens signing with cfg.Secret
+func NewTokens(cfg TokenConfig) *Tokens {
+	return &Tokens{
+		secret:        []byte(cfg.Secret),
+		accessExpiry:  cfg.AccessExpiry,
+		refreshExpiry: cfg.RefreshExpiry,
+	}
+}
+
+// ErrInvalidToken is returned for tokens that are malformed, badly signed or expired
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims are the JWT claims issued to users: user_id, the sid of the
+// session the token belongs to, and the registered exp and iat claims
+type Claims struct {
+	UserID    int    `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
+	// Purpose is set on single-purpose tokens such as email verification
+	// links, which must not be accepted as access tokens
+	Purpose string `json:"purpose,omitempty"`
+	Email   string `json:"email,omitempty"`
+	jwt.RegisteredClaims
+}
+
+const (
+	// PurposeVerifyEmail marks email verification tokens
+	PurposeVerifyEmail = "verify_email"
+	// PurposeMFAChallenge marks tokens proving the password step of a login
+	// whose second factor has not been checked yet
+	PurposeMFAChallenge = "mfa_challenge"
+)
+
+// MFAChallengeExpiry is how long a user has to enter their 2FA code after
+// giving their password
+const MFAChallengeExpiry = 5 * time.Minute
+
+// AccessExpiry is the lifetime of access tokens. Sessions are extended with
+// refresh tokens instead.
+func (t *Tokens) AccessExpiry() time.Duration {
+	return t.accessExpiry
+}
+
+// RefreshExpiry is the lifetime of refresh tokens and their sessions
+func (t *Tokens) RefreshExpiry() time.Duration {
+	return t.refreshExpiry
+}
+
+// GenerateToken creates a signed HS256 access token for userID within the
+// session sessionID
+func (t *Tokens) GenerateToken(userID int, sessionID string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessExpiry)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+	return token, nil
+}
+
+// ParseToken checks the signature and expiry of an access token and returns its claims
+func (t *Tokens) ParseToken(tokenString string) (*Claims, error) {
+	claims, err := t.parseClaims(tokenString)
+	if err != nil || claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (t *Tokens) parseClaims(tokenString string) (*Claims, error) {
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims.UserID <= 0 {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// signPurposeToken signs a single-purpose token for userID valid for expiry
+func (t *Tokens) signPurposeToken(userID int, purpose, email string, expiry time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:  userID,
+		Purpose: purpose,
+		Email:   email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+	return token, nil
+}
+
+// GenerateEmailVerificationToken signs a token confirming that userID owns
+// email. It expires after 48 hours and stops working if the email changes.
+func (t *Tokens) GenerateEmailVerificationToken(userID int, email string) (string, error) {
+	return t.signPurposeToken(userID, PurposeVerifyEmail, email, 48*time.Hour)
+}
+
+// ParseEmailVerificationToken checks a token from GenerateEmailVerificationToken
+// and returns the user ID and email it was issued for
+func (t *Tokens) ParseEmailVerificationToken(tokenString string) (int, string, error) {
+	claims, err := t.parseClaims(tokenString)
+	if err != nil || claims.Purpose != PurposeVerifyEmail || claims.Email == "" {
+		return 0, "", ErrInvalidToken
+	}
+	return claims.UserID, claims.Email, nil
+}
+
+// GenerateMFAChallengeToken signs the token returned by login when the user
+// still has to enter a 2FA code
+func (t *Tokens) GenerateMFAChallengeToken(userID int) (string, error) {
+	return t.signPurposeToken(userID, PurposeMFAChallenge, "", MFAChallengeExpiry)
+}
+
+// ParseMFAChallengeToken checks a token from GenerateMFAChallengeToken and
+// returns the user ID it was issued for
+func (t *Tokens) ParseMFAChallengeToken(tokenString string) (int, error) {
+	claims, err := t.parseClaims(tokenString)
+	if err != nil || claims.Purpose != PurposeMFAChallenge {
+		return 0, ErrInvalidToken
+	}
+	return claims.UserID, nil
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>" header
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTokens signs with "test-secret" and the default lifetimes
+var testTokens = NewTokens(TokenConfig{Secret: "test-secret", AccessExpiry: 15 * time.Minute, RefreshExpiry: 30 * 24 * time.Hour})
+
+func TestGenerateAndParseToken(t *testing.T) {
+	token, err := testTokens.GenerateToken(42, "session-1")
+	require.NoError(t, err)
+
+	claims, err := testTokens.ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 42, claims.UserID)
+	assert.Equal(t, "session-1", claims.SessionID)
+
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt.Time, time.Minute)
+	assert.WithinDuration(t, time.Now(), claims.IssuedAt.Time, time.Minute)
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	sign := func(method jwt.SigningMethod, key interface{}, claims Claims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+	valid := Claims{
+		UserID:           1,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpiry := Claims{UserID: 1}
+
+	tests := map[string]string{
+		"garbage":      "not-a-token",
+		"wrong secret": sign(jwt.SigningMethodHS256, []byte("other-secret"), valid),
+		"expired":      sign(jwt.SigningMethodHS256, []byte("test-secret"), expired),
+		"no expiry":    sign(jwt.SigningMethodHS256, []byte("test-secret"), noExpiry),
+		"none alg":     sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := testTokens.ParseToken(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	tokens := NewTokens(TokenConfig{Secret: "test-secret", AccessExpiry: 5 * time.Minute, RefreshExpiry: time.Hour})
+	assert.Equal(t, 5*time.Minute, tokens.AccessExpiry())
+	assert.Equal(t, time.Hour, tokens.RefreshExpiry())
+
+	token, err := tokens.GenerateToken(42, "session-1")
+	require.NoError(t, err)
+	claims, err := tokens.ParseToken(token)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), claims.ExpiresAt.Time, time.Minute)
+}
+
+func TestOpaqueToken(t *testing.T) {
+	token, hash, err := NewOpaqueToken()
+	require.NoError(t, err)
+	assert.Equal(t, hash, HashToken(token))
+	assert.NotContains(t, hash, token)
+
+	other, _, err := NewOpaqueToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestBearerToken(t *testing.T) {
+	token, ok := BearerToken("Bearer abc.def")
+	assert.True(t, ok)
+	assert.Equal(t, "abc.def", token)
+
+	for _, header := range []string{"", "Bearer", "Bearer ", "Basic abc", "abc.def"} {
+		_, ok := BearerToken(header)
+		assert.False(t, ok, header)
+	}
+}
+
+func TestEmailVerificationToken(t *testing.T) {
+	token, err := testTokens.GenerateEmailVerificationToken(42, "reader@example.com")
+	require.NoError(t, err)
+
+	userID, email, err := testTokens.ParseEmailVerificationToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 42, userID)
+	assert.Equal(t, "reader@example.com", email)
+
+	// Verification and access tokens are not interchangeable
+	_, err = testTokens.ParseToken(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	access, err := testTokens.GenerateToken(42, "session-1")
+	require.NoError(t, err)
+	_, _, err = testTokens.ParseEmailVerificationToken(access)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestMFAChallengeToken(t *testing.T) {
+	challenge, err := testTokens.GenerateMFAChallengeToken(7)
+	require.NoError(t, err)
+
+	userID, err := testTokens.ParseMFAChallengeToken(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	// A challenge must not grant access on its own
+	_, err = testTokens.ParseToken(challenge)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	verify, err := testTokens.GenerateEmailVerificationToken(7, "reader@example.com")
+	require.NoError(t, err)
+	_, err = testTokens.ParseMFAChallengeToken(verify)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, hash, err := NewRecoveryCode()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+
+	// Users may type codes without the dash or in upper case
+	assert.Equal(t, hash, HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))))
+	assert.Equal(t, hash, HashRecoveryCode(" "+code+" "))
+}
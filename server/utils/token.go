@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// NewOpaqueToken returns a random token, as used for refresh and password
+// reset tokens, and the hash to store for it. Only the hash is kept
+// server-side.
+func NewOpaqueToken() (token, hash string, err error) {
+	token, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// NewSessionID returns a random session family ID
+func NewSessionID() (string, error) {
+	return randomString(16)
+}
+
+// NewRecoveryCode returns a random 2FA recovery code such as "k7f2q-x9mpa"
+// and the hash to store for it
+func NewRecoveryCode() (code, hash string, err error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	code = s[:5] + "-" + s[5:]
+	return code, HashRecoveryCode(code), nil
+}
+
+// HashRecoveryCode hashes a recovery code as typed by the user, ignoring case,
+// spaces and dashes
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return HashToken(code)
+}
+
+// HashToken returns the hex SHA-256 of token. Opaque tokens are random, so
+// an unsalted fast hash is enough to keep a database leak from exposing them.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
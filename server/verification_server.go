@@ -0,0 +1,104 @@
+package server
+
+import (
+	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/mailer"
+	"bible_reading_backend_nkv/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// VerifyEmail confirms an address using the signed token from the
+// verification email. Verifying twice is not an error.
+func (s *EchoServer) VerifyEmail(ctx echo.Context) error {
+	userID, email, err := s.tokens.ParseEmailVerificationToken(ctx.QueryParam("token"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired verification token"})
+	}
+
+	user, err := s.DB.GetUserByID(ctx.Request().Context(), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && user.Email != email) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired verification token"})
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get user", "user_id", userID, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify email"})
+	}
+
+	if user.EmailVerifiedAt == nil {
+		err = s.DB.UpdateUser(ctx.Request().Context(), user.ID, map[string]interface{}{
+			"email_verified_at": time.Now().UTC(),
+		})
+		if err != nil {
+			s.Logger.ErrorContext(ctx.Request().Context(), "failed to verify email", "user_id", user.ID, "error", err)
+			return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify email"})
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification email. Like ForgotPassword it
+// responds the same way for unknown and already verified addresses.
+func (s *EchoServer) ResendVerification(ctx echo.Context) error {
+	var req dto.ResendVerificationRequest
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
+	}
+	req.Email = normalizeEmail(req.Email)
+	if !validEmail(req.Email) {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
+	}
+
+	response := map[string]string{
+		"message": "If an unverified account exists for that email, a verification link has been sent",
+	}
+
+	user, err := s.DB.GetUserByEmail(ctx.Request().Context(), req.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.JSON(http.StatusOK, response)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to get user", "email", req.Email, "error", err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process request"})
+	}
+	if user.EmailVerifiedAt == nil {
+		// Mailed after responding, so that the response takes as long whether
+		// or not the address has an unverified account
+		s.runInBackground(ctx.Request().Context(), func(ctx context.Context) {
+			if err := s.sendVerificationEmail(ctx, user); err != nil {
+				s.Logger.ErrorContext(ctx, "failed to send verification email", "user_id", user.ID, "error", err)
+			}
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *EchoServer) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.tokens.GenerateEmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm your email address by opening this link within 48 hours:\n\n%s\n\n"+
+			"If you did not create an account, you can ignore this email.\n",
+			user.FirstName, s.apiURL("/api/verify-email", token)),
+	})
+}
+
+// apiURL builds a link to this API carrying token as a query parameter
+func (s *EchoServer) apiURL(path, token string) string {
+	return s.cfg.Server.APIURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,48 @@
+// Package fixtures seeds a database with a small subset of the Bible, so the
+// integration tests can run against an empty SQLite database.
+package fixtures
+
+import (
+	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/importer"
+	"bible_reading_backend_nkv/models"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+)
+
+// bible holds Genesis 1:1-5 and 2:1-3, Psalm 23 and John 1:1-5 and
+// 3:16-17 from the public domain King James Version
+//
+//go:embed bible.usfm
+var bible []byte
+
+// Verses returns the fixture verses under the given translation
+func Verses(translationID string) ([]models.Verse, error) {
+	verses, issues, err := importer.Parse(importer.FormatUSFM, bytes.NewReader(bible))
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) > 0 {
+		return nil, fmt.Errorf("fixture bible.usfm: %s", issues[0])
+	}
+	return importer.ToModels(translationID, verses), nil
+}
+
+// Seed loads the fixture verses as the default translation, which the
+// legacy /api/niv routes serve, replacing any verses it already has
+func Seed(ctx context.Context, db database.DatabaseClient) error {
+	verses, err := Verses(models.DefaultTranslationID)
+	if err != nil {
+		return err
+	}
+	translation := &models.Translation{
+		ID:            models.DefaultTranslationID,
+		Name:          "Test fixture (King James Version text)",
+		Language:      "en",
+		Copyright:     "Public domain",
+		Versification: "KJV",
+	}
+	return db.ReplaceTranslation(ctx, translation, verses)
+}
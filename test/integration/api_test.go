@@ -2,15 +2,23 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
 
 	"bible_reading_backend_nkv/database"
 	"bible_reading_backend_nkv/dto"
+	"bible_reading_backend_nkv/mailer"
+	"bible_reading_backend_nkv/models"
 	"bible_reading_backend_nkv/server"
+	"bible_reading_backend_nkv/totp"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -34,18 +42,25 @@ func (suite *IntegrationTestSuite) SetupSuite() {
 		return
 	}
 
-	// Set TEST_DB_DSN as DB_DSN for database client
-	originalDSN := os.Getenv("DB_DSN")
-	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
-	defer os.Setenv("DB_DSN", originalDSN)
+	// Every request comes from the same test address, so keep repeated runs
+	// from tripping the per-IP login limit
+	os.Setenv("LOGIN_IP_MAX_ATTEMPTS", "100000")
+	// and from the request rate limits and explanation quotas, which have
+	// tests of their own
+	for _, name := range []string{"RATE_LIMIT_API", "RATE_LIMIT_AUTH", "RATE_LIMIT_EXPLAIN"} {
+		os.Setenv(name, "off")
+	}
+	os.Setenv("EXPLAIN_DAILY_QUOTA", "0")
+	os.Setenv("EXPLAIN_DAILY_QUOTA_ANONYMOUS", "0")
 
 	// Initialize database
-	db, err := database.NewDatabaseClient()
+	cfg := testConfig(suite.T())
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
 	require.NoError(suite.T(), err, "Failed to initialize database client")
 	suite.db = db
 
 	// Initialize server
-	srv := server.NewEchoServer(db)
+	srv := server.NewEchoServer(cfg, db, testLogger)
 	suite.server = srv
 
 	// Get Echo instance from server for testing
@@ -109,6 +124,18 @@ func (suite *IntegrationTestSuite) TestGetAllBooks() {
 	firstBook := books[0]
 	assert.Contains(suite.T(), firstBook, "book_id")
 	assert.Contains(suite.T(), firstBook, "book")
+	assert.Contains(suite.T(), firstBook, "osis")
+	assert.Contains(suite.T(), firstBook, "testament")
+	assert.Contains(suite.T(), firstBook, "genre")
+	assert.Contains(suite.T(), firstBook, "canonical_order")
+	assert.Contains(suite.T(), firstBook, "aliases")
+	assert.Contains(suite.T(), firstBook, "chapters")
+	assert.Contains(suite.T(), firstBook, "verse_counts")
+
+	// verse_counts has one entry per chapter
+	verseCounts, ok := firstBook["verse_counts"].([]interface{})
+	require.True(suite.T(), ok)
+	assert.Equal(suite.T(), firstBook["chapters"], float64(len(verseCounts)))
 }
 
 // TestGetAllVerses tests GET /api/niv/verses
@@ -213,6 +240,134 @@ func (suite *IntegrationTestSuite) TestGetVersesByChapter_InvalidBookId() {
 	assert.Contains(suite.T(), rec.Body.String(), "Invalid book ID")
 }
 
+// TestGetTranslations tests GET /api/bibles
+func (suite *IntegrationTestSuite) TestGetTranslations() {
+	req := httptest.NewRequest(http.MethodGet, "/api/bibles", nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+
+	var translations []map[string]interface{}
+	err := json.Unmarshal(rec.Body.Bytes(), &translations)
+	require.NoError(suite.T(), err)
+
+	ids := []interface{}{}
+	for _, translation := range translations {
+		ids = append(ids, translation["id"])
+	}
+	assert.Contains(suite.T(), ids, "niv", "NIV should always be registered")
+}
+
+// TestGetVersesByChapter_TranslationAlias tests that /api/niv serves the same verses as /api/bibles/niv
+func (suite *IntegrationTestSuite) TestGetVersesByChapter_TranslationAlias() {
+	req := httptest.NewRequest(http.MethodGet, "/api/niv/1/1/verses", nil)
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/bibles/niv/1/1/verses", nil)
+	aliasRec := httptest.NewRecorder()
+	suite.e.ServeHTTP(aliasRec, req)
+	assert.Equal(suite.T(), http.StatusOK, aliasRec.Code)
+
+	assert.JSONEq(suite.T(), rec.Body.String(), aliasRec.Body.String())
+}
+
+// TestGetVersesByChapter_UnknownTranslation tests an unregistered translation
+func (suite *IntegrationTestSuite) TestGetVersesByChapter_UnknownTranslation() {
+	req := httptest.NewRequest(http.MethodGet, "/api/bibles/does-not-exist/1/1/verses", nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, rec.Code)
+}
+
+// TestGetPassage tests GET /api/passage
+func (suite *IntegrationTestSuite) TestGetPassage() {
+	req := httptest.NewRequest(http.MethodGet, "/api/passage?ref=Gen+1:1-3%3B+Gen+2:1", nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+
+	var response dto.PassageResponse
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	require.NoError(suite.T(), err)
+
+	require.Len(suite.T(), response.Passages, 2)
+	assert.Equal(suite.T(), "Genesis 1:1-3", response.Passages[0].Reference)
+	assert.Len(suite.T(), response.Passages[0].Verses, 3)
+	assert.Equal(suite.T(), "Genesis 2:1", response.Passages[1].Reference)
+}
+
+// TestGetPassage_InvalidReference tests that parse errors point at the bad token
+func (suite *IntegrationTestSuite) TestGetPassage_InvalidReference() {
+	req := httptest.NewRequest(http.MethodGet, "/api/passage?ref=Jhon+3:16", nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+
+	var response dto.ParseErrorResponse
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Jhon", response.Token)
+	assert.Equal(suite.T(), 0, response.Offset)
+}
+
+// TestSearchVerses tests GET /api/search
+func (suite *IntegrationTestSuite) TestSearchVerses() {
+	req := httptest.NewRequest(http.MethodGet, `/api/search?q=%22in+the+beginning%22&book=Gen&limit=5`, nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+
+	var response struct {
+		Data  []dto.SearchResult `json:"data"`
+		Total int64              `json:"total"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	require.NoError(suite.T(), err)
+
+	require.Greater(suite.T(), len(response.Data), 0, "Genesis 1:1 should match")
+	assert.Equal(suite.T(), 1, response.Data[0].BookID)
+	assert.NotEmpty(suite.T(), response.Data[0].Highlights)
+}
+
+// TestSearchVerses_HugePage tests a page far past the last result
+func (suite *IntegrationTestSuite) TestSearchVerses_HugePage() {
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=god&page=461168601842738792", nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	var response struct {
+		Data  []dto.SearchResult `json:"data"`
+		Total int64              `json:"total"`
+	}
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Empty(suite.T(), response.Data)
+	assert.Greater(suite.T(), response.Total, int64(0))
+}
+
+// TestSearchVerses_InvalidQuery tests a malformed search expression
+func (suite *IntegrationTestSuite) TestSearchVerses_InvalidQuery() {
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=love+AND", nil)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+}
+
 // TestExplainVerse tests POST /api/niv/explain
 func (suite *IntegrationTestSuite) TestExplainVerse() {
 	// Skip if OpenAI API key is not set
@@ -244,6 +399,38 @@ func (suite *IntegrationTestSuite) TestExplainVerse() {
 	assert.Contains(suite.T(), []int{http.StatusOK, http.StatusInternalServerError}, rec.Code)
 }
 
+// TestExplainVerseStream tests POST /api/niv/explain/stream
+func (suite *IntegrationTestSuite) TestExplainVerseStream() {
+	// Skip if OpenAI API key is not set
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		suite.T().Skip("OPENAI_API_KEY not set - skipping explain stream test")
+		return
+	}
+
+	explainReq := dto.ExplainRequest{
+		Book:       "Genesis",
+		Chapter:    1,
+		StartVerse: 1,
+		EndVerse:   3,
+	}
+
+	body, err := json.Marshal(explainReq)
+	require.NoError(suite.T(), err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/niv/explain/stream", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	suite.e.ServeHTTP(rec, req)
+
+	assert.Contains(suite.T(), []int{http.StatusOK, http.StatusInternalServerError}, rec.Code)
+	if rec.Code == http.StatusOK {
+		assert.Equal(suite.T(), "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(suite.T(), rec.Body.String(), "event: delta")
+		assert.Contains(suite.T(), rec.Body.String(), "event: done")
+	}
+}
+
 // TestExplainVerse_InvalidRequest tests POST /api/niv/explain with invalid data
 func (suite *IntegrationTestSuite) TestExplainVerse_InvalidRequest() {
 	// Skip if API key is not set, as server will return 500 instead of 400
@@ -303,6 +490,680 @@ func (suite *IntegrationTestSuite) TestExplainVerse_DefaultValues() {
 	assert.Contains(suite.T(), []int{http.StatusOK, http.StatusInternalServerError}, rec.Code)
 }
 
+// TestExplainVerse_NotFound tests that references missing from the
+// translation are rejected before any LLM call is made
+func (suite *IntegrationTestSuite) TestExplainVerse_NotFound() {
+	tests := []dto.ExplainRequest{
+		{Book: "Genesis", Chapter: 1, StartVerse: 1, EndVerse: 99},
+		{Book: "Genesis", Chapter: 99, StartVerse: 1, EndVerse: 1},
+		{Book: "Hezekiah", Chapter: 1, StartVerse: 1, EndVerse: 1},
+	}
+
+	for _, explainReq := range tests {
+		body, err := json.Marshal(explainReq)
+		require.NoError(suite.T(), err)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/niv/explain", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		suite.e.ServeHTTP(rec, req)
+
+		assert.Equal(suite.T(), http.StatusNotFound, rec.Code, "%+v", explainReq)
+	}
+}
+
+// TestExplainVerse_Cache tests that a repeated explanation is served from
+// the cache, using the offline fake provider
+func (suite *IntegrationTestSuite) TestExplainVerse_Cache() {
+	suite.T().Setenv("LLM_PROVIDER", "fake")
+	e := server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer).GetEcho()
+
+	_, err := suite.db.DeleteExplanations(context.Background(), "niv", 1)
+	require.NoError(suite.T(), err)
+
+	body, err := json.Marshal(dto.ExplainRequest{Book: "Genesis", Chapter: 1, StartVerse: 1, EndVerse: 3})
+	require.NoError(suite.T(), err)
+
+	var explanations []dto.ExplainResponse
+	for _, cache := range []string{"MISS", "HIT"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/niv/explain", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		require.Equal(suite.T(), http.StatusOK, rec.Code)
+		assert.Equal(suite.T(), cache, rec.Header().Get("X-Cache"))
+
+		var response dto.ExplainResponse
+		require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(suite.T(), cache == "HIT", response.Cached)
+		explanations = append(explanations, response)
+	}
+	assert.Equal(suite.T(), explanations[0].Explanation, explanations[1].Explanation)
+}
+
+// TestExplainQuota tests that uncached explanations count against the daily
+// quota and are refused once it is used up
+func (suite *IntegrationTestSuite) TestExplainQuota() {
+	_, user := suite.registerUser()
+	suite.T().Setenv("LLM_PROVIDER", "fake")
+	suite.T().Setenv("EXPLAIN_CACHE_TTL_HOURS", "0")
+	suite.T().Setenv("EXPLAIN_DAILY_QUOTA", "1")
+	suite.T().Setenv("RATE_LIMIT_API", "100/m")
+	defer func(e *echo.Echo) { suite.e = e }(suite.e)
+	suite.e = server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer).GetEcho()
+
+	explainReq := dto.ExplainRequest{Book: "John", Chapter: 3, StartVerse: 16}
+	rec := suite.request(http.MethodPost, "/api/niv/explain", user.Access, explainReq)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(suite.T(), "1", rec.Header().Get("X-Quota-Limit"))
+	assert.Equal(suite.T(), "0", rec.Header().Get("X-Quota-Remaining"))
+	assert.Equal(suite.T(), "100", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(suite.T(), "99", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = suite.request(http.MethodPost, "/api/niv/explain", user.Access, explainReq)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(suite.T(), rec.Header().Get("Retry-After"))
+	assert.Equal(suite.T(), "98", rec.Header().Get("X-RateLimit-Remaining"))
+}
+
+// TestExplainQuota_RevokedToken tests that a logged out token is counted as
+// anonymous, so it cannot be used to get a fresh rate limit or quota
+func (suite *IntegrationTestSuite) TestExplainQuota_RevokedToken() {
+	_, user := suite.registerUser()
+	suite.T().Setenv("LLM_PROVIDER", "fake")
+	suite.T().Setenv("EXPLAIN_CACHE_TTL_HOURS", "0")
+	suite.T().Setenv("EXPLAIN_DAILY_QUOTA", "5")
+	suite.T().Setenv("EXPLAIN_DAILY_QUOTA_ANONYMOUS", "1")
+	suite.T().Setenv("RATE_LIMIT_API", "100/m")
+	defer func(e *echo.Echo) { suite.e = e }(suite.e)
+	suite.e = server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer).GetEcho()
+
+	clientIP := fmt.Sprintf("203.0.113.%d", time.Now().UnixNano()%250+1)
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		require.NoError(suite.T(), err)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+user.Access)
+		req.RemoteAddr = clientIP + ":1234"
+		rec := httptest.NewRecorder()
+		suite.e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	explainReq := dto.ExplainRequest{Book: "John", Chapter: 3, StartVerse: 17}
+	rec := do(http.MethodPost, "/api/niv/explain", explainReq)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(suite.T(), "5", rec.Header().Get("X-Quota-Limit"))
+	assert.Equal(suite.T(), "99", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = do(http.MethodPost, "/api/logout", nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), "98", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = do(http.MethodPost, "/api/niv/explain", explainReq)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(suite.T(), "1", rec.Header().Get("X-Quota-Limit"))
+	assert.Equal(suite.T(), "99", rec.Header().Get("X-RateLimit-Remaining"), "the IP bucket")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, do(http.MethodPost, "/api/niv/explain", explainReq).Code)
+}
+
+// TestExplainPersonalization tests that explanations follow the caller's
+// profile only while their session is active
+func (suite *IntegrationTestSuite) TestExplainPersonalization() {
+	_, user := suite.registerUser()
+	suite.T().Setenv("LLM_PROVIDER", "fake")
+	suite.T().Setenv("EXPLAIN_CACHE_TTL_HOURS", "0")
+	defer func(e *echo.Echo) { suite.e = e }(suite.e)
+	suite.e = server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer).GetEcho()
+
+	explain := func() string {
+		rec := suite.request(http.MethodPost, "/api/niv/explain", user.Access, dto.ExplainRequest{Book: "John", Chapter: 3, StartVerse: 16})
+		require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+		var response dto.ExplainResponse
+		require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &response))
+		return response.Explanation
+	}
+
+	assert.Contains(suite.T(), explain(), "aged 32")
+
+	// A logged out token is anonymous, even though it has not expired
+	require.Equal(suite.T(), http.StatusOK, suite.request(http.MethodPost, "/api/logout", user.Access, nil).Code)
+	assert.Contains(suite.T(), explain(), "aged 21")
+}
+
+// request sends a JSON request, with a bearer token if one is given
+func (suite *IntegrationTestSuite) request(method, url, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		require.NoError(suite.T(), err)
+	}
+	req := httptest.NewRequest(method, url, bytes.NewBuffer(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// registerUser creates a throwaway account and returns its tokens
+func (suite *IntegrationTestSuite) registerUser() (email string, tokens dto.RegisterResponse) {
+	email = fmt.Sprintf("user-%d@example.com", time.Now().UnixNano())
+	rec := suite.request(http.MethodPost, "/api/register/", "", dto.RegisterRequest{
+		FirstName: "Test", LastName: "User", Email: email, Password: "password123", Age: 30, BelifRating: 3,
+	})
+	require.Equal(suite.T(), http.StatusCreated, rec.Code, rec.Body.String())
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &tokens))
+	return email, tokens
+}
+
+// TestSessionFlow tests refresh token rotation, reuse detection and logout
+func (suite *IntegrationTestSuite) TestSessionFlow() {
+	email, registered := suite.registerUser()
+	do := suite.request
+
+	rec := do(http.MethodPost, "/api/token/refresh", "", dto.RefreshRequest{Refresh: registered.Refresh})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	var rotated dto.LoginResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &rotated))
+	assert.NotEqual(suite.T(), registered.Refresh, rotated.Refresh)
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodGet, "/api/users/me", rotated.Access, nil).Code)
+
+	// Reusing the old refresh token revokes the whole session
+	rec = do(http.MethodPost, "/api/token/refresh", "", dto.RefreshRequest{Refresh: registered.Refresh})
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", rotated.Access, nil).Code)
+	rec = do(http.MethodPost, "/api/token/refresh", "", dto.RefreshRequest{Refresh: rotated.Refresh})
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+
+	login := func() dto.LoginResponse {
+		rec := do(http.MethodPost, "/api/login/", "", dto.LoginRequest{Email: email, Password: "password123"})
+		require.Equal(suite.T(), http.StatusOK, rec.Code)
+		var tokens dto.LoginResponse
+		require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &tokens))
+		return tokens
+	}
+
+	first, second := login(), login()
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodPost, "/api/logout", first.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", first.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodGet, "/api/users/me", second.Access, nil).Code)
+
+	third := login()
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodPost, "/api/logout-all", third.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", second.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", third.Access, nil).Code)
+	rec = do(http.MethodPost, "/api/token/refresh", "", dto.RefreshRequest{Refresh: second.Refresh})
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+}
+
+// TestPasswordReset tests the forgot/reset flow with the in-memory mailer
+func (suite *IntegrationTestSuite) TestPasswordReset() {
+	email, registered := suite.registerUser()
+
+	suite.T().Setenv("MAIL_DRIVER", "memory")
+	srv := server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer)
+	outbox := srv.Mailer.(*mailer.Memory)
+	e := srv.GetEcho()
+
+	post := func(url string, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		require.NoError(suite.T(), err)
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBuffer(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Unknown addresses get the same response but no mail
+	rec := post("/api/password/forgot", dto.ForgotPasswordRequest{Email: "nobody-" + email})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Empty(suite.T(), outbox.Messages())
+
+	rec = post("/api/password/forgot", dto.ForgotPasswordRequest{Email: email})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	// The link is mailed after responding
+	require.NoError(suite.T(), srv.Shutdown(context.Background()))
+	msg, ok := outbox.Last(email)
+	require.True(suite.T(), ok)
+	match := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(msg.Body)
+	require.Len(suite.T(), match, 2)
+	token := match[1]
+
+	rec = post("/api/password/reset", dto.ResetPasswordRequest{Token: token, Password: "new-password"})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+
+	// Tokens are single use and existing sessions are ended
+	rec = post("/api/password/reset", dto.ResetPasswordRequest{Token: token, Password: "other-password"})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, suite.request(http.MethodGet, "/api/users/me", registered.Access, nil).Code)
+
+	rec = post("/api/login/", dto.LoginRequest{Email: email, Password: "password123"})
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+	rec = post("/api/login/", dto.LoginRequest{Email: email, Password: "new-password"})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+}
+
+// TestForcePasswordReset_RevokesEarlierLinks tests that a forced reset leaves
+// only its own link usable
+func (suite *IntegrationTestSuite) TestForcePasswordReset_RevokesEarlierLinks() {
+	ctx := context.Background()
+	_, registered := suite.registerUser()
+	prefix := fmt.Sprintf("%d", time.Now().UnixNano())
+	expires := time.Now().Add(time.Hour)
+
+	require.NoError(suite.T(), suite.db.CreatePasswordReset(ctx, &models.PasswordReset{
+		UserID: registered.User.ID, TokenHash: prefix + "-earlier", ExpiresAt: expires,
+	}))
+	require.NoError(suite.T(), suite.db.ForcePasswordReset(ctx, &models.PasswordReset{
+		UserID: registered.User.ID, TokenHash: prefix + "-forced", ExpiresAt: expires,
+	}))
+
+	_, err := suite.db.ResetPassword(ctx, prefix+"-earlier", "new-password")
+	assert.ErrorIs(suite.T(), err, database.ErrInvalidResetToken)
+	userID, err := suite.db.ResetPassword(ctx, prefix+"-forced", "new-password")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), registered.User.ID, userID)
+}
+
+// TestEmailVerification tests that with EMAIL_VERIFICATION=required new
+// users must confirm their address before they can log in
+func (suite *IntegrationTestSuite) TestEmailVerification() {
+	suite.T().Setenv("MAIL_DRIVER", "memory")
+	suite.T().Setenv("EMAIL_VERIFICATION", "required")
+	srv := server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer)
+	outbox := srv.Mailer.(*mailer.Memory)
+	e := srv.GetEcho()
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		require.NoError(suite.T(), err)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	email := fmt.Sprintf("verify-%d@example.com", time.Now().UnixNano())
+	rec := do(http.MethodPost, "/api/register/", dto.RegisterRequest{
+		FirstName: "Test", LastName: "User", Email: email, Password: "password123", Age: 30, BelifRating: 3,
+	})
+	require.Equal(suite.T(), http.StatusCreated, rec.Code, rec.Body.String())
+	var registered dto.RegisterResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &registered))
+	assert.True(suite.T(), registered.VerificationRequired)
+	assert.Empty(suite.T(), registered.Access)
+	assert.False(suite.T(), registered.User.EmailVerified)
+
+	login := dto.LoginRequest{Email: email, Password: "password123"}
+	assert.Equal(suite.T(), http.StatusForbidden, do(http.MethodPost, "/api/login/", login).Code)
+
+	msg, ok := outbox.Last(email)
+	require.True(suite.T(), ok)
+	match := regexp.MustCompile(`token=([A-Za-z0-9_.-]+)`).FindStringSubmatch(msg.Body)
+	require.Len(suite.T(), match, 2)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, do(http.MethodGet, "/api/verify-email?token=bogus", nil).Code)
+	rec = do(http.MethodGet, "/api/verify-email?token="+match[1], nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	// Following the link again is harmless
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodGet, "/api/verify-email?token="+match[1], nil).Code)
+
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodPost, "/api/login/", login).Code)
+
+	// Verified addresses are not sent another link
+	sent := len(outbox.Messages())
+	rec = do(http.MethodPost, "/api/verify-email/resend", dto.ResendVerificationRequest{Email: email})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Len(suite.T(), outbox.Messages(), sent)
+}
+
+// TestTwoFactor tests TOTP enrolment, the login challenge and recovery codes
+func (suite *IntegrationTestSuite) TestTwoFactor() {
+	email, registered := suite.registerUser()
+	do := suite.request
+	code := func(secret string, offset int64) string {
+		c, err := totp.Code(secret, totp.Step(time.Now())+offset)
+		require.NoError(suite.T(), err)
+		return c
+	}
+
+	rec := do(http.MethodPost, "/api/users/me/2fa/setup", registered.Access, nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	var setup dto.TwoFactorSetupResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &setup))
+	assert.Contains(suite.T(), setup.OTPAuthURI, "secret="+setup.Secret)
+
+	rec = do(http.MethodPost, "/api/users/me/2fa/enable", registered.Access, dto.TwoFactorCodeRequest{Code: "000000"})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	enableCode := code(setup.Secret, -1)
+	rec = do(http.MethodPost, "/api/users/me/2fa/enable", registered.Access, dto.TwoFactorCodeRequest{Code: enableCode})
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	var recovery dto.RecoveryCodesResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &recovery))
+	require.Len(suite.T(), recovery.RecoveryCodes, 10)
+
+	// The password alone now only yields a challenge
+	rec = do(http.MethodPost, "/api/login/", "", dto.LoginRequest{Email: email, Password: "password123"})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	var login dto.LoginResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &login))
+	assert.True(suite.T(), login.MFARequired)
+	assert.Empty(suite.T(), login.Access)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", login.Challenge, nil).Code)
+
+	// Codes cannot be replayed, including the one used to enable 2FA
+	rec = do(http.MethodPost, "/api/login/2fa", "", dto.LoginTwoFactorRequest{Challenge: login.Challenge, Code: enableCode})
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+	rec = do(http.MethodPost, "/api/login/2fa", "", dto.LoginTwoFactorRequest{Challenge: login.Challenge, Code: code(setup.Secret, 0)})
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	var tokens dto.LoginResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &tokens))
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodGet, "/api/users/me", tokens.Access, nil).Code)
+
+	// Recovery codes work once
+	recoveryLogin := dto.LoginTwoFactorRequest{Challenge: login.Challenge, Code: recovery.RecoveryCodes[0]}
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodPost, "/api/login/2fa", "", recoveryLogin).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodPost, "/api/login/2fa", "", recoveryLogin).Code)
+
+	rec = do(http.MethodPost, "/api/users/me/2fa/disable", tokens.Access, dto.TwoFactorDisableRequest{
+		Password: "password123", Code: recovery.RecoveryCodes[1],
+	})
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	rec = do(http.MethodPost, "/api/login/", "", dto.LoginRequest{Email: email, Password: "password123"})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	login = dto.LoginResponse{}
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &login))
+	assert.False(suite.T(), login.MFARequired)
+	assert.NotEmpty(suite.T(), login.Access)
+}
+
+// TestAdmin tests role enforcement and the admin user endpoints
+func (suite *IntegrationTestSuite) TestAdmin() {
+	do := suite.request
+	_, admin := suite.registerUser()
+	targetEmail, target := suite.registerUser()
+	targetPath := fmt.Sprintf("/api/admin/users/%d", target.User.ID)
+
+	assert.Equal(suite.T(), http.StatusForbidden, do(http.MethodGet, "/api/admin/users", admin.Access, nil).Code)
+	require.NoError(suite.T(), suite.db.UpdateUser(context.Background(), admin.User.ID, map[string]interface{}{"role": "admin"}))
+
+	rec := do(http.MethodGet, "/api/admin/users?q="+targetEmail, admin.Access, nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	var page struct {
+		Data  []dto.AdminUserResponse `json:"data"`
+		Total int64                   `json:"total"`
+	}
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(suite.T(), int64(1), page.Total)
+	assert.Equal(suite.T(), "user", page.Data[0].Role)
+
+	rec = do(http.MethodGet, targetPath+"/stats", admin.Access, nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Contains(suite.T(), rec.Body.String(), `"active_sessions":1`)
+
+	// Disabling ends the user's sessions and blocks login until re-enabled
+	require.Equal(suite.T(), http.StatusOK, do(http.MethodPost, targetPath+"/disable", admin.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", target.Access, nil).Code)
+	login := dto.LoginRequest{Email: targetEmail, Password: "password123"}
+	assert.Equal(suite.T(), http.StatusForbidden, do(http.MethodPost, "/api/login/", "", login).Code)
+	require.Equal(suite.T(), http.StatusOK, do(http.MethodPost, targetPath+"/enable", admin.Access, nil).Code)
+	rec = do(http.MethodPost, "/api/login/", "", login)
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	var tokens dto.LoginResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &tokens))
+
+	// Moderators can look users up but not change them
+	rec = do(http.MethodPut, targetPath+"/role", admin.Access, dto.UpdateRoleRequest{Role: "moderator"})
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	adminPath := fmt.Sprintf("/api/admin/users/%d", admin.User.ID)
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodGet, adminPath, tokens.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, do(http.MethodPost, adminPath+"/disable", tokens.Access, nil).Code)
+
+	// Admins cannot lock themselves out
+	assert.Equal(suite.T(), http.StatusBadRequest, do(http.MethodPost, adminPath+"/disable", admin.Access, nil).Code)
+
+	// A forced reset invalidates the old password and sessions
+	require.Equal(suite.T(), http.StatusOK, do(http.MethodPost, targetPath+"/password-reset", admin.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", tokens.Access, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodPost, "/api/login/", "", login).Code)
+}
+
+// TestRecordLoginFailure_Concurrent tests that simultaneous failed logins for
+// a new key are all counted, and that counters restart after the window
+func (suite *IntegrationTestSuite) TestRecordLoginFailure_Concurrent() {
+	ctx := context.Background()
+	key := fmt.Sprintf("email:race-%d@example.com", time.Now().UnixNano())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := suite.db.RecordLoginFailure(ctx, key, 10, time.Hour, time.Minute)
+			assert.NoError(suite.T(), err)
+		}()
+	}
+	wg.Wait()
+	throttles, err := suite.db.GetLoginThrottles(ctx, key)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), throttles, 1)
+	assert.Equal(suite.T(), 20, throttles[0].Failures)
+	require.NotNil(suite.T(), throttles[0].LockedUntil)
+	assert.WithinDuration(suite.T(), time.Now().Add(time.Minute), *throttles[0].LockedUntil, 5*time.Second)
+
+	// A failure after the window starts counting again and lifts the lockout
+	throttle, err := suite.db.RecordLoginFailure(ctx, key, 10, 0, time.Minute)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, throttle.Failures)
+	assert.Nil(suite.T(), throttle.LockedUntil)
+
+	throttle, err = suite.db.RecordLoginFailure(ctx, key, 2, time.Hour, time.Minute)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, throttle.Failures)
+	assert.NotNil(suite.T(), throttle.LockedUntil)
+}
+
+// TestReserveLoginAttempt_Concurrent tests that simultaneous attempts for one
+// key are reserved one at a time, so that only those before the delay pass
+func (suite *IntegrationTestSuite) TestReserveLoginAttempt_Concurrent() {
+	ctx := context.Background()
+	key := fmt.Sprintf("email:reserve-%d@example.com", time.Now().UnixNano())
+	delay := func(failures int) time.Duration {
+		if failures < 3 {
+			return 0
+		}
+		return time.Hour
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := suite.db.ReserveLoginAttempt(ctx, key, 10, time.Hour, time.Minute, delay)
+			assert.NoError(suite.T(), err)
+			if ok {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(suite.T(), 3, reserved)
+
+	throttle, ok, err := suite.db.ReserveLoginAttempt(ctx, key, 10, time.Hour, time.Minute, delay)
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 3, throttle.Failures, "refused attempts are not counted")
+
+	// Reaching the threshold locks the key
+	throttle, ok, err = suite.db.ReserveLoginAttempt(ctx, key, 4, time.Hour, time.Minute, func(int) time.Duration { return 0 })
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 4, throttle.Failures)
+	require.NotNil(suite.T(), throttle.LockedUntil)
+	_, ok, err = suite.db.ReserveLoginAttempt(ctx, key, 4, time.Hour, time.Minute, func(int) time.Duration { return 0 })
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+}
+
+// TestTwoFactorCodeGuessing tests that wrong codes sent with an access token
+// count towards the login lockout
+func (suite *IntegrationTestSuite) TestTwoFactorCodeGuessing() {
+	_, registered := suite.registerUser()
+	clientIP := fmt.Sprintf("198.51.100.%d", time.Now().UnixNano()%250+1)
+	do := func(url string, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		require.NoError(suite.T(), err)
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBuffer(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+registered.Access)
+		req.RemoteAddr = clientIP + ":1234"
+		rec := httptest.NewRecorder()
+		suite.e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/api/users/me/2fa/setup", nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code, rec.Body.String())
+	var setup dto.TwoFactorSetupResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &setup))
+	code, err := totp.Code(setup.Secret, totp.Step(time.Now()))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, do("/api/users/me/2fa/enable", dto.TwoFactorCodeRequest{Code: code}).Code)
+
+	// From the third failure on, attempts have to be spaced out
+	wrong := dto.TwoFactorCodeRequest{Code: "000000"}
+	assert.Equal(suite.T(), http.StatusUnauthorized, do("/api/users/me/2fa/recovery-codes", wrong).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do("/api/users/me/2fa/disable", dto.TwoFactorDisableRequest{
+		Password: "password123", Code: "000000",
+	}).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do("/api/users/me/2fa/disable", dto.TwoFactorDisableRequest{
+		Password: "wrong-password", Code: "000000",
+	}).Code)
+	rec = do("/api/users/me/2fa/recovery-codes", wrong)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(suite.T(), rec.Header().Get("Retry-After"))
+	rec = do("/api/users/me/2fa/disable", dto.TwoFactorDisableRequest{Password: "password123", Code: "000000"})
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rec.Code)
+}
+
+// TestLoginLockout tests the progressive delay, lockout and admin unlock
+func (suite *IntegrationTestSuite) TestLoginLockout() {
+	email, user := suite.registerUser()
+	_, admin := suite.registerUser()
+	require.NoError(suite.T(), suite.db.UpdateUser(context.Background(), admin.User.ID, map[string]interface{}{"role": "admin"}))
+	userPath := fmt.Sprintf("/api/admin/users/%d", user.User.ID)
+
+	suite.T().Setenv("LOGIN_MAX_ATTEMPTS", "4")
+	e := server.NewEchoServer(testConfig(suite.T()), suite.db, testLogger).(*server.EchoServer).GetEcho()
+	clientIP := fmt.Sprintf("198.51.100.%d", time.Now().UnixNano()%250+1)
+	login := func(password string) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(dto.LoginRequest{Email: email, Password: password})
+		require.NoError(suite.T(), err)
+		req := httptest.NewRequest(http.MethodPost, "/api/login/", bytes.NewBuffer(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.RemoteAddr = clientIP + ":1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(suite.T(), http.StatusUnauthorized, login("wrong-password").Code)
+	}
+	// From the third failure on, attempts have to be spaced out
+	rec := login("password123")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rec.Code)
+	assert.Equal(suite.T(), "1", rec.Header().Get("Retry-After"))
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(suite.T(), http.StatusUnauthorized, login("wrong-password").Code)
+	rec = login("password123")
+	assert.Equal(suite.T(), http.StatusLocked, rec.Code)
+	assert.NotEmpty(suite.T(), rec.Header().Get("Retry-After"))
+
+	rec = suite.request(http.MethodGet, userPath+"/stats", admin.Access, nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Contains(suite.T(), rec.Body.String(), `"failed_logins":4`)
+
+	rec = suite.request(http.MethodPost, userPath+"/unlock", admin.Access, nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), http.StatusOK, login("password123").Code)
+}
+
+// TestUserFlow tests registration, login, the profile endpoints and favorites
+func (suite *IntegrationTestSuite) TestUserFlow() {
+	email := fmt.Sprintf("flow-%d@example.com", time.Now().UnixNano())
+	do := suite.request
+
+	rec := do(http.MethodPost, "/api/register/", "", dto.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: email, Password: "password123", Age: 30, BelifRating: 4,
+	})
+	require.Equal(suite.T(), http.StatusCreated, rec.Code, rec.Body.String())
+	var registered dto.RegisterResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &registered))
+	assert.Equal(suite.T(), "John Doe", registered.User.Name)
+
+	rec = do(http.MethodPost, "/api/register/", "", dto.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: email, Password: "password123", Age: 30, BelifRating: 4,
+	})
+	assert.Equal(suite.T(), http.StatusConflict, rec.Code)
+
+	rec = do(http.MethodPost, "/api/login/", "", dto.LoginRequest{Email: email, Password: "wrong-password"})
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+
+	rec = do(http.MethodPost, "/api/login/", "", dto.LoginRequest{Email: email, Password: "password123"})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	var login dto.LoginResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &login))
+	token := login.Access
+
+	rec = do(http.MethodGet, "/api/users/me", "", nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, rec.Code)
+
+	rec = do(http.MethodPut, "/api/users/me", token, map[string]int{"age": 31})
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	var user dto.UserResponse
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &user))
+	assert.Equal(suite.T(), 31, user.Age)
+	assert.Equal(suite.T(), email, user.Email)
+
+	favorite := dto.AddFavoriteVerseRequest{BookID: 1, Chapter: 1, Verse: 1}
+	assert.Equal(suite.T(), http.StatusCreated, do(http.MethodPost, "/api/users/me/favorites", token, favorite).Code)
+	assert.Equal(suite.T(), http.StatusConflict, do(http.MethodPost, "/api/users/me/favorites", token, favorite).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, do(http.MethodPost, "/api/users/me/favorites", token,
+		dto.AddFavoriteVerseRequest{BookID: 1, Chapter: 999, Verse: 1}).Code)
+
+	rec = do(http.MethodGet, "/api/users/me/favorites", token, nil)
+	require.Equal(suite.T(), http.StatusOK, rec.Code)
+	var favorites struct {
+		Data  []dto.FavoriteVerseResponse `json:"data"`
+		Total int64                       `json:"total"`
+	}
+	require.NoError(suite.T(), json.Unmarshal(rec.Body.Bytes(), &favorites))
+	require.Len(suite.T(), favorites.Data, 1)
+	assert.Equal(suite.T(), int64(1), favorites.Total)
+	assert.NotEmpty(suite.T(), favorites.Data[0].Text)
+
+	// Deleting the account also ends its sessions
+	assert.Equal(suite.T(), http.StatusOK, do(http.MethodDelete, "/api/users/me", token, nil).Code)
+	assert.Equal(suite.T(), http.StatusUnauthorized, do(http.MethodGet, "/api/users/me", token, nil).Code)
+}
+
 // Run the test suite
 func TestIntegrationSuite(t *testing.T) {
 	suite.Run(t, new(IntegrationTestSuite))
@@ -25,15 +25,12 @@ func TestEndpoints(t *testing.T) {
 		return
 	}
 
-	// Set TEST_DB_DSN as DB_DSN
-	originalDSN := os.Getenv("DB_DSN")
-	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
-	defer os.Setenv("DB_DSN", originalDSN)
+	cfg := testConfig(t)
 
-	db, err := database.NewDatabaseClient()
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
 	require.NoError(t, err, "Failed to initialize database")
 
-	srv := server.NewEchoServer(db)
+	srv := server.NewEchoServer(cfg, db, testLogger)
 
 	// Get Echo instance for testing
 	var e *echo.Echo
@@ -227,14 +224,12 @@ func TestErrorHandling(t *testing.T) {
 		return
 	}
 
-	originalDSN := os.Getenv("DB_DSN")
-	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
-	defer os.Setenv("DB_DSN", originalDSN)
+	cfg := testConfig(t)
 
-	db, err := database.NewDatabaseClient()
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
 	require.NoError(t, err)
 
-	srv := server.NewEchoServer(db)
+	srv := server.NewEchoServer(cfg, db, testLogger)
 
 	var e *echo.Echo
 	if echoSrv, ok := srv.(*server.EchoServer); ok {
@@ -294,14 +289,12 @@ func TestResponseFormats(t *testing.T) {
 		return
 	}
 
-	originalDSN := os.Getenv("DB_DSN")
-	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
-	defer os.Setenv("DB_DSN", originalDSN)
+	cfg := testConfig(t)
 
-	db, err := database.NewDatabaseClient()
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
 	require.NoError(t, err)
 
-	srv := server.NewEchoServer(db)
+	srv := server.NewEchoServer(cfg, db, testLogger)
 
 	var e *echo.Echo
 	if echoSrv, ok := srv.(*server.EchoServer); ok {
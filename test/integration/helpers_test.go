@@ -44,9 +44,7 @@ func (h *TestHelper) MakeRequest(method, path string, body interface{}, headers
 	}
 
 	rec := httptest.NewRecorder()
-	
-	// This helper is not fully implemented yet
-	// Tests should use echo.ServeHTTP directly
+	h.Server.(*server.EchoServer).GetEcho().ServeHTTP(rec, req)
 	return rec, nil
 }
 
@@ -1,15 +1,90 @@
 package integration
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"bible_reading_backend_nkv/config"
 	"bible_reading_backend_nkv/database"
+	"bible_reading_backend_nkv/logging"
 	"bible_reading_backend_nkv/server"
+	"bible_reading_backend_nkv/test/fixtures"
 
 	"github.com/stretchr/testify/require"
 )
 
+// testLogger drops the request and query logs of the servers under test
+var testLogger = logging.Discard()
+
+// TestMain runs the tests against TEST_DB_DSN if it is set, and otherwise
+// against a temporary SQLite database seeded with the fixture verses
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_DB_DSN") != "" {
+		os.Exit(m.Run())
+	}
+
+	dir, err := os.MkdirTemp("", "bible-reading-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating test database directory: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("TEST_DB_DSN", "sqlite://"+filepath.Join(dir, "test.db"))
+	if err := seedTestDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "setting up test database: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// seedTestDatabase migrates the database at TEST_DB_DSN and loads the
+// fixtures into it
+func seedTestDatabase() error {
+	cfg, err := loadTestConfig()
+	if err != nil {
+		return err
+	}
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := db.(*database.Client).Migrator()
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return err
+	}
+	return fixtures.Seed(context.Background(), db)
+}
+
+// testConfig loads the configuration from the environment with the database
+// pointed at TEST_DB_DSN. Tests that change settings with Setenv load it
+// again to build a server that uses them.
+func testConfig(t *testing.T) *config.Config {
+	cfg, err := loadTestConfig()
+	require.NoError(t, err, "Failed to load test configuration")
+	return cfg
+}
+
+func loadTestConfig() (*config.Config, error) {
+	if os.Getenv("JWT_SECRET") == "" {
+		os.Setenv("JWT_SECRET", "integration-test-secret")
+	}
+	originalDSN := os.Getenv("DB_DSN")
+	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
+	defer os.Setenv("DB_DSN", originalDSN)
+	return config.Load()
+}
+
 // setupTestServer creates a test server instance
 func setupTestServer(t *testing.T) server.Server {
 	if os.Getenv("TEST_DB_DSN") == "" {
@@ -17,15 +92,11 @@ func setupTestServer(t *testing.T) server.Server {
 		return nil
 	}
 
-	// Set TEST_DB_DSN as DB_DSN for database client
-	originalDSN := os.Getenv("DB_DSN")
-	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
-	defer os.Setenv("DB_DSN", originalDSN)
-
-	db, err := database.NewDatabaseClient()
+	cfg := testConfig(t)
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
 	require.NoError(t, err, "Failed to initialize test database")
 
-	return server.NewEchoServer(db)
+	return server.NewEchoServer(cfg, db, testLogger)
 }
 
 // checkDatabaseConnection verifies database is available
@@ -34,11 +105,11 @@ func checkDatabaseConnection(t *testing.T) bool {
 		return false
 	}
 
-	originalDSN := os.Getenv("DB_DSN")
-	os.Setenv("DB_DSN", os.Getenv("TEST_DB_DSN"))
-	defer os.Setenv("DB_DSN", originalDSN)
-
-	db, err := database.NewDatabaseClient()
+	cfg, err := loadTestConfig()
+	if err != nil {
+		return false
+	}
+	db, err := database.NewDatabaseClient(cfg.Database, testLogger)
 	if err != nil {
 		return false
 	}
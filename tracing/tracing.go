@@ -0,0 +1,86 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are started by the
+// Echo middleware, the database plugin and the LLM client through the global
+// tracer provider, which records nothing until Setup installs an exporter.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// ExporterNone records no spans, but trace context is still passed on
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON lines, for local use
+	ExporterStdout = "stdout"
+)
+
+// Config selects where spans go
+type Config struct {
+	Exporter string
+	// Endpoint is the collector URL for ExporterOTLP, such as
+	// http://localhost:4318. Empty means the OTEL_EXPORTER_OTLP_* variables
+	// or the exporter's default.
+	Endpoint string
+	// SampleRatio is the share of new traces that are recorded; traces
+	// started by a caller follow the caller's decision
+	SampleRatio float64
+	ServiceName string
+}
+
+// Setup installs the W3C trace context and baggage propagators and a global
+// tracer provider exporting as cfg says. The stdout exporter writes to w.
+// The returned function flushes pending spans and stops the exporter.
+func Setup(ctx context.Context, w io.Writer, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(attribute.String("service.name", cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), &buf, Config{
+		Exporter:    ExporterStdout,
+		SampleRatio: 1,
+		ServiceName: "tracing-test",
+	})
+	require.NoError(t, err)
+
+	// An incoming traceparent becomes the parent of our spans
+	carrier := propagation.HeaderCarrier{}
+	carrier.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	_, span := otel.Tracer("test").Start(ctx, "work")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	var exported struct {
+		Name        string
+		SpanContext struct{ TraceID string }
+		Parent      struct{ SpanID string }
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Equal(t, "work", exported.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", exported.SpanContext.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", exported.Parent.SpanID)
+	assert.Contains(t, buf.String(), `{"Key":"service.name","Value":{"Type":"STRING","Value":"tracing-test"}}`)
+}
+
+func TestUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), nil, Config{Exporter: "zipkin"})
+	assert.EqualError(t, err, `unknown trace exporter "zipkin"`)
+}